@@ -0,0 +1,364 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	tgbot "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const (
+	roleUser  = "user"
+	roleAdmin = "admin"
+)
+
+const (
+	// deniedLogInterval is the least time between two audit entries of the
+	// same user, so that a stranger can't flood the access_denied table
+	deniedLogInterval = time.Minute
+	// deniedRetention is how long audit entries are kept
+	deniedRetention = 90 * 24 * time.Hour
+	// deniedTextLimit is the number of characters of a message kept
+	deniedTextLimit = 100
+)
+
+// accessControl decides who may talk to the bot. Users and chats come from
+// the configuration; additional users granted via /allow live in SQLite.
+type accessControl struct {
+	db           *sql.DB
+	restricted   bool
+	allowedUsers map[int64]bool
+	allowedChats map[int64]bool
+	admins       map[int64]bool // from config, cannot be revoked
+
+	// Prepared once since they run on every message
+	roleStmt  *sql.Stmt
+	touchStmt *sql.Stmt
+
+	mu         sync.Mutex
+	lastDenied map[int64]time.Time // user ID → last audit entry
+	now        func() time.Time
+}
+
+// accessUser is a row of the users table
+type accessUser struct {
+	UserID    int64
+	Username  string
+	Role      string
+	AddedBy   int64
+	CreatedAt time.Time
+}
+
+// deniedAttempt is a row of the access_denied audit log
+type deniedAttempt struct {
+	UserID      int64
+	Username    string
+	ChatID      int64
+	Text        string
+	AttemptedAt time.Time
+}
+
+func newAccessControl(db *sql.DB, cfg *Config) (*accessControl, error) {
+	a := &accessControl{
+		db:           db,
+		restricted:   cfg.PrivateMode || len(cfg.AllowedUserIDs) > 0 || len(cfg.AllowedChatIDs) > 0,
+		allowedUsers: make(map[int64]bool),
+		allowedChats: make(map[int64]bool),
+		admins:       make(map[int64]bool),
+		lastDenied:   make(map[int64]time.Time),
+		now:          time.Now,
+	}
+	for _, id := range cfg.AllowedUserIDs {
+		a.allowedUsers[id] = true
+	}
+	for _, id := range cfg.AllowedChatIDs {
+		a.allowedChats[id] = true
+	}
+	for _, id := range cfg.AdminUserIDs {
+		a.admins[id] = true
+	}
+
+	var err error
+	if a.roleStmt, err = db.Prepare("SELECT role FROM users WHERE user_id = ?"); err != nil {
+		return nil, err
+	}
+	if a.touchStmt, err = db.Prepare("UPDATE users SET username = ? WHERE user_id = ?"); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// String summarizes the access mode for the startup log
+func (a *accessControl) String() string {
+	if !a.restricted {
+		return fmt.Sprintf("Access: open to everyone (%d admins)", len(a.admins))
+	}
+	return fmt.Sprintf("Access: private (%d users, %d chats, %d admins in config)",
+		len(a.allowedUsers), len(a.allowedChats), len(a.admins))
+}
+
+// role returns the stored role of a user, or "" if not in the users table
+func (a *accessControl) role(userID int64) string {
+	var role string
+	err := a.roleStmt.QueryRow(userID).Scan(&role)
+	if err != nil && err != sql.ErrNoRows {
+		log.Printf("Error looking up user %d: %v", userID, err)
+	}
+	return role
+}
+
+// isAdmin reports whether the user may manage access
+func (a *accessControl) isAdmin(userID int64) bool {
+	return a.admins[userID] || a.role(userID) == roleAdmin
+}
+
+// hasAdmins reports whether any admin is configured or granted
+func (a *accessControl) hasAdmins() bool {
+	if len(a.admins) > 0 {
+		return true
+	}
+	var exists int
+	err := a.db.QueryRow("SELECT 1 FROM users WHERE role = ? LIMIT 1", roleAdmin).Scan(&exists)
+	if err != nil && err != sql.ErrNoRows {
+		log.Printf("Error looking up admins: %v", err)
+	}
+	return err == nil
+}
+
+// isAllowed reports whether the user may use the bot in the given chat
+func (a *accessControl) isAllowed(userID, chatID int64) bool {
+	if !a.restricted {
+		return true
+	}
+	if a.allowedUsers[userID] || a.allowedChats[chatID] || a.admins[userID] {
+		return true
+	}
+	return a.role(userID) != ""
+}
+
+// recordDenied writes a rejected message to the audit log, at most once per
+// deniedLogInterval for each user, and drops entries older than
+// deniedRetention
+func (a *accessControl) recordDenied(m *tgbot.Message) {
+	var userID int64
+	var username string
+	if m.From != nil {
+		userID = m.From.ID
+		username = m.From.UserName
+	}
+	log.Printf("Access denied: user %d (@%s) in chat %d", userID, username, m.Chat.ID)
+
+	now := a.now().UTC()
+	a.mu.Lock()
+	last, seen := a.lastDenied[userID]
+	throttled := seen && now.Sub(last) < deniedLogInterval
+	if !throttled {
+		a.lastDenied[userID] = now
+	}
+	for id, t := range a.lastDenied {
+		if now.Sub(t) >= deniedLogInterval {
+			delete(a.lastDenied, id)
+		}
+	}
+	a.mu.Unlock()
+	if throttled {
+		return
+	}
+
+	// Keep the audit entry short; it is only meant to identify the attempt
+	text := m.Text
+	if runes := []rune(text); len(runes) > deniedTextLimit {
+		text = string(runes[:deniedTextLimit])
+	}
+	if _, err := a.db.Exec(
+		"INSERT INTO access_denied (user_id, username, chat_id, text, attempted_at) VALUES (?, ?, ?, ?, ?)",
+		userID, username, m.Chat.ID, text, now); err != nil {
+		log.Printf("Error recording denied access: %v", err)
+	}
+	if _, err := a.db.Exec("DELETE FROM access_denied WHERE attempted_at < ?", now.Add(-deniedRetention)); err != nil {
+		log.Printf("Error pruning denied access log: %v", err)
+	}
+}
+
+// allow grants a user access with the given role
+func (a *accessControl) allow(userID int64, role string, addedBy int64) error {
+	_, err := a.db.Exec(`
+		INSERT INTO users (user_id, role, added_by) VALUES (?, ?, ?)
+		ON CONFLICT(user_id) DO UPDATE SET role = excluded.role, added_by = excluded.added_by`,
+		userID, role, addedBy)
+	return err
+}
+
+// revoke removes a user from the users table. It reports false if the user
+// had no stored access.
+func (a *accessControl) revoke(userID int64) (bool, error) {
+	res, err := a.db.Exec("DELETE FROM users WHERE user_id = ?", userID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// touch remembers the latest username of a known user for /users
+func (a *accessControl) touch(u *tgbot.User) {
+	if u == nil || u.UserName == "" {
+		return
+	}
+	if _, err := a.touchStmt.Exec(u.UserName, u.ID); err != nil {
+		log.Printf("Error updating username: %v", err)
+	}
+}
+
+// users returns all users granted access through /allow
+func (a *accessControl) users() ([]accessUser, error) {
+	rows, err := a.db.Query("SELECT user_id, COALESCE(username, ''), role, added_by, created_at FROM users ORDER BY created_at")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []accessUser
+	for rows.Next() {
+		var u accessUser
+		if err := rows.Scan(&u.UserID, &u.Username, &u.Role, &u.AddedBy, &u.CreatedAt); err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+// recentDenied returns the latest entries of the audit log
+func (a *accessControl) recentDenied(limit int) ([]deniedAttempt, error) {
+	rows, err := a.db.Query(
+		"SELECT user_id, username, chat_id, text, attempted_at FROM access_denied ORDER BY attempted_at DESC, id DESC LIMIT ?",
+		limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attempts []deniedAttempt
+	for rows.Next() {
+		var d deniedAttempt
+		if err := rows.Scan(&d.UserID, &d.Username, &d.ChatID, &d.Text, &d.AttemptedAt); err != nil {
+			return nil, err
+		}
+		attempts = append(attempts, d)
+	}
+	return attempts, rows.Err()
+}
+
+// handleAllow implements /allow <user_id> [admin]
+func (a *accessControl) handleAllow(m *tgbot.Message) string {
+	args := strings.Fields(m.CommandArguments())
+	if len(args) == 0 || len(args) > 2 {
+		return "Usage: /allow <user_id> [admin]"
+	}
+	userID, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return "User ID must be a number. Denied users are listed in /users."
+	}
+	role := roleUser
+	if len(args) == 2 {
+		if args[1] != roleAdmin {
+			return "Usage: /allow <user_id> [admin]"
+		}
+		role = roleAdmin
+	}
+
+	if err := a.allow(userID, role, m.From.ID); err != nil {
+		log.Printf("Error allowing user %d: %v", userID, err)
+		return "Failed to update access."
+	}
+	log.Printf("User %d granted %s access by %d", userID, role, m.From.ID)
+	return fmt.Sprintf("✅ User %d can now use the bot as %s", userID, role)
+}
+
+// handleRevoke implements /revoke <user_id>
+func (a *accessControl) handleRevoke(m *tgbot.Message) string {
+	args := strings.Fields(m.CommandArguments())
+	if len(args) != 1 {
+		return "Usage: /revoke <user_id>"
+	}
+	userID, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return "User ID must be a number."
+	}
+	if userID == m.From.ID {
+		return "You can't revoke your own access."
+	}
+
+	removed, err := a.revoke(userID)
+	if err != nil {
+		log.Printf("Error revoking user %d: %v", userID, err)
+		return "Failed to update access."
+	}
+
+	var notes []string
+	if a.admins[userID] {
+		notes = append(notes, "they are still an admin via ADMIN_USER_IDS")
+	}
+	if a.allowedUsers[userID] {
+		notes = append(notes, "they are still allowed via ALLOWED_USER_IDS")
+	}
+	if !removed && len(notes) == 0 {
+		return fmt.Sprintf("User %d had no access to revoke.", userID)
+	}
+
+	text := fmt.Sprintf("🚫 Access revoked for user %d", userID)
+	if len(notes) > 0 {
+		text += " (note: " + strings.Join(notes, "; ") + ")"
+	}
+	log.Printf("User %d revoked by %d", userID, m.From.ID)
+	return text
+}
+
+// handleUsers implements /users
+func (a *accessControl) handleUsers() string {
+	var b strings.Builder
+	b.WriteString(a.String() + "\n")
+
+	users, err := a.users()
+	if err != nil {
+		log.Printf("Error listing users: %v", err)
+		return "Failed to list users."
+	}
+	if len(users) > 0 {
+		b.WriteString("\n👥 Users:\n")
+		for _, u := range users {
+			name := ""
+			if u.Username != "" {
+				name = " @" + u.Username
+			}
+			fmt.Fprintf(&b, "• %d%s (%s, since %s)\n", u.UserID, name, u.Role, u.CreatedAt.Format("2006-01-02"))
+		}
+	}
+
+	denied, err := a.recentDenied(5)
+	if err != nil {
+		log.Printf("Error listing denied attempts: %v", err)
+	} else if len(denied) > 0 {
+		b.WriteString("\n⛔ Recent denied attempts:\n")
+		for _, d := range denied {
+			name := ""
+			if d.Username != "" {
+				name = " @" + d.Username
+			}
+			fmt.Fprintf(&b, "• %d%s at %s: %s\n", d.UserID, name, d.AttemptedAt.Format("2006-01-02 15:04"), d.Text)
+		}
+	}
+
+	return strings.TrimSpace(b.String())
+}
+
+// rejectionText is the polite reply sent to strangers
+func rejectionText(userID int64) string {
+	return fmt.Sprintf("🔒 Sorry, this is a private bot.\nIf you know the owner, ask them to run /allow %d", userID)
+}
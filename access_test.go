@@ -0,0 +1,119 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	tgbot "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestAccessControl(t *testing.T) {
+	if err := initDB(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	open, err := newAccessControl(db, &Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !open.isAllowed(1, 1) {
+		t.Error("isAllowed() = false for open bot, want true")
+	}
+
+	a, err := newAccessControl(db, &Config{
+		AllowedUserIDs: []int64{10},
+		AllowedChatIDs: []int64{-500},
+		AdminUserIDs:   []int64{1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		userID int64
+		chatID int64
+		want   bool
+	}{
+		{name: "Config admin", userID: 1, chatID: 1, want: true},
+		{name: "Allowed user", userID: 10, chatID: 10, want: true},
+		{name: "Allowed group chat", userID: 99, chatID: -500, want: true},
+		{name: "Stranger", userID: 99, chatID: 99, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := a.isAllowed(tt.userID, tt.chatID); got != tt.want {
+				t.Errorf("isAllowed(%d, %d) = %v, want %v", tt.userID, tt.chatID, got, tt.want)
+			}
+		})
+	}
+
+	// Grant and revoke through the database
+	if err := a.allow(99, roleAdmin, 1); err != nil {
+		t.Fatal(err)
+	}
+	if !a.isAllowed(99, 99) || !a.isAdmin(99) {
+		t.Error("user 99 should be an allowed admin after allow()")
+	}
+	if removed, err := a.revoke(99); err != nil || !removed {
+		t.Fatalf("revoke() = %v, %v", removed, err)
+	}
+	if a.isAllowed(99, 99) {
+		t.Error("user 99 should be denied after revoke()")
+	}
+
+	// Denied attempts end up in the audit log
+	a.recordDenied(&tgbot.Message{
+		From: &tgbot.User{ID: 99, UserName: "stranger"},
+		Chat: &tgbot.Chat{ID: 99},
+		Text: "/list",
+	})
+	denied, err := a.recentDenied(5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(denied) != 1 || denied[0].UserID != 99 || denied[0].Text != "/list" {
+		t.Errorf("recentDenied() = %+v, want one attempt by user 99", denied)
+	}
+}
+
+func TestRecordDeniedLimits(t *testing.T) {
+	if err := initDB(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	a, err := newAccessControl(db, &Config{PrivateMode: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	a.now = func() time.Time { return now }
+	attempt := func(userID int64, text string) {
+		a.recordDenied(&tgbot.Message{From: &tgbot.User{ID: userID}, Chat: &tgbot.Chat{ID: userID}, Text: text})
+	}
+
+	// Long texts are cut by characters, not bytes
+	attempt(1, strings.Repeat("é", 150))
+	// Repeated attempts within deniedLogInterval are not recorded
+	attempt(1, "again")
+	attempt(2, "other user")
+	now = now.Add(deniedLogInterval)
+	attempt(1, "later")
+
+	denied, err := a.recentDenied(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(denied) != 3 || denied[0].Text != "later" || denied[2].Text != strings.Repeat("é", deniedTextLimit) {
+		t.Errorf("recentDenied() = %+v", denied)
+	}
+
+	// Old entries are dropped
+	now = now.Add(deniedRetention + time.Hour)
+	attempt(3, "much later")
+	if denied, _ := a.recentDenied(10); len(denied) != 1 || denied[0].UserID != 3 {
+		t.Errorf("recentDenied() after retention = %+v", denied)
+	}
+}
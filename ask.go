@@ -0,0 +1,281 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbot "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	timecalc "github.com/jgabriele321/onmymind/time"
+)
+
+const askUsage = `Usage: /ask what ideas did I save about onboarding last month?
+The assistant searches, summarizes, groups and tags your notes, citing them as #ID.`
+
+const (
+	// Number of notes a search returns by default and at most
+	askSearchLimit    = 20
+	askSearchMaxLimit = 50
+	// askTextLimit shortens long notes in search results
+	askTextLimit = 300
+)
+
+// "#12" in an answer cites item 12
+var citationPattern = regexp.MustCompile(`#(\d+)\b`)
+
+// askAssistant answers /ask with the OpenRouter assistant, nil when /ask is
+// disabled
+var askAssistant *timecalc.TimeCalculator
+
+// handleAsk implements /ask <question>
+func handleAsk(m *tgbot.Message, budget *llmBudget, prefs *userPrefs) string {
+	return askReply(askAssistant, budget, prefs, m.Chat.ID, m.From.ID, m.CommandArguments(), time.Now())
+}
+
+func askReply(calc *timecalc.TimeCalculator, budget *llmBudget, prefs *userPrefs, chatID, userID int64, question string, now time.Time) string {
+	question = strings.TrimSpace(question)
+	if question == "" {
+		return askUsage
+	}
+	if reason, err := budget.exceeded(); err != nil {
+		log.Printf("Error checking LLM budget: %v", err)
+	} else if reason != "" {
+		return fmt.Sprintf("The AI %s has been reached, please try again later.", reason)
+	}
+
+	loc := prefs.location(userID)
+	answer, usage, err := calc.ChatWithTools(askSystemPrompt(now.In(loc)), question, notesTools(now.In(loc)))
+	if usage.TotalTokens > 0 || usage.Cost > 0 {
+		budget.record(chatID, userID, "ask", usage)
+	}
+	if err != nil {
+		log.Printf("Error answering /ask: %v", err)
+		return fmt.Sprintf("Error: %v", err)
+	}
+	if sources := citedItems(answer); sources != "" {
+		answer += "\n\n" + sources
+	}
+	return answer
+}
+
+// askSystemPrompt tells the assistant what it can do with the notes
+func askSystemPrompt(now time.Time) string {
+	return fmt.Sprintf(`You are an assistant for a personal notes bot. The user saves notes, ideas, links,
+photos, voice notes and files as items, each with a numeric ID, and can tag them.
+
+Answer the user's question about their notes using the tools:
+- SearchNotes finds notes by keyword, kind, tag and date range. Keywords match
+  text literally, so search for short keywords and try synonyms or related words.
+- GetNote shows the full text of a note.
+- ListTags shows how notes are tagged.
+- TagNote tags notes, but only when the user asks for it.
+
+Summarize, compare or group notes yourself from the search results. Never
+invent notes: every note you mention must come from a tool result and be cited
+by its ID, e.g. "#12". If nothing matches, say so. Answer briefly in plain text.
+
+Today is %s in the user's time zone (%s).`,
+		now.Format("Monday 2006-01-02 15:04"), now.Location())
+}
+
+// notesTools lets the assistant search and tag items. Dates are read in
+// the time zone of now.
+func notesTools(now time.Time) *timecalc.ToolRegistry {
+	tools := timecalc.NewToolRegistry()
+	tools.MustRegister(
+		&timecalc.FuncTool{
+			ToolName: "SearchNotes",
+			Desc:     "Finds notes, newest first. Every filter is optional; without any it lists the latest notes.",
+			Parameters: []timecalc.Param{
+				{Name: "query", Description: "text the note must contain, case-insensitive"},
+				{Name: "kind", Description: "item kind: " + strings.Join(itemKinds, ", ")},
+				{Name: "tag", Description: "tag the note must have"},
+				{Name: "since", Description: "first day to include, YYYY-MM-DD"},
+				{Name: "until", Description: "last day to include, YYYY-MM-DD"},
+				{Name: "limit", Type: timecalc.ParamInteger, Description: fmt.Sprintf("maximum number of notes, default %d, at most %d", askSearchLimit, askSearchMaxLimit)},
+			},
+			Run: func(args timecalc.Args) (string, error) {
+				return searchNotes(args, now)
+			},
+		},
+		&timecalc.FuncTool{
+			ToolName: "GetNote",
+			Desc:     "Returns a note with its full text, kind, date and tags.",
+			Parameters: []timecalc.Param{
+				{Name: "id", Type: timecalc.ParamInteger, Required: true, Description: "note ID"},
+			},
+			Run: func(args timecalc.Args) (string, error) {
+				item, err := store.Get(int64(args.Int("id")))
+				if err == errItemNotFound {
+					return fmt.Sprintf("No note #%d.", args.Int("id")), nil
+				} else if err != nil {
+					return "", err
+				}
+				tags, err := store.Tags(item.ID)
+				if err != nil {
+					return "", err
+				}
+				return formatNote(item, tags, now.Location(), 0), nil
+			},
+		},
+		&timecalc.FuncTool{
+			ToolName: "ListTags",
+			Desc:     "Returns every tag with the number of notes that have it.",
+			Run: func(timecalc.Args) (string, error) {
+				return listTagCounts()
+			},
+		},
+		&timecalc.FuncTool{
+			ToolName: "TagNote",
+			Desc:     "Adds tags to a note.",
+			Parameters: []timecalc.Param{
+				{Name: "id", Type: timecalc.ParamInteger, Required: true, Description: "note ID"},
+				{Name: "tags", Required: true, Description: "tags separated by spaces or commas"},
+			},
+			Run: func(args timecalc.Args) (string, error) {
+				return tagItem(int64(args.Int("id")), strings.Fields(args.String("tags"))), nil
+			},
+		},
+	)
+	return tools
+}
+
+// searchNotes implements the SearchNotes tool
+func searchNotes(args timecalc.Args, now time.Time) (string, error) {
+	kind := strings.ToLower(args.String("kind"))
+	if kind != "" && !slices.Contains(itemKinds, kind) {
+		return "", fmt.Errorf("unknown kind %q, use one of %s", kind, strings.Join(itemKinds, ", "))
+	}
+	var since, until time.Time
+	if s := args.String("since"); s != "" {
+		d, err := timecalc.ParseDate(s, now)
+		if err != nil {
+			return "", fmt.Errorf("invalid since date: %v", err)
+		}
+		since = d
+	}
+	if s := args.String("until"); s != "" {
+		d, err := timecalc.ParseDate(s, now)
+		if err != nil {
+			return "", fmt.Errorf("invalid until date: %v", err)
+		}
+		until = d.AddDate(0, 0, 1)
+	}
+	limit := args.Int("limit")
+	if limit <= 0 || limit > askSearchMaxLimit {
+		limit = askSearchLimit
+	}
+
+	items, err := store.Search(args.String("query"), ListOptions{Kind: kind})
+	if err != nil {
+		return "", err
+	}
+	tags, err := store.AllTags()
+	if err != nil {
+		return "", err
+	}
+	tag := strings.ToLower(strings.TrimLeft(strings.TrimSpace(args.String("tag")), "#"))
+
+	var lines []string
+	matches := 0
+	for _, item := range items {
+		if (!since.IsZero() && item.CreatedAt.Before(since)) ||
+			(!until.IsZero() && !item.CreatedAt.Before(until)) ||
+			(tag != "" && !slices.Contains(tags[item.ID], tag)) {
+			continue
+		}
+		matches++
+		if len(lines) < limit {
+			lines = append(lines, formatNote(item, tags[item.ID], now.Location(), askTextLimit))
+		}
+	}
+	if matches == 0 {
+		return "No notes found.", nil
+	}
+	header := fmt.Sprintf("%d notes found", matches)
+	if matches > len(lines) {
+		header += fmt.Sprintf(", showing the newest %d", len(lines))
+	}
+	return header + ":\n" + strings.Join(lines, "\n"), nil
+}
+
+// formatNote describes an item for the assistant, shortening its text to
+// maxRunes unless that is 0
+func formatNote(item Item, tags []string, loc *time.Location, maxRunes int) string {
+	text := item.Text
+	if text == "" {
+		text = "(no text)"
+	} else if runes := []rune(text); maxRunes > 0 && len(runes) > maxRunes {
+		text = string(runes[:maxRunes]) + "…"
+	}
+	s := fmt.Sprintf("#%d · %s · %s · %s", item.ID, item.CreatedAt.In(loc).Format("2006-01-02 15:04"), item.Kind, text)
+	if len(tags) > 0 {
+		s += " · tags: " + strings.Join(tags, ", ")
+	}
+	return s
+}
+
+// listTagCounts implements the ListTags tool
+func listTagCounts() (string, error) {
+	tags, err := store.AllTags()
+	if err != nil {
+		return "", err
+	}
+	counts := make(map[string]int)
+	for _, itemTags := range tags {
+		for _, tag := range itemTags {
+			counts[tag]++
+		}
+	}
+	if len(counts) == 0 {
+		return "No notes are tagged.", nil
+	}
+
+	names := make([]string, 0, len(counts))
+	for tag := range counts {
+		names = append(names, tag)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if counts[names[i]] != counts[names[j]] {
+			return counts[names[i]] > counts[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	lines := make([]string, len(names))
+	for i, tag := range names {
+		lines[i] = fmt.Sprintf("%s: %d", tag, counts[tag])
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// citedItems lists the items an answer cites, or returns "" if it cites
+// none that exist
+func citedItems(answer string) string {
+	seen := make(map[int64]bool)
+	var lines []string
+	for _, match := range citationPattern.FindAllStringSubmatch(answer, -1) {
+		id, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil || seen[id] {
+			continue
+		}
+		seen[id] = true
+		item, err := store.Get(id)
+		if err != nil {
+			continue
+		}
+		text := item.Text
+		if runes := []rune(text); len(runes) > 60 {
+			text = string(runes[:60]) + "…"
+		}
+		lines = append(lines, fmt.Sprintf("• #%d %s%s", item.ID, kindIcon(item.Kind), text))
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return "📎 Sources:\n" + strings.Join(lines, "\n")
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	timecalc "github.com/jgabriele321/onmymind/time"
+)
+
+// addNotes stores test items and returns their IDs
+func addNotes(t *testing.T, items ...Item) []int64 {
+	var ids []int64
+	for _, item := range items {
+		id, err := store.Add(item)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func TestNotesTools(t *testing.T) {
+	if err := initDB(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ids := addNotes(t,
+		Item{Text: "Onboarding idea: pair new hires with a buddy", CreatedAt: time.Date(2024, 5, 3, 10, 0, 0, 0, time.UTC)},
+		Item{Text: "Onboarding checklist should include laptop setup", CreatedAt: time.Date(2024, 5, 31, 23, 30, 0, 0, time.UTC)},
+		Item{Text: "Buy milk", CreatedAt: time.Date(2024, 6, 2, 8, 0, 0, 0, time.UTC)},
+		Item{Text: "onboarding survey results", Kind: kindDocument, CreatedAt: time.Date(2024, 6, 5, 9, 0, 0, 0, time.UTC)},
+	)
+	if err := store.AddTags(ids[0], []string{"ideas", "hr"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.AddTags(ids[1], []string{"hr"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Late on May 31 UTC is already June 1 in Tokyo
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tools := notesTools(time.Date(2024, 6, 10, 12, 0, 0, 0, tokyo))
+
+	tests := []struct {
+		tool    string
+		args    string
+		want    []string
+		notWant []string
+		wantErr bool
+	}{
+		{tool: "SearchNotes", args: `{"query": "onboarding"}`,
+			want: []string{"3 notes found:\n#4 · 2024-06-05 18:00 · document · onboarding survey results", "#1 · 2024-05-03 19:00 · text · Onboarding idea: pair new hires with a buddy · tags: hr, ideas"}},
+		{tool: "SearchNotes", args: `{"query": "onboarding", "since": "2024-05-01", "until": "2024-05-31"}`,
+			want: []string{"1 notes found", "#1 "}, notWant: []string{"#2 "}},
+		{tool: "SearchNotes", args: `{"since": "2024-06-01", "until": "2024-06-01"}`,
+			want: []string{"1 notes found", "#2 · 2024-06-01 08:30"}},
+		{tool: "SearchNotes", args: `{"tag": "#HR", "limit": 1}`,
+			want: []string{"2 notes found, showing the newest 1:\n#2 "}, notWant: []string{"#1 "}},
+		{tool: "SearchNotes", args: `{"kind": "document"}`, want: []string{"1 notes found", "#4 "}},
+		{tool: "SearchNotes", args: `{"query": "kangaroo"}`, want: []string{"No notes found."}},
+		{tool: "SearchNotes", args: `{"kind": "poem"}`, wantErr: true},
+		{tool: "SearchNotes", args: `{"since": "someday"}`, wantErr: true},
+		{tool: "GetNote", args: `{"id": 3}`, want: []string{"#3 · 2024-06-02 17:00 · text · Buy milk"}},
+		{tool: "GetNote", args: `{"id": 99}`, want: []string{"No note #99."}},
+		{tool: "ListTags", args: `{}`, want: []string{"hr: 2\nideas: 1"}},
+		{tool: "TagNote", args: `{"id": 3, "tags": "errands, shopping"}`, want: []string{"#3 tagged: errands, shopping"}},
+		{tool: "TagNote", args: `{"id": 3}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tool+" "+tt.args, func(t *testing.T) {
+			got, err := tools.ExecuteJSON(tt.tool, tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("%s(%s) error = %v, wantErr %v", tt.tool, tt.args, err, tt.wantErr)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("%s(%s) = %q, want it to contain %q", tt.tool, tt.args, got, want)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(got, notWant) {
+					t.Errorf("%s(%s) = %q, want it not to contain %q", tt.tool, tt.args, got, notWant)
+				}
+			}
+		})
+	}
+}
+
+func TestAskReply(t *testing.T) {
+	if err := initDB(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	addNotes(t,
+		Item{Text: "Onboarding idea: pair new hires with a buddy"},
+		Item{Text: "Buy milk"},
+	)
+
+	// The model searches once, then answers citing a real and a made up note
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req timecalc.OpenRouterRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatal(err)
+		}
+		var reply timecalc.Message
+		if requests == 0 {
+			if !strings.Contains(req.Messages[0].Content, "in the user's time zone (Asia/Kolkata)") {
+				t.Errorf("system prompt = %q", req.Messages[0].Content)
+			}
+			var call timecalc.ToolCall
+			call.ID, call.Type = "1", "function"
+			call.Function.Name, call.Function.Arguments = "SearchNotes", `{"query": "onboarding"}`
+			reply.ToolCalls = []timecalc.ToolCall{call}
+		} else {
+			result := req.Messages[len(req.Messages)-1]
+			if result.Role != "tool" || !strings.Contains(result.Content, "#1 ") {
+				t.Errorf("tool result = %+v", result)
+			}
+			reply.Content = "You saved one onboarding idea: pairing new hires with a buddy (#1, see also #7)."
+		}
+		requests++
+		json.NewEncoder(w).Encode(map[string]any{
+			"choices": []map[string]any{{"message": reply}},
+			"usage":   map[string]any{"total_tokens": 100, "cost": 0.01},
+		})
+	}))
+	defer server.Close()
+
+	calc := timecalc.NewTimeCalculator("key").WithBaseURL(server.URL)
+	budget := newLLMBudget(db, &Config{})
+	prefs := newUserPrefs(db)
+	const user = 5
+	prefs.handleSetTZ(commandMessage(user, "/settz Kolkata"))
+
+	if got := askReply(calc, budget, prefs, user, user, " ", time.Now()); got != askUsage {
+		t.Errorf("empty question = %q", got)
+	}
+
+	got := askReply(calc, budget, prefs, user, user, "what ideas did I save about onboarding?", time.Now())
+	want := "You saved one onboarding idea: pairing new hires with a buddy (#1, see also #7).\n\n" +
+		"📎 Sources:\n• #1 Onboarding idea: pair new hires with a buddy"
+	if got != want {
+		t.Errorf("askReply() = %q, want %q", got, want)
+	}
+
+	totals, err := budget.totals(time.Time{}, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if totals.Calls != 1 || totals.Tokens != 200 {
+		t.Errorf("recorded usage = %+v, want one call with 200 tokens", totals)
+	}
+
+	// Over budget the model isn't asked
+	budget.dailyTokens = 100
+	if got := askReply(calc, budget, prefs, user, user, "anything?", time.Now()); !strings.Contains(got, "has been reached") || requests != 2 {
+		t.Errorf("over budget = %q after %d requests", got, requests)
+	}
+}
@@ -0,0 +1,381 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jgabriele321/onmymind/objectstore"
+	"github.com/mattn/go-sqlite3"
+)
+
+// Snapshot files are named mind-<UTC timestamp>.db so they sort by age
+const (
+	snapshotPrefix = "mind-"
+	snapshotSuffix = ".db"
+	snapshotLayout = "20060102-150405"
+)
+
+// snapshotInfo describes one stored snapshot
+type snapshotInfo struct {
+	Name    string
+	Size    int64
+	TakenAt time.Time
+}
+
+// backupScheduler writes rotated snapshots of the database to a local
+// directory and, optionally, to S3-compatible storage
+type backupScheduler struct {
+	db       *sql.DB
+	dir      string
+	keep     int
+	interval time.Duration
+	store    objectstore.Store // nil when remote upload is off
+	prefix   string            // key prefix inside the bucket
+
+	mu      sync.Mutex // Serializes snapshots and guards the fields below
+	lastRun time.Time
+	lastErr error
+	now     func() time.Time
+}
+
+// newBackupScheduler creates a scheduler from the configuration
+func newBackupScheduler(db *sql.DB, cfg *Config) *backupScheduler {
+	dir := cfg.BackupDir
+	if dir == "" {
+		dir = filepath.Join(cfg.DataDir, "backups")
+	}
+	// Checked by cfg.Validate
+	interval, _ := parseBackupInterval(cfg.BackupInterval)
+	if cfg.DatabaseURL != "" {
+		// Postgres is backed up by its provider
+		interval = 0
+	}
+
+	s := &backupScheduler{
+		db:       db,
+		dir:      dir,
+		keep:     cfg.BackupKeep,
+		interval: interval,
+		prefix:   cfg.S3Prefix,
+		now:      time.Now,
+	}
+	if cfg.S3Bucket != "" {
+		s.store = objectstore.NewS3Client(cfg.S3Endpoint, cfg.S3Region, cfg.S3Bucket, cfg.S3AccessKey, cfg.S3SecretKey)
+	}
+	return s
+}
+
+// parseBackupInterval parses BACKUP_INTERVAL; "" and "0" disable backups
+func parseBackupInterval(s string) (time.Duration, error) {
+	if s == "" || s == "0" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	if d < time.Minute {
+		return 0, fmt.Errorf("%s is shorter than a minute", s)
+	}
+	return d, nil
+}
+
+// String describes the backup setup for the startup log
+func (s *backupScheduler) String() string {
+	if s.interval == 0 {
+		return "Backups: disabled"
+	}
+	desc := fmt.Sprintf("Backups: every %s to %s, keeping %d", s.interval, s.dir, s.keep)
+	if s.store != nil {
+		desc += ", uploaded to S3"
+	}
+	return desc
+}
+
+// start takes a snapshot every interval until ctx is done
+func (s *backupScheduler) start(ctx context.Context) {
+	if s.interval == 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if name, err := s.snapshot(ctx); err != nil {
+					log.Printf("Error creating backup: %v", err)
+				} else {
+					log.Printf("Created backup %s", name)
+				}
+			}
+		}
+	}()
+}
+
+// snapshot copies the live database with SQLite's online backup API,
+// uploads the copy if configured and applies the retention policy. It
+// returns the snapshot file name.
+func (s *backupScheduler) snapshot(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	name, err := s.takeSnapshot(ctx)
+	s.lastRun, s.lastErr = s.now(), err
+	return name, err
+}
+
+func (s *backupScheduler) takeSnapshot(ctx context.Context) (string, error) {
+	if dbDialect != dialectSQLite {
+		return "", errors.New("snapshots are only available for SQLite")
+	}
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create backup directory: %v", err)
+	}
+
+	name := snapshotPrefix + s.now().UTC().Format(snapshotLayout) + snapshotSuffix
+	dest := filepath.Join(s.dir, name)
+	tmp := dest + ".tmp"
+	os.Remove(tmp)
+
+	if err := backupDatabase(ctx, s.db, tmp); err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+	if err := os.Rename(tmp, dest); err != nil {
+		os.Remove(tmp)
+		return "", fmt.Errorf("failed to store snapshot: %v", err)
+	}
+
+	var errs []error
+	if s.store != nil {
+		data, err := os.ReadFile(dest)
+		if err == nil {
+			err = s.store.Put(ctx, s.prefix+name, data)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("upload failed: %v", err))
+		}
+	}
+	if err := s.rotate(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("rotation failed: %v", err))
+	}
+	return name, errors.Join(errs...)
+}
+
+// backupDatabase copies src into a new SQLite file at destPath while the
+// bot keeps running
+func backupDatabase(ctx context.Context, src *sql.DB, destPath string) error {
+	destDB, err := sql.Open("sqlite3", destPath)
+	if err != nil {
+		return fmt.Errorf("failed to open snapshot: %v", err)
+	}
+	defer destDB.Close()
+
+	destConn, err := destDB.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to open snapshot: %v", err)
+	}
+	defer destConn.Close()
+
+	srcConn, err := src.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %v", err)
+	}
+	defer srcConn.Close()
+
+	return destConn.Raw(func(destRaw any) error {
+		return srcConn.Raw(func(srcRaw any) error {
+			backup, err := destRaw.(*sqlite3.SQLiteConn).Backup("main", srcRaw.(*sqlite3.SQLiteConn), "main")
+			if err != nil {
+				return fmt.Errorf("failed to start backup: %v", err)
+			}
+			// Copy in chunks so writers are only blocked briefly
+			for {
+				done, err := backup.Step(256)
+				if err != nil {
+					backup.Finish()
+					return fmt.Errorf("backup failed: %v", err)
+				}
+				if done {
+					break
+				}
+			}
+			return backup.Finish()
+		})
+	})
+}
+
+// isSnapshotName reports whether a file name was written by snapshot
+func isSnapshotName(name string) bool {
+	stamp, ok := strings.CutPrefix(name, snapshotPrefix)
+	if !ok {
+		return false
+	}
+	stamp, ok = strings.CutSuffix(stamp, snapshotSuffix)
+	if !ok {
+		return false
+	}
+	_, err := time.Parse(snapshotLayout, stamp)
+	return err == nil
+}
+
+// snapshotTime extracts the time from a snapshot name
+func snapshotTime(name string) time.Time {
+	stamp := strings.TrimSuffix(strings.TrimPrefix(name, snapshotPrefix), snapshotSuffix)
+	t, _ := time.Parse(snapshotLayout, stamp)
+	return t
+}
+
+// localSnapshots lists the snapshots in the backup directory, oldest first
+func (s *backupScheduler) localSnapshots() ([]snapshotInfo, error) {
+	entries, err := os.ReadDir(s.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var snapshots []snapshotInfo
+	for _, e := range entries {
+		if e.IsDir() || !isSnapshotName(e.Name()) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, snapshotInfo{Name: e.Name(), Size: info.Size(), TakenAt: snapshotTime(e.Name())})
+	}
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].Name < snapshots[j].Name })
+	return snapshots, nil
+}
+
+// remoteSnapshots lists the uploaded snapshots, oldest first
+func (s *backupScheduler) remoteSnapshots(ctx context.Context) ([]snapshotInfo, error) {
+	objects, err := s.store.List(ctx, s.prefix)
+	if err != nil {
+		return nil, err
+	}
+	var snapshots []snapshotInfo
+	for _, o := range objects {
+		name := path.Base(o.Key)
+		if strings.TrimPrefix(o.Key, s.prefix) != name || !isSnapshotName(name) {
+			continue
+		}
+		snapshots = append(snapshots, snapshotInfo{Name: name, Size: o.Size, TakenAt: snapshotTime(name)})
+	}
+	return snapshots, nil
+}
+
+// rotate deletes all but the newest keep snapshots, locally and remotely
+func (s *backupScheduler) rotate(ctx context.Context) error {
+	local, err := s.localSnapshots()
+	if err != nil {
+		return err
+	}
+	for _, snap := range expired(local, s.keep) {
+		if err := os.Remove(filepath.Join(s.dir, snap.Name)); err != nil {
+			return err
+		}
+	}
+
+	if s.store == nil {
+		return nil
+	}
+	remote, err := s.remoteSnapshots(ctx)
+	if err != nil {
+		return err
+	}
+	for _, snap := range expired(remote, s.keep) {
+		if err := s.store.Delete(ctx, s.prefix+snap.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// expired returns the snapshots beyond the newest keep, given oldest first
+func expired(snapshots []snapshotInfo, keep int) []snapshotInfo {
+	if keep <= 0 || len(snapshots) <= keep {
+		return nil
+	}
+	return snapshots[:len(snapshots)-keep]
+}
+
+// handleBackups implements the admin command /backups [now]
+func (s *backupScheduler) handleBackups(args string) string {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	switch strings.ToLower(strings.TrimSpace(args)) {
+	case "":
+	case "now":
+		name, err := s.snapshot(ctx)
+		if name == "" {
+			log.Printf("Error creating backup: %v", err)
+			return "❌ Backup failed."
+		}
+		if err != nil {
+			log.Printf("Error finishing backup %s: %v", name, err)
+			return fmt.Sprintf("⚠️ Saved %s, but: %v", name, err)
+		}
+		return fmt.Sprintf("✅ Saved %s", name)
+	default:
+		return "Usage: /backups [now]"
+	}
+
+	lines := []string{s.String()}
+	s.mu.Lock()
+	if !s.lastRun.IsZero() {
+		status := "ok"
+		if s.lastErr != nil {
+			status = s.lastErr.Error()
+		}
+		lines = append(lines, fmt.Sprintf("Last run: %s (%s)", s.lastRun.Format("2006-01-02 15:04"), status))
+	}
+	s.mu.Unlock()
+
+	local, err := s.localSnapshots()
+	if err != nil {
+		log.Printf("Error listing backups: %v", err)
+		return "❌ Failed to list backups."
+	}
+	lines = append(lines, "", fmt.Sprintf("💾 Local snapshots (%d):", len(local)))
+	lines = append(lines, formatSnapshots(local)...)
+
+	if s.store != nil {
+		remote, err := s.remoteSnapshots(ctx)
+		if err != nil {
+			log.Printf("Error listing remote backups: %v", err)
+			lines = append(lines, "", "☁️ Remote snapshots: unavailable")
+		} else {
+			lines = append(lines, "", fmt.Sprintf("☁️ Remote snapshots (%d):", len(remote)))
+			lines = append(lines, formatSnapshots(remote)...)
+		}
+	}
+	lines = append(lines, "", "Use /backups now to take a snapshot.")
+	return strings.Join(lines, "\n")
+}
+
+// formatSnapshots lists snapshots newest first
+func formatSnapshots(snapshots []snapshotInfo) []string {
+	var lines []string
+	for i := len(snapshots) - 1; i >= 0; i-- {
+		snap := snapshots[i]
+		lines = append(lines, fmt.Sprintf("• %s (%.1f KB)", snap.TakenAt.Format("2006-01-02 15:04"), float64(snap.Size)/1024))
+	}
+	return lines
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jgabriele321/onmymind/objectstore"
+)
+
+func TestBackupScheduler(t *testing.T) {
+	dir := t.TempDir()
+	if err := initDB(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if _, err := storeCapturedItem(capturedItem{Text: "keep me safe", Source: sourceMessage}, 1, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	store := objectstore.NewMemory()
+	clock := time.Date(2024, 3, 1, 3, 0, 0, 0, time.UTC)
+	s := &backupScheduler{
+		db:       db,
+		dir:      filepath.Join(dir, "backups"),
+		keep:     2,
+		interval: time.Hour,
+		store:    store,
+		prefix:   "mindbot/",
+		now:      func() time.Time { return clock },
+	}
+
+	ctx := context.Background()
+	var names []string
+	for i := 0; i < 3; i++ {
+		name, err := s.snapshot(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, name)
+		clock = clock.Add(time.Hour)
+	}
+	if names[0] != "mind-20240301-030000.db" {
+		t.Errorf("snapshot name = %q", names[0])
+	}
+
+	local, err := s.localSnapshots()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(local) != 2 || local[0].Name != names[1] || local[1].Name != names[2] {
+		t.Errorf("local snapshots after rotation = %+v, want the newest two", local)
+	}
+	if _, ok := store.Objects["mindbot/"+names[0]]; ok || len(store.Objects) != 2 {
+		t.Errorf("remote snapshots after rotation = %d objects", len(store.Objects))
+	}
+
+	// The snapshot is a complete, readable database
+	snap, err := sql.Open("sqlite3", filepath.Join(s.dir, names[2]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer snap.Close()
+	var text string
+	if err := snap.QueryRow("SELECT text FROM items").Scan(&text); err != nil || text != "keep me safe" {
+		t.Errorf("snapshot item = %q, %v", text, err)
+	}
+
+	if status := s.handleBackups(""); !strings.Contains(status, "Local snapshots (2)") || !strings.Contains(status, "Remote snapshots (2)") {
+		t.Errorf("handleBackups() = %q", status)
+	}
+}
+
+func TestParseBackupInterval(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"", 0, false},
+		{"0", 0, false},
+		{"6h", 6 * time.Hour, false},
+		{"10s", 0, true},
+		{"daily", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parseBackupInterval(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseBackupInterval(%q) = %v, %v", tt.in, got, err)
+		}
+	}
+}
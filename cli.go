@@ -0,0 +1,404 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	tgbot "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const cliUsage = `Usage: mindbot [flags] <command> [args]
+
+Without a command the Telegram bot is started.
+
+Commands:
+  items list [--kind KIND] [--limit N]   list stored items, newest first
+  items add TEXT...                      add a text item
+  items rm ID...                         delete items (kept in deleted items)
+  export [--format FORMAT] [-o FILE]     write an export (json, zip, md, csv, obsidian)
+  import [--replace] FILE                import an /export backup (merges by default)
+  backup [-o FILE]                       write a consistent snapshot of the database
+  migrate [--from-sqlite FILE]           create or upgrade the database schema; with
+                                         DATABASE_URL set, optionally copy a mind.db
+  vacuum                                 compact the database file
+  stats                                  show database statistics
+`
+
+// cliCommands are the subcommands that run instead of the bot
+var cliCommands = map[string]func(cfg *Config, args []string, out io.Writer) error{
+	"items":   cliItems,
+	"export":  cliExport,
+	"import":  cliImport,
+	"backup":  cliBackup,
+	"migrate": cliMigrate,
+	"vacuum":  cliVacuum,
+	"stats":   cliStats,
+}
+
+// errUsage makes runCLI print the usage text
+var errUsage = errors.New("invalid usage")
+
+// runCLI runs an administration command against the database in
+// cfg.DataDir and returns the process exit code
+func runCLI(cfg *Config, args []string, out, errOut io.Writer) int {
+	if len(args) == 0 || args[0] == "help" {
+		fmt.Fprint(out, cliUsage)
+		return 0
+	}
+	cmd, ok := cliCommands[args[0]]
+	if !ok {
+		fmt.Fprintf(errOut, "Unknown command %q\n\n%s", args[0], cliUsage)
+		return 2
+	}
+
+	if err := openDatabase(cfg); err != nil {
+		fmt.Fprintf(errOut, "Failed to open database: %v\n", err)
+		return 1
+	}
+	defer db.Close()
+
+	if err := cmd(cfg, args[1:], out); err != nil {
+		if errors.Is(err, errUsage) || errors.Is(err, flag.ErrHelp) {
+			fmt.Fprint(errOut, cliUsage)
+			return 2
+		}
+		fmt.Fprintf(errOut, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// newFlagSet creates a flag set whose errors are reported by runCLI
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	return fs
+}
+
+// cliItems implements items list|add|rm
+func cliItems(cfg *Config, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+	switch args[0] {
+	case "list":
+		fs := newFlagSet("items list")
+		kind := fs.String("kind", "", "only items of this kind")
+		limit := fs.Int("limit", 0, "maximum number of items")
+		if err := fs.Parse(args[1:]); err != nil {
+			return errUsage
+		}
+		return listItems(out, *kind, *limit)
+	case "add":
+		text := strings.TrimSpace(strings.Join(args[1:], " "))
+		if text == "" {
+			return errUsage
+		}
+		id, err := store.Add(Item{Text: text, Source: sourceCLI})
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Added #%d: %s\n", id, text)
+		return nil
+	case "rm":
+		if len(args) < 2 {
+			return errUsage
+		}
+		for _, arg := range args[1:] {
+			id, err := strconv.ParseInt(strings.TrimPrefix(arg, "#"), 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid item ID %q", arg)
+			}
+			removed, err := store.Delete(id)
+			if err != nil {
+				return err
+			}
+			if removed {
+				fmt.Fprintf(out, "Deleted #%d\n", id)
+			} else {
+				fmt.Fprintf(out, "No item #%d\n", id)
+			}
+		}
+		return nil
+	}
+	return errUsage
+}
+
+// listItems prints items, newest first
+func listItems(out io.Writer, kind string, limit int) error {
+	if kind != "" && !slices.Contains(itemKinds, kind) {
+		return fmt.Errorf("unknown kind %q", kind)
+	}
+	items, err := store.List(ListOptions{Kind: kind, Limit: limit})
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tCREATED\tKIND\tTEXT")
+	for _, item := range items {
+		text := strings.ReplaceAll(item.Text, "\n", " ")
+		if r := []rune(text); len(r) > 80 {
+			text = string(r[:79]) + "…"
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", item.ID, item.CreatedAt.Format("2006-01-02 15:04"), item.Kind, text)
+	}
+	return tw.Flush()
+}
+
+// cliExport writes an export to a file or stdout. Formats that bundle
+// media download it from Telegram, which needs BOT_TOKEN.
+func cliExport(cfg *Config, args []string, out io.Writer) error {
+	fs := newFlagSet("export")
+	format := fs.String("format", "json", "export format")
+	output := fs.String("o", "", "output file (default mindbot-<format>-<time><ext>, - for stdout)")
+	if err := fs.Parse(args); err != nil || fs.NArg() > 0 {
+		return errUsage
+	}
+	exp, ok := exporters[*format]
+	if !ok {
+		return fmt.Errorf("unknown format %q, use one of %s", *format, strings.Join(exportFormats(), ", "))
+	}
+
+	backup, err := buildBackup()
+	if err != nil {
+		return err
+	}
+
+	var bot *tgbot.BotAPI
+	fetch := func(fileID string) ([]byte, error) {
+		if cfg.BotToken == "" {
+			return nil, errors.New("BOT_TOKEN is needed to download media")
+		}
+		if bot == nil {
+			b, err := tgbot.NewBotAPI(cfg.BotToken)
+			if err != nil {
+				return nil, err
+			}
+			bot = b
+		}
+		return downloadTelegramFile(bot, fileID)
+	}
+
+	result, err := exp.Export(backup, fetch)
+	if err != nil {
+		return err
+	}
+
+	name := *output
+	if name == "-" {
+		_, err := out.Write(result.Data)
+		return err
+	}
+	if name == "" {
+		name = fmt.Sprintf("mindbot-%s-%s%s", exportPrefix(*format), time.Now().Format("2006-01-02-150405"), exp.Extension())
+	}
+	if err := os.WriteFile(name, result.Data, 0644); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Wrote %s: %d items, %d deleted items\n", name, len(backup.Items), len(backup.DeletedItems))
+	if result.Note != "" {
+		fmt.Fprintln(out, result.Note)
+	}
+	return nil
+}
+
+// cliImport imports a backup file
+func cliImport(cfg *Config, args []string, out io.Writer) error {
+	fs := newFlagSet("import")
+	replace := fs.Bool("replace", false, "delete all stored data first")
+	if err := fs.Parse(args); err != nil || fs.NArg() != 1 {
+		return errUsage
+	}
+
+	data, err := os.ReadFile(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	backup, err := parseBackup(data)
+	if err != nil {
+		return err
+	}
+
+	mode := importMerge
+	if *replace {
+		mode = importReplace
+	}
+	result, err := applyImport(backup, mode)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(out, result)
+	return nil
+}
+
+// cliBackup writes a snapshot with the online backup API
+func cliBackup(cfg *Config, args []string, out io.Writer) error {
+	fs := newFlagSet("backup")
+	output := fs.String("o", "", "output file (default mind-<time>.db)")
+	if err := fs.Parse(args); err != nil || fs.NArg() > 0 {
+		return errUsage
+	}
+	if dbDialect != dialectSQLite {
+		return errors.New("backup only works on SQLite; use pg_dump for Postgres")
+	}
+	name := *output
+	if name == "" {
+		name = snapshotPrefix + time.Now().UTC().Format(snapshotLayout) + snapshotSuffix
+	}
+	if err := backupDatabase(context.Background(), db, name); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Wrote %s\n", name)
+	return nil
+}
+
+// cliMigrate reports the schema, which opening the database already
+// migrated, and optionally copies a SQLite database into Postgres
+func cliMigrate(cfg *Config, args []string, out io.Writer) error {
+	fs := newFlagSet("migrate")
+	from := fs.String("from-sqlite", "", "mind.db to copy into the Postgres database")
+	if err := fs.Parse(args); err != nil || fs.NArg() > 0 {
+		return errUsage
+	}
+
+	if *from != "" {
+		copied, err := copyFromSQLite(*from)
+		if err != nil {
+			return err
+		}
+		for _, t := range copiedTables {
+			fmt.Fprintf(out, "Copied %d rows into %s\n", copied[t.name], t.name)
+		}
+	}
+
+	query := "SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' ORDER BY name"
+	if dbDialect == dialectPostgres {
+		query = "SELECT table_name FROM information_schema.tables WHERE table_schema = current_schema() ORDER BY table_name"
+	}
+	rows, err := db.Query(query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return err
+		}
+		tables = append(tables, name)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Database schema is up to date (%s)\n", strings.Join(tables, ", "))
+	return nil
+}
+
+// cliVacuum rebuilds the database file to reclaim free pages
+func cliVacuum(cfg *Config, args []string, out io.Writer) error {
+	if dbDialect != dialectSQLite {
+		return errors.New("vacuum only works on SQLite; Postgres vacuums automatically")
+	}
+	path := filepath.Join(cfg.DataDir, "mind.db")
+	before, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if _, err := db.Exec("VACUUM"); err != nil {
+		return err
+	}
+	// VACUUM goes through the WAL; fold it back so the file shrinks
+	if _, err := db.Exec("PRAGMA wal_checkpoint(TRUNCATE)"); err != nil {
+		return err
+	}
+	after, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "Vacuumed %s: %s → %s\n", path, formatBytes(before.Size()), formatBytes(after.Size()))
+	return nil
+}
+
+// cliStats prints item counts and database size
+func cliStats(cfg *Config, args []string, out io.Writer) error {
+	tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+
+	var total int
+	if err := db.QueryRow("SELECT COUNT(*) FROM items").Scan(&total); err != nil {
+		return err
+	}
+	fmt.Fprintf(tw, "Items:\t%d\n", total)
+
+	rows, err := db.Query("SELECT kind, COUNT(*) FROM items GROUP BY kind ORDER BY COUNT(*) DESC, kind")
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var kind string
+		var n int
+		if err := rows.Scan(&kind, &n); err != nil {
+			rows.Close()
+			return err
+		}
+		fmt.Fprintf(tw, "  %s:\t%d\n", kind, n)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, c := range []struct{ label, query string }{
+		{"Deleted items", "SELECT COUNT(*) FROM deleted"},
+		{"Tags", "SELECT COUNT(DISTINCT tag) FROM item_tags"},
+		{"Revisions", "SELECT COUNT(*) FROM item_revisions"},
+		{"Known users", "SELECT COUNT(*) FROM users"},
+	} {
+		var n int
+		if err := db.QueryRow(c.query).Scan(&n); err != nil {
+			return err
+		}
+		fmt.Fprintf(tw, "%s:\t%d\n", c.label, n)
+	}
+
+	if total > 0 {
+		var oldest, newest time.Time
+		if err := db.QueryRow("SELECT created_at FROM items ORDER BY created_at LIMIT 1").Scan(&oldest); err != nil {
+			return err
+		}
+		if err := db.QueryRow("SELECT created_at FROM items ORDER BY created_at DESC LIMIT 1").Scan(&newest); err != nil {
+			return err
+		}
+		fmt.Fprintf(tw, "Oldest item:\t%s\n", oldest.Format("2006-01-02 15:04"))
+		fmt.Fprintf(tw, "Newest item:\t%s\n", newest.Format("2006-01-02 15:04"))
+	}
+	if dbDialect == dialectSQLite {
+		if info, err := os.Stat(filepath.Join(cfg.DataDir, "mind.db")); err == nil {
+			fmt.Fprintf(tw, "Database size:\t%s\n", formatBytes(info.Size()))
+		}
+	}
+	return tw.Flush()
+}
+
+// formatBytes formats a size for humans
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%d B", n)
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunCLI(t *testing.T) {
+	cfg := &Config{DataDir: t.TempDir()}
+	backupFile := filepath.Join(cfg.DataDir, "backup.json")
+
+	tests := []struct {
+		args     []string
+		wantCode int
+		wantOut  string
+	}{
+		{[]string{"items", "add", "buy", "milk"}, 0, "Added #1: buy milk"},
+		{[]string{"items", "add", "call mom"}, 0, "Added #2: call mom"},
+		{[]string{"items", "list", "--limit", "1"}, 0, "call mom"},
+		{[]string{"items", "list", "--kind", "sticker"}, 1, ""},
+		{[]string{"export", "--format", "json", "-o", backupFile}, 0, "2 items, 0 deleted items"},
+		{[]string{"items", "rm", "1", "#7"}, 0, "Deleted #1\nNo item #7"},
+		{[]string{"stats"}, 0, "Deleted items:  1"},
+		{[]string{"import", backupFile}, 0, "Imported 1 items"},
+		{[]string{"export", "--format", "csv", "-o", "-"}, 0, "id,created_at,deleted_at,kind,text"},
+		{[]string{"migrate"}, 0, "up to date"},
+		{[]string{"items"}, 2, ""},
+		{[]string{"frobnicate"}, 2, ""},
+	}
+	for _, tt := range tests {
+		var out, errOut bytes.Buffer
+		code := runCLI(cfg, tt.args, &out, &errOut)
+		if code != tt.wantCode {
+			t.Errorf("runCLI(%v) = %d, want %d; stderr: %s", tt.args, code, tt.wantCode, errOut.String())
+			continue
+		}
+		if !strings.Contains(out.String(), tt.wantOut) {
+			t.Errorf("runCLI(%v) output = %q, want %q", tt.args, out.String(), tt.wantOut)
+		}
+	}
+}
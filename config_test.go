@@ -0,0 +1,131 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name:  "Plain values",
+			input: "BOT_TOKEN=abc\nPORT = 9000\n",
+			want:  map[string]string{"BOT_TOKEN": "abc", "PORT": "9000"},
+		},
+		{
+			name:  "Comments and blank lines",
+			input: "# comment\n\nDATA_DIR=/data # trailing comment\n",
+			want:  map[string]string{"DATA_DIR": "/data"},
+		},
+		{
+			name:  "Export prefix",
+			input: "export BOT_TOKEN=abc\n",
+			want:  map[string]string{"BOT_TOKEN": "abc"},
+		},
+		{
+			name:  "Quoted values",
+			input: "A=\"hello # world\"\nB='raw \\n value'\nC=\"line\\nbreak\"\n",
+			want:  map[string]string{"A": "hello # world", "B": "raw \\n value", "C": "line\nbreak"},
+		},
+		{
+			name:    "Unterminated quote",
+			input:   "A=\"oops\n",
+			wantErr: true,
+		},
+		{
+			name:    "Missing equals",
+			input:   "JUSTAKEY\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseEnv(strings.NewReader(tt.input))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseEnv() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("parseEnv() = %v, want %v", got, tt.want)
+			}
+			for k, v := range tt.want {
+				if got[k] != v {
+					t.Errorf("parseEnv()[%s] = %q, want %q", k, got[k], v)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	yamlPath := filepath.Join(dir, "mindbot.yaml")
+	if err := os.WriteFile(yamlPath, []byte("data_dir: /from/yaml\nport: 9001\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	envPath := filepath.Join(dir, ".env")
+	if err := os.WriteFile(envPath, []byte("OPENROUTER_KEY=legacy-key-123456\nPORT=9002\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("BOT_TOKEN", "env-token-123456")
+	t.Setenv("OPENROUTER_API_KEY", "")
+	t.Setenv("DATA_DIR", "")
+	t.Setenv("PORT", "")
+
+	cfg, err := LoadConfig(configSources{ConfigFile: yamlPath, EnvFile: envPath})
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if cfg.BotToken != "env-token-123456" {
+		t.Errorf("BotToken = %q, want value from environment", cfg.BotToken)
+	}
+	if cfg.OpenRouterKey != "legacy-key-123456" {
+		t.Errorf("OpenRouterKey = %q, want value from OPENROUTER_KEY alias", cfg.OpenRouterKey)
+	}
+	if cfg.DataDir != "/from/yaml" {
+		t.Errorf("DataDir = %q, want value from config file", cfg.DataDir)
+	}
+	if cfg.Port != 9002 {
+		t.Errorf("Port = %d, want .env to override config file", cfg.Port)
+	}
+
+	var out strings.Builder
+	cfg.Print(&out)
+	if strings.Contains(out.String(), "env-token-123456") || strings.Contains(out.String(), "legacy-key-123456") {
+		t.Errorf("Print() leaked a secret:\n%s", out.String())
+	}
+}
+
+func TestLoadConfigValidation(t *testing.T) {
+	t.Setenv("BOT_TOKEN", "")
+	t.Setenv("TELEGRAM_BOT_TOKEN", "")
+	t.Setenv("OPENROUTER_API_KEY", "")
+	t.Setenv("OPENROUTER_KEY", "")
+	t.Setenv("PORT", "not-a-port")
+
+	if _, err := LoadConfig(configSources{}); err == nil {
+		t.Error("LoadConfig() expected error for invalid PORT")
+	}
+
+	t.Setenv("PORT", "")
+	_, err := LoadConfig(configSources{})
+	if err == nil || !strings.Contains(err.Error(), "BOT_TOKEN is required") {
+		t.Errorf("LoadConfig() error = %v, want missing BOT_TOKEN", err)
+	}
+
+	// CLI commands don't need a token
+	if _, err := LoadConfig(configSources{Offline: true}); err != nil {
+		t.Errorf("LoadConfig(Offline) error = %v", err)
+	}
+}
@@ -0,0 +1,318 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+// Database dialects
+const (
+	dialectSQLite   = "sqlite"
+	dialectPostgres = "postgres"
+)
+
+// dbDialect is the dialect of db, set by initDB and initPostgres
+var dbDialect = dialectSQLite
+
+// postgresSchema mirrors sqliteSchema. IDs are BIGINT because Telegram
+// user and chat IDs don't fit in 32 bits; counters summed by /usage stay
+// INTEGER so SUM returns a bigint rather than a numeric.
+const postgresSchema = `
+	CREATE TABLE IF NOT EXISTS items (
+		id BIGSERIAL PRIMARY KEY,
+		text TEXT NOT NULL,
+		created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE TABLE IF NOT EXISTS deleted (
+		id BIGSERIAL PRIMARY KEY,
+		text TEXT NOT NULL,
+		deleted_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE TABLE IF NOT EXISTS users (
+		user_id BIGINT PRIMARY KEY,
+		username TEXT,
+		role TEXT NOT NULL DEFAULT 'user',
+		added_by BIGINT NOT NULL DEFAULT 0,
+		created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE TABLE IF NOT EXISTS access_denied (
+		id BIGSERIAL PRIMARY KEY,
+		user_id BIGINT NOT NULL,
+		username TEXT NOT NULL DEFAULT '',
+		chat_id BIGINT NOT NULL,
+		text TEXT NOT NULL DEFAULT '',
+		attempted_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE TABLE IF NOT EXISTS llm_usage (
+		id BIGSERIAL PRIMARY KEY,
+		chat_id BIGINT NOT NULL,
+		user_id BIGINT NOT NULL,
+		command TEXT NOT NULL,
+		model TEXT NOT NULL DEFAULT '',
+		prompt_tokens INTEGER NOT NULL DEFAULT 0,
+		completion_tokens INTEGER NOT NULL DEFAULT 0,
+		total_tokens INTEGER NOT NULL DEFAULT 0,
+		cost DOUBLE PRECISION NOT NULL DEFAULT 0,
+		created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS idx_llm_usage_created_at ON llm_usage (created_at);
+	CREATE TABLE IF NOT EXISTS item_revisions (
+		id BIGSERIAL PRIMARY KEY,
+		item_id BIGINT NOT NULL,
+		text TEXT NOT NULL,
+		edited_by BIGINT NOT NULL DEFAULT 0,
+		source TEXT NOT NULL,
+		edited_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS idx_item_revisions_item_id ON item_revisions (item_id);
+	CREATE TABLE IF NOT EXISTS item_tags (
+		item_id BIGINT NOT NULL,
+		tag TEXT NOT NULL,
+		PRIMARY KEY (item_id, tag)
+	);
+	CREATE TABLE IF NOT EXISTS world_clocks (
+		chat_id BIGINT NOT NULL,
+		label TEXT NOT NULL,
+		zone TEXT NOT NULL,
+		position INTEGER NOT NULL,
+		PRIMARY KEY (chat_id, label)
+	);
+	CREATE TABLE IF NOT EXISTS user_settings (
+		user_id BIGINT PRIMARY KEY,
+		label TEXT NOT NULL,
+		zone TEXT NOT NULL,
+		updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+	);`
+
+// postgresType translates a column definition from addedColumns
+func postgresType(def string) string {
+	typ, rest, _ := strings.Cut(def, " ")
+	switch typ {
+	case "INTEGER":
+		typ = "BIGINT"
+	case "DATETIME":
+		typ = "TIMESTAMPTZ"
+	}
+	return strings.TrimSpace(typ + " " + rest)
+}
+
+// openDatabase opens Postgres when DATABASE_URL is set and mind.db in
+// DATA_DIR otherwise
+func openDatabase(cfg *Config) error {
+	if cfg.DatabaseURL != "" {
+		return initPostgres(cfg.DatabaseURL)
+	}
+	return initDB(cfg.DataDir)
+}
+
+// initPostgres connects to Postgres and applies the migrations
+func initPostgres(url string) error {
+	config, err := pgx.ParseConfig(url)
+	if err != nil {
+		return fmt.Errorf("invalid DATABASE_URL: %v", err)
+	}
+	log.Printf("Using Postgres database %s on %s", config.Database, config.Host)
+
+	db = sql.OpenDB(rebindConnector{stdlib.GetConnector(*config)})
+	if err := db.Ping(); err != nil {
+		return fmt.Errorf("failed to connect to database: %v", err)
+	}
+	dbDialect = dialectPostgres
+	return migrateDB(postgresSchema)
+}
+
+// rebind converts the ? placeholders used throughout the bot into
+// Postgres' $1, $2, ... Quoted strings and identifiers are left alone.
+func rebind(query string) string {
+	if !strings.Contains(query, "?") {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	var quote byte
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '?':
+			n++
+			fmt.Fprintf(&b, "$%d", n)
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// rebindConnector hands out pgx connections that accept ? placeholders
+type rebindConnector struct {
+	driver.Connector
+}
+
+func (c rebindConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return rebindConn{conn.(*stdlib.Conn)}, nil
+}
+
+type rebindConn struct {
+	*stdlib.Conn
+}
+
+func (c rebindConn) Prepare(query string) (driver.Stmt, error) {
+	return c.Conn.Prepare(rebind(query))
+}
+
+func (c rebindConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	return c.Conn.PrepareContext(ctx, rebind(query))
+}
+
+func (c rebindConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	return c.Conn.ExecContext(ctx, rebind(query), args)
+}
+
+func (c rebindConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return c.Conn.QueryContext(ctx, rebind(query), args)
+}
+
+// copiedTables lists the tables copied from SQLite, with their serial
+// column if any
+var copiedTables = []struct{ name, serial string }{
+	{"items", "id"},
+	{"deleted", "id"},
+	{"users", ""},
+	{"access_denied", "id"},
+	{"llm_usage", "id"},
+	{"item_revisions", "id"},
+	{"item_tags", ""},
+	{"world_clocks", ""},
+	{"user_settings", ""},
+}
+
+// copyFromSQLite copies every row of a mind.db into the (empty) Postgres
+// database, keeping IDs and timestamps. It returns the rows copied per
+// table.
+func copyFromSQLite(path string) (map[string]int, error) {
+	if dbDialect != dialectPostgres {
+		return nil, errors.New("DATABASE_URL must point to the Postgres database to copy into")
+	}
+
+	src, err := sql.Open("sqlite3", "file:"+path+"?mode=ro")
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+	if err := src.Ping(); err != nil {
+		return nil, fmt.Errorf("failed to open %s: %v", path, err)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	for _, t := range copiedTables {
+		var n int
+		if err := tx.QueryRow("SELECT COUNT(*) FROM " + t.name).Scan(&n); err != nil {
+			return nil, err
+		}
+		if n > 0 {
+			return nil, fmt.Errorf("table %s in Postgres is not empty", t.name)
+		}
+	}
+
+	copied := make(map[string]int)
+	for _, t := range copiedTables {
+		n, err := copyTable(src, tx, t.name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to copy %s: %v", t.name, err)
+		}
+		copied[t.name] = n
+
+		// Continue numbering after the copied IDs
+		if t.serial != "" && n > 0 {
+			if _, err := tx.Exec(fmt.Sprintf("SELECT setval(pg_get_serial_sequence('%s', '%s'), (SELECT MAX(%s) FROM %s))",
+				t.name, t.serial, t.serial, t.name)); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return copied, tx.Commit()
+}
+
+// copyTable copies the columns a SQLite table has; older databases may
+// lack some, which then get their Postgres defaults
+func copyTable(src *sql.DB, tx *sql.Tx, table string) (int, error) {
+	columns, err := sqliteColumns(src, table)
+	if err != nil || len(columns) == 0 {
+		return 0, err
+	}
+
+	rows, err := src.Query(fmt.Sprintf("SELECT %s FROM %s", strings.Join(columns, ", "), table))
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	insert := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+		table, strings.Join(columns, ", "), strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", "))
+	n := 0
+	for rows.Next() {
+		values := make([]any, len(columns))
+		ptrs := make([]any, len(columns))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return n, err
+		}
+		if _, err := tx.Exec(insert, values...); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, rows.Err()
+}
+
+// sqliteColumns returns the column names of a table, or none if the table
+// doesn't exist
+func sqliteColumns(src *sql.DB, table string) ([]string, error) {
+	rows, err := src.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var columns []string
+	for rows.Next() {
+		var (
+			cid       int
+			name      string
+			colType   string
+			notNull   int
+			dfltValue sql.NullString
+			pk        int
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dfltValue, &pk); err != nil {
+			return nil, err
+		}
+		columns = append(columns, name)
+	}
+	return columns, rows.Err()
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// How often dbMaintenance refreshes the query planner statistics and
+// checks the database file
+const (
+	optimizeInterval  = 6 * time.Hour
+	integrityInterval = 24 * time.Hour
+)
+
+// dbMaintenance runs PRAGMA optimize and integrity checks on SQLite and
+// remembers the results for the health check
+type dbMaintenance struct {
+	db *sql.DB
+
+	mu          sync.Mutex
+	optimizedAt time.Time
+	checkedAt   time.Time
+	integrity   string // "ok" or the problems reported by quick_check
+	now         func() time.Time
+}
+
+// dbHealth is the database part of the /health response
+type dbHealth struct {
+	Dialect     string     `json:"dialect"`
+	Ping        string     `json:"ping"`
+	Integrity   string     `json:"integrity,omitempty"`
+	CheckedAt   *time.Time `json:"checked_at,omitempty"`
+	OptimizedAt *time.Time `json:"optimized_at,omitempty"`
+}
+
+func newDBMaintenance(db *sql.DB) *dbMaintenance {
+	return &dbMaintenance{db: db, now: time.Now}
+}
+
+// start checks the database once and then keeps it optimized until ctx is
+// done. Postgres takes care of itself.
+func (m *dbMaintenance) start(ctx context.Context) {
+	if dbDialect != dialectSQLite {
+		return
+	}
+	go func() {
+		m.checkIntegrity(ctx)
+		optimize := time.NewTicker(optimizeInterval)
+		defer optimize.Stop()
+		check := time.NewTicker(integrityInterval)
+		defer check.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-optimize.C:
+				m.optimize(ctx)
+			case <-check.C:
+				m.checkIntegrity(ctx)
+			}
+		}
+	}()
+}
+
+// optimize lets SQLite refresh the statistics of tables whose queries
+// would benefit from it
+func (m *dbMaintenance) optimize(ctx context.Context) error {
+	if _, err := m.db.ExecContext(ctx, "PRAGMA optimize"); err != nil {
+		log.Printf("Error optimizing database: %v", err)
+		return err
+	}
+	m.mu.Lock()
+	m.optimizedAt = m.now()
+	m.mu.Unlock()
+	return nil
+}
+
+// checkIntegrity runs PRAGMA quick_check and returns "ok" or the problems
+// found
+func (m *dbMaintenance) checkIntegrity(ctx context.Context) (string, error) {
+	rows, err := m.db.QueryContext(ctx, "PRAGMA quick_check")
+	if err != nil {
+		log.Printf("Error checking database integrity: %v", err)
+		return "", err
+	}
+	defer rows.Close()
+
+	var problems []string
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			return "", err
+		}
+		problems = append(problems, line)
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+	result := strings.Join(problems, "; ")
+	if result != "ok" {
+		log.Printf("Database integrity check failed: %s", result)
+	}
+
+	m.mu.Lock()
+	m.integrity, m.checkedAt = result, m.now()
+	m.mu.Unlock()
+	return result, nil
+}
+
+// health pings the database and reports the latest maintenance results
+func (m *dbMaintenance) health(ctx context.Context) dbHealth {
+	h := dbHealth{Dialect: dbDialect, Ping: "ok"}
+	if err := m.db.PingContext(ctx); err != nil {
+		h.Ping = err.Error()
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	h.Integrity = m.integrity
+	if !m.checkedAt.IsZero() {
+		checkedAt := m.checkedAt
+		h.CheckedAt = &checkedAt
+	}
+	if !m.optimizedAt.IsZero() {
+		optimizedAt := m.optimizedAt
+		h.OptimizedAt = &optimizedAt
+	}
+	return h
+}
+
+// healthHandler serves /health. It fails only when the database is
+// unreachable; a failed integrity check is reported as degraded so the
+// bot keeps running while someone looks at it.
+func healthHandler(m *dbMaintenance) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+		defer cancel()
+
+		resp := struct {
+			Status   string   `json:"status"`
+			Database dbHealth `json:"database"`
+		}{Status: "ok", Database: m.health(ctx)}
+
+		code := http.StatusOK
+		switch {
+		case resp.Database.Ping != "ok":
+			resp.Status = "unavailable"
+			code = http.StatusServiceUnavailable
+		case resp.Database.Integrity != "" && resp.Database.Integrity != "ok":
+			resp.Status = "degraded"
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		json.NewEncoder(w).Encode(resp)
+	}
+}
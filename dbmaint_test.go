@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSQLiteSettings(t *testing.T) {
+	if err := initDB(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	tests := []struct {
+		pragma string
+		want   string
+	}{
+		{"journal_mode", "wal"},
+		{"busy_timeout", "5000"},
+		{"foreign_keys", "1"},
+		{"synchronous", "1"},
+	}
+	for _, tt := range tests {
+		var got string
+		if err := db.QueryRow("PRAGMA " + tt.pragma).Scan(&got); err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("PRAGMA %s = %s, want %s", tt.pragma, got, tt.want)
+		}
+	}
+	if got := db.Stats().MaxOpenConnections; got != sqliteMaxConns {
+		t.Errorf("MaxOpenConnections = %d, want %d", got, sqliteMaxConns)
+	}
+}
+
+func TestHealthHandler(t *testing.T) {
+	if err := initDB(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	m := newDBMaintenance(db)
+	get := func() (int, map[string]any) {
+		rec := httptest.NewRecorder()
+		healthHandler(m)(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+		var body map[string]any
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("invalid response %q: %v", rec.Body.String(), err)
+		}
+		return rec.Code, body
+	}
+
+	if result, err := m.checkIntegrity(context.Background()); err != nil || result != "ok" {
+		t.Fatalf("checkIntegrity() = %q, %v", result, err)
+	}
+	if err := m.optimize(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	code, body := get()
+	database, _ := body["database"].(map[string]any)
+	if code != http.StatusOK || body["status"] != "ok" || database["integrity"] != "ok" || database["optimized_at"] == nil {
+		t.Errorf("healthy response = %d %v", code, body)
+	}
+
+	m.integrity = "row 3 missing from index idx_items_kind"
+	if code, body := get(); code != http.StatusOK || body["status"] != "degraded" {
+		t.Errorf("corrupt response = %d %v, want 200 degraded", code, body)
+	}
+
+	db.Close()
+	if code, body := get(); code != http.StatusServiceUnavailable || body["status"] != "unavailable" {
+		t.Errorf("closed database response = %d %v, want 503 unavailable", code, body)
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	tgbot "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	timecalc "github.com/jgabriele321/onmymind/time"
+)
+
+const durationUsage = `Usage:
+/duration 3:15 PM + 2.5h — add or subtract time
+/duration 1h 20m + 45m + 2.5 hours — add up durations
+/duration 9am New York to 5pm London — time between two times`
+
+const leaveByUsage = `Usage: /leaveby 7:45 AM; breakfast 40m, drive 1h, security 25m
+Steps are taken in the order given. For travel across time zones add the
+arrival place after the time and the departure place at the end:
+/leaveby 6pm London; flight 7h; New York`
+
+// "7:45 AM", "6pm London", "9:00 tomorrow"
+var leaveByArrivalPattern = regexp.MustCompile(`(?i)^(\d{1,2}(?::\d{2})?\s*(?:am|pm)?)(?:\s+(?:in\s+)?(.+))?$`)
+
+// handleDuration handles /duration
+func handleDuration(m *tgbot.Message) string {
+	expr := strings.TrimSpace(m.CommandArguments())
+	if expr == "" {
+		return durationUsage
+	}
+	result, err := timecalc.EvaluateTimeExpression(expr)
+	if err != nil {
+		return "❌ " + err.Error() + "\n\n" + durationUsage
+	}
+	return result
+}
+
+// handleLeaveBy handles /leaveby
+func handleLeaveBy(m *tgbot.Message) string {
+	return leaveByReply(m.CommandArguments(), time.Now())
+}
+
+func leaveByReply(args string, now time.Time) string {
+	parts := strings.Split(args, ";")
+	if len(parts) < 2 || len(parts) > 3 {
+		return leaveByUsage
+	}
+
+	arrival, arrivalPlace := strings.TrimSpace(parts[0]), ""
+	if match := leaveByArrivalPattern.FindStringSubmatch(arrival); match != nil && match[2] != "" {
+		// Whatever follows the time is a date or the arrival place
+		if _, err := timecalc.ParseDate(match[2], now); err != nil {
+			arrival, arrivalPlace = match[1], match[2]
+		}
+	}
+	departurePlace := ""
+	if len(parts) == 3 {
+		departurePlace = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(parts[2]), "from "))
+	}
+
+	result, err := timecalc.LeaveByWithTools(arrival, parts[1], arrivalPlace, departurePlace)
+	if err != nil {
+		return "❌ " + err.Error()
+	}
+	return result
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLeaveByReply(t *testing.T) {
+	now := time.Date(2024, 6, 10, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		args string
+		want string
+	}{
+		{"7:45 AM; breakfast 40m, drive 1h, security 25m", "Leave at 5:40 AM (05:40)"},
+		{"9am tomorrow; commute 30 min", "Leave at 8:30 AM (08:30)"},
+		{"6pm London; flight 7h; from New York", "6:00 PM (18:00) BST"},
+		{"6pm London; flight 7h; New York", " EDT to arrive by "},
+		{"7:45 AM", "Usage"},
+		{"7:45 AM; later", "❌"},
+		{"6pm Portland; flight 2h", "ambiguous"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.args, func(t *testing.T) {
+			if got := leaveByReply(tt.args, now); !strings.Contains(got, tt.want) {
+				t.Errorf("leaveByReply(%q) = %q, want it to contain %q", tt.args, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleDuration(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"/duration 3:15 PM + 2.5h", "5:45 PM (17:45)"},
+		{"/duration", "Usage"},
+		{"/duration 99999999999 days", "❌ durations longer than 10 years are not supported"},
+		{"/duration 1.5.5h + 1h", "❌ invalid number"},
+	}
+	for _, tt := range tests {
+		if got := handleDuration(commandMessage(1, tt.text)); !strings.Contains(got, tt.want) {
+			t.Errorf("%s = %q, want it to contain %q", tt.text, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	tgbot "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Revision sources
+const (
+	editSourceCommand  = "command"
+	editSourceTelegram = "telegram_edit"
+)
+
+var errItemNotFound = errors.New("item not found")
+
+// editItem replaces the text of an item, keeping the old text as a
+// revision. It returns the previous text.
+func editItem(id int64, newText string, editedBy int64, source string) (string, error) {
+	oldText, err := store.Edit(id, newText, editedBy, source)
+	if err != nil || oldText == newText {
+		return oldText, err
+	}
+
+	// Keep a pending /delete pointing at the new text
+	lpMutex.Lock()
+	for chatID, item := range lastPulled {
+		if item.ID == id {
+			lastPulled[chatID] = pulledItem{ID: id, Text: newText}
+		}
+	}
+	lpMutex.Unlock()
+
+	return oldText, nil
+}
+
+// handleEdit implements /edit <id> <new text> and /edit <new text>, which
+// edits the item last shown by /pull
+func handleEdit(m *tgbot.Message) string {
+	args := strings.TrimSpace(m.CommandArguments())
+	if args == "" {
+		return "Usage: /edit <id> <new text>\nOr /edit <new text> to change the last pulled item"
+	}
+
+	var id int64
+	text := args
+	first, rest, _ := strings.Cut(args, " ")
+	if n, err := strconv.ParseInt(strings.TrimPrefix(first, "#"), 10, 64); err == nil {
+		id = n
+		text = strings.TrimSpace(rest)
+	} else {
+		lpMutex.RLock()
+		item, ok := lastPulled[m.Chat.ID]
+		lpMutex.RUnlock()
+		if !ok {
+			return "Pull an item first using /pull, or use /edit <id> <new text>"
+		}
+		id = item.ID
+	}
+	if text == "" {
+		return "Usage: /edit <id> <new text>"
+	}
+
+	oldText, err := editItem(id, text, m.From.ID, editSourceCommand)
+	if err == errItemNotFound {
+		return fmt.Sprintf("No item #%d.", id)
+	} else if err != nil {
+		log.Printf("Error editing item %d: %v", id, err)
+		return "Failed to edit item."
+	}
+	if oldText == text {
+		return fmt.Sprintf("#%d is unchanged.", id)
+	}
+	return fmt.Sprintf("✏️ Updated #%d: %s", id, text)
+}
+
+// handleHistory implements /history <id>
+func handleHistory(m *tgbot.Message) string {
+	id, err := strconv.ParseInt(strings.TrimPrefix(strings.TrimSpace(m.CommandArguments()), "#"), 10, 64)
+	if err != nil {
+		return "Usage: /history <id>"
+	}
+
+	current, err := store.Get(id)
+	if err == errItemNotFound {
+		return fmt.Sprintf("No item #%d.", id)
+	} else if err != nil {
+		log.Printf("Error loading item %d: %v", id, err)
+		return "Failed to load history."
+	}
+
+	revisions, err := store.Revisions(id)
+	if err != nil {
+		log.Printf("Error loading revisions for %d: %v", id, err)
+		return "Failed to load history."
+	}
+	if len(revisions) == 0 {
+		return fmt.Sprintf("#%d has never been edited.", id)
+	}
+
+	lines := []string{fmt.Sprintf("📜 History of #%d\nNow: %s", id, current.Text)}
+	for _, r := range revisions {
+		lines = append(lines, fmt.Sprintf("• %s: %s", r.EditedAt.Format("2006-01-02 15:04"), r.Text))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// handleEditedMessage updates the note created from a message the user
+// edited in Telegram. It returns the reply to send, or "" to stay silent.
+func handleEditedMessage(m *tgbot.Message) string {
+	var text string
+	switch {
+	case m.IsCommand() && m.Command() == "add":
+		text = strings.TrimSpace(m.CommandArguments())
+	case m.IsCommand():
+		return ""
+	case m.Text != "":
+		text = strings.TrimSpace(m.Text)
+	default:
+		// Edited caption of a media item
+		text = stripAddCaption(m.Caption)
+	}
+	if text == "" {
+		return ""
+	}
+
+	item, err := store.FindByMessage(m.Chat.ID, m.MessageID)
+	if err == errItemNotFound {
+		return ""
+	} else if err != nil {
+		log.Printf("Error looking up edited message: %v", err)
+		return ""
+	}
+	id := item.ID
+
+	oldText, err := editItem(id, text, m.From.ID, editSourceTelegram)
+	if err != nil {
+		log.Printf("Error editing item %d: %v", id, err)
+		return "Failed to update the note."
+	}
+	if oldText == text {
+		return ""
+	}
+	return fmt.Sprintf("✏️ Updated #%d: %s", id, text)
+}
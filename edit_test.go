@@ -0,0 +1,60 @@
+package main
+
+import (
+	"testing"
+
+	tgbot "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestEditItem(t *testing.T) {
+	if err := initDB(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	res, err := db.Exec("INSERT INTO items (text, chat_id, message_id) VALUES (?, ?, ?)", "first draft", 7, 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, _ := res.LastInsertId()
+
+	old, err := editItem(id, "second draft", 1, editSourceCommand)
+	if err != nil || old != "first draft" {
+		t.Fatalf("editItem() = %q, %v; want previous text", old, err)
+	}
+
+	if _, err := editItem(id+100, "nope", 1, editSourceCommand); err != errItemNotFound {
+		t.Errorf("editItem() on missing item error = %v, want errItemNotFound", err)
+	}
+
+	// Editing the original /add message in Telegram updates the note
+	reply := handleEditedMessage(&tgbot.Message{
+		MessageID: 42,
+		From:      &tgbot.User{ID: 1},
+		Chat:      &tgbot.Chat{ID: 7},
+		Text:      "/add final draft",
+		Entities:  []tgbot.MessageEntity{{Type: "bot_command", Offset: 0, Length: 4}},
+	})
+	if reply == "" {
+		t.Error("handleEditedMessage() returned no reply for a tracked message")
+	}
+
+	var text string
+	if err := db.QueryRow("SELECT text FROM items WHERE id = ?", id).Scan(&text); err != nil {
+		t.Fatal(err)
+	}
+	if text != "final draft" {
+		t.Errorf("item text = %q, want %q", text, "final draft")
+	}
+
+	revisions, err := store.Revisions(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revisions) != 2 {
+		t.Fatalf("got %d revisions, want 2", len(revisions))
+	}
+	if revisions[0].Text != "second draft" || revisions[0].Source != editSourceTelegram {
+		t.Errorf("latest revision = %+v, want second draft from telegram edit", revisions[0])
+	}
+}
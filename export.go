@@ -0,0 +1,222 @@
+package main
+
+import (
+	"archive/zip"
+	"fmt"
+	"log"
+	"mime"
+	"path"
+	"strings"
+	"time"
+
+	tgbot "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// backupVersion is written to every export. Version 1 is the original
+// format without a version field.
+const backupVersion = 2
+
+// backupDocument is the JSON produced by /export
+type backupDocument struct {
+	Version      int             `json:"version"`
+	Items        []backupItem    `json:"items"`
+	DeletedItems []backupDeleted `json:"deleted_items"`
+	ExportedAt   time.Time       `json:"exported_at"`
+}
+
+type backupItem struct {
+	ID        int64        `json:"id,omitempty"`
+	Text      string       `json:"text"`
+	CreatedAt time.Time    `json:"created_at"`
+	Kind      string       `json:"kind,omitempty"`
+	Tags      []string     `json:"tags,omitempty"`
+	Media     *backupMedia `json:"media,omitempty"`
+}
+
+// backupMedia references a Telegram file. Attachment is the path of the
+// downloaded copy inside a zip export.
+type backupMedia struct {
+	FileID     string `json:"file_id"`
+	FileName   string `json:"file_name,omitempty"`
+	MimeType   string `json:"mime_type,omitempty"`
+	FileSize   int    `json:"file_size,omitempty"`
+	Duration   int    `json:"duration,omitempty"`
+	Attachment string `json:"attachment,omitempty"`
+}
+
+type backupDeleted struct {
+	Text      string    `json:"text"`
+	Kind      string    `json:"kind,omitempty"`
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+// buildBackup reads all items, tags and deleted items
+func buildBackup() (*backupDocument, error) {
+	backup := &backupDocument{
+		Version:    backupVersion,
+		ExportedAt: time.Now(),
+	}
+
+	tags, err := store.AllTags()
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch tags: %v", err)
+	}
+
+	items, err := store.List(ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch items: %v", err)
+	}
+	// Oldest first
+	for i := len(items) - 1; i >= 0; i-- {
+		it := items[i]
+		item := backupItem{ID: it.ID, Text: it.Text, CreatedAt: it.CreatedAt, Kind: it.Kind, Tags: tags[it.ID]}
+		if it.Kind != kindText && it.FileID != "" {
+			item.Media = &backupMedia{
+				FileID:   it.FileID,
+				FileName: it.FileName,
+				MimeType: it.MimeType,
+				FileSize: it.FileSize,
+				Duration: it.Duration,
+			}
+		}
+		backup.Items = append(backup.Items, item)
+	}
+
+	deleted, err := store.Deleted(0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch deleted items: %v", err)
+	}
+	for i := len(deleted) - 1; i >= 0; i-- {
+		d := deleted[i]
+		backup.DeletedItems = append(backup.DeletedItems, backupDeleted{Text: d.Text, Kind: d.Kind, DeletedAt: d.DeletedAt})
+	}
+	return backup, nil
+}
+
+// attachmentName picks a file name for a media item inside the zip
+func attachmentName(item backupItem) string {
+	name := path.Base(item.Media.FileName)
+	if name == "." || name == "/" || name == "" {
+		name = item.Kind + extensionFor(item.Media.MimeType)
+	}
+	return fmt.Sprintf("attachments/%d-%s", item.ID, name)
+}
+
+// extensionFor returns a file extension for the MIME types Telegram uses
+func extensionFor(mimeType string) string {
+	switch mimeType {
+	case "image/jpeg":
+		return ".jpg"
+	case "audio/ogg":
+		return ".ogg"
+	case "audio/mpeg":
+		return ".mp3"
+	case "video/mp4":
+		return ".mp4"
+	}
+	if exts, _ := mime.ExtensionsByType(mimeType); len(exts) > 0 {
+		return exts[0]
+	}
+	return ""
+}
+
+// writeAttachments downloads every media file into the zip and records
+// its path in the item. Files that can't be downloaded stay in the backup
+// as references only. It returns how many files were included.
+func writeAttachments(zw *zip.Writer, backup *backupDocument, fetch fileFetcher) (int, error) {
+	downloaded := 0
+	for i := range backup.Items {
+		item := &backup.Items[i]
+		if item.Media == nil {
+			continue
+		}
+		data, err := fetch(item.Media.FileID)
+		if err != nil {
+			log.Printf("Error downloading attachment for item %d: %v", item.ID, err)
+			continue
+		}
+
+		name := attachmentName(*item)
+		w, err := zw.Create(name)
+		if err != nil {
+			return 0, err
+		}
+		if _, err := w.Write(data); err != nil {
+			return 0, err
+		}
+		item.Media.Attachment = name
+		downloaded++
+	}
+	return downloaded, nil
+}
+
+// mediaCount returns how many items reference a media file
+func mediaCount(backup *backupDocument) int {
+	n := 0
+	for _, item := range backup.Items {
+		if item.Media != nil {
+			n++
+		}
+	}
+	return n
+}
+
+// handleExport implements /export [format]. It sends the backup itself and
+// returns an error message, or "" on success.
+func handleExport(bot *tgbot.BotAPI, m *tgbot.Message) string {
+	format := strings.ToLower(strings.TrimSpace(m.CommandArguments()))
+	if format == "" {
+		format = "json"
+	}
+	exp, ok := exporters[format]
+	if !ok {
+		return "Usage: /export [" + strings.Join(exportFormats(), "|") + "]"
+	}
+
+	// Let the user know, exports with attachments can take a while
+	preparing, _ := bot.Send(tgbot.NewMessage(m.Chat.ID, "📦 Preparing your data export..."))
+	defer func() {
+		if preparing.MessageID != 0 {
+			bot.Send(tgbot.NewDeleteMessage(m.Chat.ID, preparing.MessageID))
+		}
+	}()
+
+	backup, err := buildBackup()
+	if err != nil {
+		log.Printf("Error building backup: %v", err)
+		return "❌ Failed to create backup"
+	}
+
+	fetch := func(fileID string) ([]byte, error) {
+		return downloadTelegramFile(bot, fileID)
+	}
+	out, err := exp.Export(backup, fetch)
+	if err != nil {
+		log.Printf("Error exporting %s: %v", format, err)
+		return "❌ Failed to create backup"
+	}
+
+	stamp := time.Now().Format("2006-01-02-150405")
+	caption := fmt.Sprintf("📦 Your MindBot Backup\n• %d items (%d with media)\n• %d deleted items",
+		len(backup.Items), mediaCount(backup), len(backup.DeletedItems))
+	if out.Note != "" {
+		caption += "\n• " + out.Note
+	}
+
+	file := tgbot.FileBytes{Name: fmt.Sprintf("mindbot-%s-%s%s", exportPrefix(format), stamp, exp.Extension()), Bytes: out.Data}
+	doc := tgbot.NewDocument(m.Chat.ID, file)
+	doc.Caption = caption
+	if _, err := bot.Send(doc); err != nil {
+		log.Printf("Error sending backup: %v", err)
+		return "❌ Failed to send backup file"
+	}
+	return ""
+}
+
+// exportPrefix keeps the historical file name for restorable backups
+func exportPrefix(format string) string {
+	if format == "json" || format == "zip" {
+		return "backup"
+	}
+	return "export"
+}
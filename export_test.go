@@ -0,0 +1,63 @@
+package main
+
+import (
+	"testing"
+
+	tgbot "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestBuildBackupWithMedia(t *testing.T) {
+	if err := initDB(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	msg := &tgbot.Message{
+		Caption: "/add whiteboard sketch",
+		Photo: []tgbot.PhotoSize{
+			{FileID: "small", Width: 90},
+			{FileID: "large", FileUniqueID: "u1", Width: 1280, FileSize: 2048},
+		},
+	}
+	media, ok := mediaFromMessage(msg)
+	if !ok || media.Kind != kindPhoto || media.FileID != "large" {
+		t.Fatalf("mediaFromMessage() = %+v, %v; want largest photo", media, ok)
+	}
+	c, _ := captureFromMessage(msg)
+	if c.Text != "whiteboard sketch" {
+		t.Errorf("caption = %q, want /add stripped", c.Text)
+	}
+
+	photoID, err := storeMediaItem(c, media, 1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := storeCapturedItem(capturedItem{Text: "plain note", Source: sourceMessage}, 1, 11); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.AddTags(photoID, []string{"design"}); err != nil {
+		t.Fatal(err)
+	}
+
+	backup, err := buildBackup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if backup.Version != backupVersion || len(backup.Items) != 2 {
+		t.Fatalf("buildBackup() = version %d with %d items", backup.Version, len(backup.Items))
+	}
+
+	photo := backup.Items[0]
+	if photo.Kind != kindPhoto || photo.Media == nil || photo.Media.FileID != "large" {
+		t.Errorf("photo item = %+v, want media reference", photo)
+	}
+	if len(photo.Tags) != 1 || photo.Tags[0] != "design" {
+		t.Errorf("photo tags = %v, want [design]", photo.Tags)
+	}
+	if name := attachmentName(photo); name != "attachments/1-photo.jpg" {
+		t.Errorf("attachmentName() = %q", name)
+	}
+	if backup.Items[1].Media != nil {
+		t.Error("text item should have no media")
+	}
+}
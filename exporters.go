@@ -0,0 +1,255 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// fileFetcher downloads a Telegram file by ID
+type fileFetcher func(fileID string) ([]byte, error)
+
+// exportFile is a rendered export. Note is added to the caption.
+type exportFile struct {
+	Data []byte
+	Note string
+}
+
+// exporter renders a backup in one output format. fetch is only used by
+// formats that bundle media files.
+type exporter interface {
+	Extension() string
+	Export(backup *backupDocument, fetch fileFetcher) (exportFile, error)
+}
+
+// exporters maps /export arguments to formats
+var exporters = map[string]exporter{
+	"json":     jsonExporter{},
+	"zip":      zipExporter{},
+	"md":       markdownExporter{},
+	"csv":      csvExporter{},
+	"obsidian": obsidianExporter{},
+}
+
+// exportFormats lists the supported formats, sorted
+func exportFormats() []string {
+	var formats []string
+	for name := range exporters {
+		formats = append(formats, name)
+	}
+	sort.Strings(formats)
+	return formats
+}
+
+// jsonExporter writes the restorable backup document
+type jsonExporter struct{}
+
+func (jsonExporter) Extension() string { return ".json" }
+
+func (jsonExporter) Export(backup *backupDocument, _ fileFetcher) (exportFile, error) {
+	data, err := json.MarshalIndent(backup, "", "    ")
+	return exportFile{Data: data}, err
+}
+
+// zipExporter writes backup.json plus the downloaded attachments
+type zipExporter struct{}
+
+func (zipExporter) Extension() string { return ".zip" }
+
+func (zipExporter) Export(backup *backupDocument, fetch fileFetcher) (exportFile, error) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+
+	downloaded, err := writeAttachments(zw, backup, fetch)
+	if err != nil {
+		return exportFile{}, err
+	}
+
+	jsonData, err := json.MarshalIndent(backup, "", "    ")
+	if err != nil {
+		return exportFile{}, err
+	}
+	w, err := zw.Create("backup.json")
+	if err != nil {
+		return exportFile{}, err
+	}
+	if _, err := w.Write(jsonData); err != nil {
+		return exportFile{}, err
+	}
+
+	if err := zw.Close(); err != nil {
+		return exportFile{}, err
+	}
+	note := fmt.Sprintf("%d of %d attachments included", downloaded, mediaCount(backup))
+	return exportFile{Data: buf.Bytes(), Note: note}, nil
+}
+
+// markdownExporter writes a single readable document
+type markdownExporter struct{}
+
+func (markdownExporter) Extension() string { return ".md" }
+
+func (markdownExporter) Export(backup *backupDocument, _ fileFetcher) (exportFile, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# MindBot export\n\nExported %s\n", backup.ExportedAt.Format("2006-01-02 15:04"))
+
+	b.WriteString("\n## Items\n")
+	if len(backup.Items) == 0 {
+		b.WriteString("\nNo items.\n")
+	}
+	for _, item := range backup.Items {
+		fmt.Fprintf(&b, "\n### #%d · %s\n\n", item.ID, item.CreatedAt.Format("2006-01-02 15:04"))
+		if item.Media != nil {
+			fmt.Fprintf(&b, "%s %s", kindIcon(item.Kind), item.Kind)
+			if item.Media.FileName != "" {
+				fmt.Fprintf(&b, " `%s`", item.Media.FileName)
+			}
+			b.WriteString("\n\n")
+		}
+		if item.Text != "" {
+			b.WriteString(item.Text + "\n")
+		}
+		if len(item.Tags) > 0 {
+			fmt.Fprintf(&b, "\nTags: %s\n", hashTags(item.Tags))
+		}
+	}
+
+	if len(backup.DeletedItems) > 0 {
+		b.WriteString("\n## Deleted items\n\n")
+		for _, item := range backup.DeletedItems {
+			fmt.Fprintf(&b, "- %s (deleted %s)\n", item.Text, item.DeletedAt.Format("2006-01-02 15:04"))
+		}
+	}
+	return exportFile{Data: []byte(b.String())}, nil
+}
+
+// hashTags formats tags the way Markdown note apps recognize them
+func hashTags(tags []string) string {
+	out := make([]string, len(tags))
+	for i, tag := range tags {
+		out[i] = "#" + tag
+	}
+	return strings.Join(out, " ")
+}
+
+// csvExporter writes one row per item and deleted item, for spreadsheets
+type csvExporter struct{}
+
+func (csvExporter) Extension() string { return ".csv" }
+
+func (csvExporter) Export(backup *backupDocument, _ fileFetcher) (exportFile, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write([]string{"id", "created_at", "deleted_at", "kind", "text", "tags", "file_id", "file_name", "mime_type"})
+
+	for _, item := range backup.Items {
+		media := backupMedia{}
+		if item.Media != nil {
+			media = *item.Media
+		}
+		w.Write([]string{
+			strconv.FormatInt(item.ID, 10),
+			item.CreatedAt.UTC().Format(time.RFC3339),
+			"",
+			item.Kind,
+			item.Text,
+			strings.Join(item.Tags, ","),
+			media.FileID,
+			media.FileName,
+			media.MimeType,
+		})
+	}
+	for _, item := range backup.DeletedItems {
+		w.Write([]string{"", "", item.DeletedAt.UTC().Format(time.RFC3339), item.Kind, item.Text, "", "", "", ""})
+	}
+
+	w.Flush()
+	return exportFile{Data: buf.Bytes()}, w.Error()
+}
+
+// obsidianExporter writes a vault: one note per item with YAML front-matter
+// and the media files under attachments/
+type obsidianExporter struct{}
+
+func (obsidianExporter) Extension() string { return ".zip" }
+
+func (obsidianExporter) Export(backup *backupDocument, fetch fileFetcher) (exportFile, error) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+
+	downloaded, err := writeAttachments(zw, backup, fetch)
+	if err != nil {
+		return exportFile{}, err
+	}
+
+	used := make(map[string]bool)
+	for _, item := range backup.Items {
+		name := obsidianNoteName(item, used)
+		w, err := zw.Create(name)
+		if err != nil {
+			return exportFile{}, err
+		}
+		if _, err := w.Write([]byte(obsidianNote(item))); err != nil {
+			return exportFile{}, err
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return exportFile{}, err
+	}
+	note := fmt.Sprintf("%d notes, %d of %d attachments included", len(backup.Items), downloaded, mediaCount(backup))
+	return exportFile{Data: buf.Bytes(), Note: note}, nil
+}
+
+var unsafeNameChars = regexp.MustCompile(`[\\/:*?"<>|#^\[\]\n\r\t]+`)
+
+// obsidianNoteName derives a unique file name from the first line of the item
+func obsidianNoteName(item backupItem, used map[string]bool) string {
+	title, _, _ := strings.Cut(strings.TrimSpace(item.Text), "\n")
+	title = strings.Join(strings.Fields(unsafeNameChars.ReplaceAllString(title, " ")), " ")
+	if r := []rune(title); len(r) > 60 {
+		title = strings.TrimSpace(string(r[:60]))
+	}
+	if title == "" {
+		title = item.Kind
+	}
+
+	name := fmt.Sprintf("%d %s.md", item.ID, title)
+	for n := 2; used[name]; n++ {
+		name = fmt.Sprintf("%d %s %d.md", item.ID, title, n)
+	}
+	used[name] = true
+	return name
+}
+
+// obsidianNote renders an item as Markdown with YAML front-matter
+func obsidianNote(item backupItem) string {
+	var b strings.Builder
+	b.WriteString("---\n")
+	fmt.Fprintf(&b, "id: %d\n", item.ID)
+	fmt.Fprintf(&b, "created: %s\n", item.CreatedAt.UTC().Format(time.RFC3339))
+	fmt.Fprintf(&b, "kind: %s\n", item.Kind)
+	if len(item.Tags) > 0 {
+		b.WriteString("tags:\n")
+		for _, tag := range item.Tags {
+			fmt.Fprintf(&b, "  - %s\n", strconv.Quote(tag))
+		}
+	}
+	b.WriteString("---\n\n")
+
+	if item.Media != nil && item.Media.Attachment != "" {
+		fmt.Fprintf(&b, "![[%s]]\n\n", path.Base(item.Media.Attachment))
+	}
+	if item.Text != "" {
+		b.WriteString(item.Text + "\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testBackup() *backupDocument {
+	created := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	return &backupDocument{
+		Version: backupVersion,
+		Items: []backupItem{
+			{ID: 1, Text: "buy milk, eggs", CreatedAt: created, Kind: kindText, Tags: []string{"shopping"}},
+			{ID: 2, Text: "whiteboard", CreatedAt: created, Kind: kindPhoto,
+				Media: &backupMedia{FileID: "f2", MimeType: "image/jpeg"}},
+			{ID: 3, Text: "lost", CreatedAt: created, Kind: kindVoice,
+				Media: &backupMedia{FileID: "missing", MimeType: "audio/ogg"}},
+		},
+		DeletedItems: []backupDeleted{{Text: "old idea", Kind: kindText, DeletedAt: created}},
+		ExportedAt:   created,
+	}
+}
+
+func fakeFetch(fileID string) ([]byte, error) {
+	if fileID == "missing" {
+		return nil, errors.New("not found")
+	}
+	return []byte("data:" + fileID), nil
+}
+
+func readZip(t *testing.T, data []byte) map[string]string {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := make(map[string]string)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, _ := io.ReadAll(rc)
+		rc.Close()
+		files[f.Name] = string(content)
+	}
+	return files
+}
+
+func TestExporters(t *testing.T) {
+	for _, format := range exportFormats() {
+		t.Run(format, func(t *testing.T) {
+			out, err := exporters[format].Export(testBackup(), fakeFetch)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(out.Data) == 0 {
+				t.Fatal("empty export")
+			}
+		})
+	}
+}
+
+func TestZipExportRestores(t *testing.T) {
+	out, err := zipExporter{}.Export(testBackup(), fakeFetch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Note != "1 of 2 attachments included" {
+		t.Errorf("note = %q", out.Note)
+	}
+	files := readZip(t, out.Data)
+	if files["attachments/2-photo.jpg"] != "data:f2" {
+		t.Errorf("attachment missing: %v", files)
+	}
+
+	backup, err := parseBackup(out.Data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backup.Items) != 3 || backup.Items[1].Media.Attachment != "attachments/2-photo.jpg" {
+		t.Errorf("parsed zip backup = %+v", backup.Items)
+	}
+}
+
+func TestCSVExport(t *testing.T) {
+	out, err := csvExporter{}.Export(testBackup(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(bytes.NewReader(out.Data)).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 5 {
+		t.Fatalf("got %d rows, want header + 3 items + 1 deleted", len(records))
+	}
+	if got := records[1]; got[4] != "buy milk, eggs" || got[5] != "shopping" {
+		t.Errorf("first row = %v", got)
+	}
+	if got := records[4]; got[0] != "" || got[2] == "" || got[4] != "old idea" {
+		t.Errorf("deleted row = %v", got)
+	}
+}
+
+func TestObsidianExport(t *testing.T) {
+	out, err := obsidianExporter{}.Export(testBackup(), fakeFetch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := readZip(t, out.Data)
+
+	note, ok := files["1 buy milk, eggs.md"]
+	if !ok {
+		t.Fatalf("note missing, files: %v", files)
+	}
+	for _, want := range []string{"---\nid: 1\n", "created: 2024-03-01T10:00:00Z", "tags:\n  - \"shopping\"", "---\n\nbuy milk, eggs\n"} {
+		if !strings.Contains(note, want) {
+			t.Errorf("note missing %q:\n%s", want, note)
+		}
+	}
+	if photo := files["2 whiteboard.md"]; !strings.Contains(photo, "![[2-photo.jpg]]") {
+		t.Errorf("photo note should embed attachment:\n%s", photo)
+	}
+	if voice := files["3 lost.md"]; strings.Contains(voice, "![[") {
+		t.Errorf("missing attachment should not be embedded:\n%s", voice)
+	}
+}
+
+func TestObsidianNoteName(t *testing.T) {
+	used := make(map[string]bool)
+	tests := []struct {
+		item backupItem
+		want string
+	}{
+		{backupItem{ID: 1, Text: "a/b: c?\nsecond line"}, "1 a b c.md"},
+		{backupItem{ID: 2, Kind: kindPhoto}, "2 photo.md"},
+		{backupItem{ID: 1, Text: "a/b: c?"}, "1 a b c 2.md"},
+	}
+	for _, tt := range tests {
+		if got := obsidianNoteName(tt.item, used); got != tt.want {
+			t.Errorf("obsidianNoteName(%q) = %q, want %q", tt.item.Text, got, tt.want)
+		}
+	}
+}
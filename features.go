@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Features records which optional parts of the bot are available. The
+// notes commands (/add, /pull, /list, /delete) are always enabled.
+type Features struct {
+	Time       bool   // /time command
+	TimeMode   string // TIME_MODE the /time features come from
+	LLM        bool   // /time answered by the OpenRouter assistant
+	Ask        bool   // /ask assistant over the notes, needs an OpenRouter key
+	Inbox      bool   // plain messages are saved as items
+	Transcribe bool   // voice notes are transcribed into text items
+}
+
+// newFeatures derives the enabled features from the configuration
+func newFeatures(cfg *Config) Features {
+	f := Features{TimeMode: cfg.TimeMode, Inbox: cfg.InboxMode, Transcribe: cfg.STTAPIKey != "", Ask: cfg.OpenRouterKey != ""}
+	switch cfg.TimeMode {
+	case "auto":
+		f.Time = true
+		f.LLM = cfg.OpenRouterKey != ""
+	case "tools":
+		f.Time = true
+	}
+	return f
+}
+
+// String summarizes the feature set for the startup log
+func (f Features) String() string {
+	var parts []string
+	parts = append(parts, "notes: enabled")
+
+	switch {
+	case f.Time && f.LLM:
+		parts = append(parts, "time: enabled (OpenRouter assistant)")
+	case f.Time && f.TimeMode == "tools":
+		parts = append(parts, "time: enabled (deterministic tools, TIME_MODE=tools)")
+	case f.Time:
+		parts = append(parts, "time: enabled (deterministic tools, no OpenRouter key)")
+	default:
+		parts = append(parts, "time: disabled")
+	}
+
+	if f.Ask {
+		parts = append(parts, "ask: enabled")
+	} else {
+		parts = append(parts, "ask: disabled (no OpenRouter key)")
+	}
+
+	if f.Inbox {
+		parts = append(parts, "inbox: enabled")
+	} else {
+		parts = append(parts, "inbox: disabled")
+	}
+
+	if f.Transcribe {
+		parts = append(parts, "voice transcription: enabled")
+	} else {
+		parts = append(parts, "voice transcription: disabled")
+	}
+
+	return fmt.Sprintf("Features: %s", strings.Join(parts, ", "))
+}
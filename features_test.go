@@ -0,0 +1,26 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFeaturesTimeSummary(t *testing.T) {
+	tests := []struct {
+		mode string
+		key  string
+		want string
+	}{
+		{"auto", "sk-test", "time: enabled (OpenRouter assistant)"},
+		{"auto", "", "time: enabled (deterministic tools, no OpenRouter key)"},
+		{"tools", "sk-test", "time: enabled (deterministic tools, TIME_MODE=tools)"},
+		{"tools", "", "time: enabled (deterministic tools, TIME_MODE=tools)"},
+		{"off", "sk-test", "time: disabled"},
+	}
+	for _, tt := range tests {
+		got := newFeatures(&Config{TimeMode: tt.mode, OpenRouterKey: tt.key}).String()
+		if !strings.Contains(got, tt.want) {
+			t.Errorf("TIME_MODE=%s, key %q: %q does not contain %q", tt.mode, tt.key, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,416 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	tgbot "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// importTimeout is how long an uploaded backup waits for Merge/Replace
+const importTimeout = 15 * time.Minute
+
+// Import modes
+const (
+	importMerge   = "merge"
+	importReplace = "replace"
+)
+
+// pendingImport is a parsed backup waiting for the user's choice
+type pendingImport struct {
+	Backup     *backupDocument
+	UploadedAt time.Time
+}
+
+var (
+	awaitingImport = make(map[int64]time.Time)      // chatID → when /import was sent
+	pendingImports = make(map[int64]*pendingImport) // chatID → parsed upload
+	piMutex        = &sync.Mutex{}                  // Protects both maps
+)
+
+// importResult summarizes what an import changed
+type importResult struct {
+	Items            int
+	DuplicateItems   int
+	DeletedItems     int
+	DuplicateDeleted int
+	ReplacedItems    int
+	ReplacedDeleted  int
+}
+
+// parseBackup reads an /export document, either the JSON itself or a zip
+// export containing backup.json, and validates its schema
+func parseBackup(data []byte) (*backupDocument, error) {
+	if bytes.HasPrefix(data, []byte("PK")) {
+		jsonData, err := backupFromZip(data)
+		if err != nil {
+			return nil, err
+		}
+		data = jsonData
+	}
+
+	// Check the shape first so unrelated JSON files are rejected clearly
+	var shape map[string]json.RawMessage
+	if err := json.Unmarshal(data, &shape); err != nil {
+		return nil, fmt.Errorf("not a valid JSON backup: %v", err)
+	}
+	for _, key := range []string{"items", "exported_at"} {
+		if _, ok := shape[key]; !ok {
+			return nil, fmt.Errorf("not a MindBot backup: missing %q", key)
+		}
+	}
+
+	var backup backupDocument
+	if err := json.Unmarshal(data, &backup); err != nil {
+		return nil, fmt.Errorf("invalid backup: %v", err)
+	}
+
+	// Backups written before versioning have no version field
+	if backup.Version == 0 {
+		backup.Version = 1
+	}
+	if backup.Version > backupVersion {
+		return nil, fmt.Errorf("backup version %d is newer than this bot supports (%d)", backup.Version, backupVersion)
+	}
+
+	for i := range backup.Items {
+		item := &backup.Items[i]
+		if item.Kind == "" {
+			item.Kind = kindText
+		}
+		if item.Kind != kindText && (item.Media == nil || item.Media.FileID == "") {
+			return nil, fmt.Errorf("item %d: %s item without file reference", i+1, item.Kind)
+		}
+		if item.Kind == kindText && strings.TrimSpace(item.Text) == "" {
+			return nil, fmt.Errorf("item %d: empty text", i+1)
+		}
+		if item.CreatedAt.IsZero() {
+			item.CreatedAt = backup.ExportedAt
+		}
+	}
+	for i := range backup.DeletedItems {
+		if backup.DeletedItems[i].Kind == "" {
+			backup.DeletedItems[i].Kind = kindText
+		}
+	}
+	return &backup, nil
+}
+
+// backupFromZip extracts backup.json from a zip export
+func backupFromZip(data []byte) ([]byte, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid zip file: %v", err)
+	}
+	for _, f := range zr.File {
+		if f.Name != "backup.json" {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("invalid zip file: %v", err)
+		}
+		defer rc.Close()
+		return io.ReadAll(io.LimitReader(rc, maxDownloadSize))
+	}
+	return nil, errors.New("zip file has no backup.json")
+}
+
+// itemKey identifies duplicate items across backups
+func itemKey(text, kind, fileID string) string {
+	return kind + "\x00" + fileID + "\x00" + text
+}
+
+// existingItemKeys returns the duplicate keys of all stored items
+func existingItemKeys(s ItemStore) (map[string]bool, error) {
+	items, err := s.List(ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	keys := make(map[string]bool)
+	for _, item := range items {
+		keys[itemKey(item.Text, item.Kind, item.FileID)] = true
+	}
+	return keys, nil
+}
+
+// countDuplicates reports how many backup items are already stored
+func countDuplicates(backup *backupDocument) (int, error) {
+	keys, err := existingItemKeys(store)
+	if err != nil {
+		return 0, err
+	}
+	dups := 0
+	for _, item := range backup.Items {
+		key := itemKey(item.Text, item.Kind, mediaFileID(item))
+		if keys[key] {
+			dups++
+		}
+		keys[key] = true
+	}
+	return dups, nil
+}
+
+func mediaFileID(item backupItem) string {
+	if item.Media == nil {
+		return ""
+	}
+	return item.Media.FileID
+}
+
+// applyImport writes a backup into the database. In replace mode all
+// existing items, tags, revisions and deleted items are removed first; in
+// merge mode items already present are skipped. Timestamps are preserved.
+func applyImport(backup *backupDocument, mode string) (importResult, error) {
+	var result importResult
+	if mode != importMerge && mode != importReplace {
+		return result, fmt.Errorf("unknown import mode %q", mode)
+	}
+
+	err := store.Transaction(func(tx ItemStore) error {
+		var err error
+		result, err = importInto(tx, backup, mode)
+		return err
+	})
+	return result, err
+}
+
+// importInto does the work of applyImport on a store
+func importInto(s ItemStore, backup *backupDocument, mode string) (importResult, error) {
+	var result importResult
+	itemKeys := make(map[string]bool)
+	deletedKeys := make(map[string]bool)
+
+	if mode == importReplace {
+		items, deleted, err := s.Clear()
+		if err != nil {
+			return result, err
+		}
+		result.ReplacedItems, result.ReplacedDeleted = items, deleted
+	} else {
+		var err error
+		if itemKeys, err = existingItemKeys(s); err != nil {
+			return result, err
+		}
+		deleted, err := s.Deleted(0)
+		if err != nil {
+			return result, err
+		}
+		for _, d := range deleted {
+			deletedKeys[d.Text+"\x00"+d.DeletedAt.UTC().Format(time.RFC3339)] = true
+		}
+	}
+
+	for _, item := range backup.Items {
+		key := itemKey(item.Text, item.Kind, mediaFileID(item))
+		if itemKeys[key] {
+			result.DuplicateItems++
+			continue
+		}
+		itemKeys[key] = true
+
+		media := backupMedia{}
+		if item.Media != nil {
+			media = *item.Media
+		}
+		id, err := s.Add(Item{
+			Text:      item.Text,
+			Kind:      item.Kind,
+			FileID:    media.FileID,
+			Source:    sourceImport,
+			CreatedAt: item.CreatedAt,
+			FileName:  media.FileName,
+			MimeType:  media.MimeType,
+			FileSize:  media.FileSize,
+			Duration:  media.Duration,
+		})
+		if err != nil {
+			return result, err
+		}
+		if tags := normalizeTags(item.Tags); len(tags) > 0 {
+			if err := s.AddTags(id, tags); err != nil {
+				return result, err
+			}
+		}
+		result.Items++
+	}
+
+	for _, item := range backup.DeletedItems {
+		key := item.Text + "\x00" + item.DeletedAt.UTC().Format(time.RFC3339)
+		if deletedKeys[key] {
+			result.DuplicateDeleted++
+			continue
+		}
+		deletedKeys[key] = true
+
+		if err := s.AddDeleted(DeletedItem{Text: item.Text, Kind: item.Kind, DeletedAt: item.DeletedAt}); err != nil {
+			return result, err
+		}
+		result.DeletedItems++
+	}
+	return result, nil
+}
+
+// String describes the result for the user
+func (r importResult) String() string {
+	var lines []string
+	if r.ReplacedItems > 0 || r.ReplacedDeleted > 0 {
+		lines = append(lines, fmt.Sprintf("• Removed %d items and %d deleted items", r.ReplacedItems, r.ReplacedDeleted))
+	}
+	lines = append(lines, fmt.Sprintf("• Imported %d items", r.Items))
+	if r.DuplicateItems > 0 {
+		lines = append(lines, fmt.Sprintf("• Skipped %d duplicate items", r.DuplicateItems))
+	}
+	lines = append(lines, fmt.Sprintf("• Imported %d deleted items", r.DeletedItems))
+	if r.DuplicateDeleted > 0 {
+		lines = append(lines, fmt.Sprintf("• Skipped %d duplicate deleted items", r.DuplicateDeleted))
+	}
+	return "✅ Import finished\n" + strings.Join(lines, "\n")
+}
+
+// handleImportCommand implements /import: the next document sent to the
+// chat is treated as a backup
+func handleImportCommand(m *tgbot.Message) string {
+	piMutex.Lock()
+	awaitingImport[m.Chat.ID] = time.Now()
+	piMutex.Unlock()
+	return "📤 Send the mindbot-backup .json (or .zip) file produced by /export."
+}
+
+// wantsImport reports whether a message is a backup upload: a document
+// captioned /import, or any document after /import
+func wantsImport(m *tgbot.Message) bool {
+	if m.Document == nil {
+		return false
+	}
+	first, _, _ := strings.Cut(strings.TrimSpace(m.Caption), " ")
+	first, _, _ = strings.Cut(first, "@")
+	if first == "/import" {
+		return true
+	}
+
+	piMutex.Lock()
+	defer piMutex.Unlock()
+	since, ok := awaitingImport[m.Chat.ID]
+	return ok && time.Since(since) < importTimeout
+}
+
+// handleImportDocument downloads and validates an uploaded backup, then
+// asks whether to merge or replace
+func handleImportDocument(bot *tgbot.BotAPI, m *tgbot.Message) string {
+	piMutex.Lock()
+	delete(awaitingImport, m.Chat.ID)
+	piMutex.Unlock()
+
+	if m.Document.FileSize > maxDownloadSize {
+		return fmt.Sprintf("❌ Backup is larger than %d MB.", maxDownloadSize>>20)
+	}
+	data, err := downloadTelegramFile(bot, m.Document.FileID)
+	if err != nil {
+		log.Printf("Error downloading backup: %v", err)
+		return "❌ Failed to download the backup file."
+	}
+
+	backup, err := parseBackup(data)
+	if err != nil {
+		return fmt.Sprintf("❌ %v", err)
+	}
+	dups, err := countDuplicates(backup)
+	if err != nil {
+		log.Printf("Error checking duplicates: %v", err)
+		return "❌ Failed to read existing items."
+	}
+
+	piMutex.Lock()
+	pendingImports[m.Chat.ID] = &pendingImport{Backup: backup, UploadedAt: time.Now()}
+	piMutex.Unlock()
+
+	preview := fmt.Sprintf("📦 Backup from %s (format v%d)\n• %d items (%d already stored)\n• %d deleted items\n\n"+
+		"Merge adds the new items. Replace deletes everything stored first.",
+		backup.ExportedAt.Format("2006-01-02 15:04"), backup.Version,
+		len(backup.Items), dups, len(backup.DeletedItems))
+
+	reply := tgbot.NewMessage(m.Chat.ID, preview)
+	reply.ReplyMarkup = tgbot.NewInlineKeyboardMarkup(tgbot.NewInlineKeyboardRow(
+		tgbot.NewInlineKeyboardButtonData("➕ Merge", "import:"+importMerge),
+		tgbot.NewInlineKeyboardButtonData("♻️ Replace", "import:"+importReplace),
+		tgbot.NewInlineKeyboardButtonData("✖️ Cancel", "import:cancel"),
+	))
+	if _, err := bot.Send(reply); err != nil {
+		log.Printf("Error sending message: %v", err)
+	}
+	return ""
+}
+
+// importDenied returns why a user may not use an import button, or "" if
+// they may. Replace wipes every item, so it always needs an admin.
+func importDenied(access *accessControl, mode string, userID int64) string {
+	switch mode {
+	case importMerge, "cancel":
+		return ""
+	case importReplace:
+		if !access.hasAdmins() {
+			return "Replace needs an admin, set ADMIN_USER_IDS."
+		}
+		if !access.isAdmin(userID) {
+			return "Only admins can replace all items."
+		}
+		return ""
+	default:
+		return "Unknown import option."
+	}
+}
+
+// handleImportCallback applies or cancels a pending import. Replace is
+// limited to admins when access control is enabled.
+func handleImportCallback(bot *tgbot.BotAPI, q *tgbot.CallbackQuery, access *accessControl) {
+	chatID := q.Message.Chat.ID
+	_, mode, _ := strings.Cut(q.Data, ":")
+
+	answer := tgbot.NewCallback(q.ID, "")
+	defer func() {
+		if _, err := bot.Request(answer); err != nil {
+			log.Printf("Error answering callback: %v", err)
+		}
+	}()
+
+	if reason := importDenied(access, mode, q.From.ID); reason != "" {
+		answer.Text = reason
+		return
+	}
+
+	piMutex.Lock()
+	pending, ok := pendingImports[chatID]
+	delete(pendingImports, chatID)
+	piMutex.Unlock()
+
+	text := ""
+	switch {
+	case mode == "cancel":
+		text = "Import cancelled."
+	case !ok || time.Since(pending.UploadedAt) > importTimeout:
+		text = "This import has expired. Send the file again with /import."
+	default:
+		result, err := applyImport(pending.Backup, mode)
+		if err != nil {
+			log.Printf("Error importing backup: %v", err)
+			text = "❌ Import failed, nothing was changed."
+		} else {
+			log.Printf("Imported backup (%s) by user %d: %+v", mode, q.From.ID, result)
+			text = result.String()
+		}
+	}
+
+	edit := tgbot.NewEditMessageText(chatID, q.Message.MessageID, text)
+	if _, err := bot.Send(edit); err != nil {
+		log.Printf("Error editing message: %v", err)
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+const legacyBackup = `{
+    "items": [
+        {"text": "buy milk", "created_at": "2024-03-01T10:00:00Z"},
+        {"text": "call mom", "created_at": "2024-03-02T11:30:00Z"}
+    ],
+    "deleted_items": [
+        {"text": "old idea", "deleted_at": "2024-02-20T09:00:00Z"}
+    ],
+    "exported_at": "2024-03-05T12:00:00Z"
+}`
+
+func TestParseBackup(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		version int
+		items   int
+		wantErr string
+	}{
+		{"legacy", legacyBackup, 1, 2, ""},
+		{"current", `{"version": 2, "items": [{"text": "x", "kind": "photo", "media": {"file_id": "f"}}], "exported_at": "2024-03-05T12:00:00Z"}`, 2, 1, ""},
+		{"newer", `{"version": 99, "items": [], "exported_at": "2024-03-05T12:00:00Z"}`, 0, 0, "newer"},
+		{"not a backup", `{"foo": 1}`, 0, 0, "missing"},
+		{"not json", `hello`, 0, 0, "not a valid JSON"},
+		{"media without file", `{"version": 2, "items": [{"text": "x", "kind": "photo"}], "exported_at": "2024-03-05T12:00:00Z"}`, 0, 0, "file reference"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backup, err := parseBackup([]byte(tt.data))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("parseBackup() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if backup.Version != tt.version || len(backup.Items) != tt.items {
+				t.Errorf("got version %d with %d items, want %d with %d", backup.Version, len(backup.Items), tt.version, tt.items)
+			}
+		})
+	}
+}
+
+func TestApplyImport(t *testing.T) {
+	if err := initDB(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if _, err := storeCapturedItem(capturedItem{Text: "buy milk", Source: sourceMessage}, 1, 1); err != nil {
+		t.Fatal(err)
+	}
+	backup, err := parseBackup([]byte(legacyBackup))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if dups, err := countDuplicates(backup); err != nil || dups != 1 {
+		t.Fatalf("countDuplicates() = %d, %v; want 1", dups, err)
+	}
+
+	result, err := applyImport(backup, importMerge)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Items != 1 || result.DuplicateItems != 1 || result.DeletedItems != 1 {
+		t.Errorf("merge result = %+v", result)
+	}
+
+	var createdAt time.Time
+	if err := db.QueryRow("SELECT created_at FROM items WHERE text = 'call mom'").Scan(&createdAt); err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2024, 3, 2, 11, 30, 0, 0, time.UTC); !createdAt.Equal(want) {
+		t.Errorf("created_at = %v, want %v", createdAt, want)
+	}
+
+	// Importing the same file again changes nothing
+	result, err = applyImport(backup, importMerge)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Items != 0 || result.DuplicateItems != 2 || result.DuplicateDeleted != 1 {
+		t.Errorf("second merge result = %+v", result)
+	}
+
+	// Round trip through /export and replace
+	if err := store.AddTags(1, []string{"shopping"}); err != nil {
+		t.Fatal(err)
+	}
+	exported, err := buildBackup()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(exported)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored, err := parseBackup(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err = applyImport(restored, importReplace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.ReplacedItems != 2 || result.Items != 2 || result.DeletedItems != 1 {
+		t.Errorf("replace result = %+v", result)
+	}
+
+	var tagged int
+	if err := db.QueryRow("SELECT COUNT(*) FROM item_tags WHERE tag = 'shopping'").Scan(&tagged); err != nil {
+		t.Fatal(err)
+	}
+	if tagged != 1 {
+		t.Errorf("tags after replace = %d, want 1", tagged)
+	}
+}
+
+func TestImportDenied(t *testing.T) {
+	if err := initDB(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// An open bot without admins never allows Replace
+	open, err := newAccessControl(db, &Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := importDenied(open, importReplace, 5); got == "" {
+		t.Error("Replace allowed on a bot without admins")
+	}
+	if got := importDenied(open, importMerge, 5); got != "" {
+		t.Errorf("Merge denied: %q", got)
+	}
+
+	// An admin granted in the database counts too
+	if err := open.allow(7, roleAdmin, 0); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		mode   string
+		userID int64
+		denied bool
+	}{
+		{importReplace, 7, false},
+		{importReplace, 5, true},
+		{importMerge, 5, false},
+		{"cancel", 5, false},
+		{"wipe", 7, true},
+		{"", 7, true},
+	}
+	if _, err := applyImport(&backupDocument{}, "wipe"); err == nil {
+		t.Error("applyImport() accepted an unknown mode")
+	}
+	for _, tt := range tests {
+		if got := importDenied(open, tt.mode, tt.userID); (got != "") != tt.denied {
+			t.Errorf("importDenied(%q, %d) = %q, want denied %v", tt.mode, tt.userID, got, tt.denied)
+		}
+	}
+}
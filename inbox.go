@@ -0,0 +1,346 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	tgbot "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Item sources
+const (
+	sourceCommand = "command"
+	sourceMessage = "message"
+	sourceForward = "forward"
+	sourceReply   = "reply"
+	sourceCLI     = "cli"
+	sourceImport  = "import"
+)
+
+const (
+	// inboxUndoWindow is how long the Undo button of a saved item works
+	inboxUndoWindow = 10 * time.Minute
+	// pendingTagsTimeout is how long the bot waits for tags after Tag
+	pendingTagsTimeout = 5 * time.Minute
+)
+
+// pendingTagKey identifies who was asked for tags, so that in a group only
+// the member who pressed Tag answers
+type pendingTagKey struct {
+	ChatID int64
+	UserID int64
+}
+
+// pendingTag is an item waiting for tags
+type pendingTag struct {
+	ItemID  int64
+	AskedAt time.Time
+}
+
+var (
+	pendingTags = make(map[pendingTagKey]pendingTag)
+	ptMutex     = &sync.Mutex{} // Protects pendingTags map
+)
+
+// takePendingTag returns and forgets the item a user was asked to tag,
+// unless the request has expired
+func takePendingTag(chatID, userID int64, now time.Time) (int64, bool) {
+	key := pendingTagKey{ChatID: chatID, UserID: userID}
+	ptMutex.Lock()
+	defer ptMutex.Unlock()
+	p, ok := pendingTags[key]
+	delete(pendingTags, key)
+	// Drop requests nobody answered
+	for k, other := range pendingTags {
+		if now.Sub(other.AskedAt) > pendingTagsTimeout {
+			delete(pendingTags, k)
+		}
+	}
+	if !ok || now.Sub(p.AskedAt) > pendingTagsTimeout {
+		return 0, false
+	}
+	return p.ItemID, true
+}
+
+// capturedItem describes a note taken from a plain message
+type capturedItem struct {
+	Text       string
+	Source     string
+	Origin     string     // original sender or channel of a forward
+	OriginDate *time.Time // when the forwarded message was first sent
+	ReplyTo    string     // text of the message being replied to
+}
+
+// captureFromMessage extracts the note from a non-command message. It
+// returns false if the message carries no text.
+func captureFromMessage(m *tgbot.Message) (capturedItem, bool) {
+	c := capturedItem{Text: strings.TrimSpace(m.Text), Source: sourceMessage}
+	if c.Text == "" {
+		c.Text = stripAddCaption(m.Caption)
+	}
+
+	switch {
+	case m.ForwardDate != 0:
+		c.Source = sourceForward
+		c.Origin = forwardOrigin(m)
+		t := time.Unix(int64(m.ForwardDate), 0).UTC()
+		c.OriginDate = &t
+	case m.ReplyToMessage != nil:
+		c.Source = sourceReply
+		c.ReplyTo = m.ReplyToMessage.Text
+		if c.ReplyTo == "" {
+			c.ReplyTo = m.ReplyToMessage.Caption
+		}
+	}
+	return c, c.Text != ""
+}
+
+// forwardOrigin describes where a forwarded message came from
+func forwardOrigin(m *tgbot.Message) string {
+	switch {
+	case m.ForwardFrom != nil:
+		name := strings.TrimSpace(m.ForwardFrom.FirstName + " " + m.ForwardFrom.LastName)
+		if m.ForwardFrom.UserName != "" {
+			name += " (@" + m.ForwardFrom.UserName + ")"
+		}
+		return name
+	case m.ForwardFromChat != nil:
+		name := m.ForwardFromChat.Title
+		if m.ForwardFromChat.UserName != "" {
+			name += " (@" + m.ForwardFromChat.UserName + ")"
+		}
+		if m.ForwardSignature != "" {
+			name += ", " + m.ForwardSignature
+		}
+		return name
+	case m.ForwardSenderName != "":
+		// Sender hides their account
+		return m.ForwardSenderName
+	}
+	return "unknown"
+}
+
+// storeCapturedItem inserts a captured note and returns its ID
+func storeCapturedItem(c capturedItem, chatID int64, messageID int) (int64, error) {
+	return store.Add(Item{
+		Text:       c.Text,
+		ChatID:     chatID,
+		MessageID:  messageID,
+		Source:     c.Source,
+		Origin:     c.Origin,
+		OriginDate: c.OriginDate,
+		ReplyTo:    c.ReplyTo,
+	})
+}
+
+// normalizeTags lower-cases tags, strips leading '#' and drops duplicates
+func normalizeTags(fields []string) []string {
+	seen := make(map[string]bool)
+	var tags []string
+	for _, f := range fields {
+		for _, tag := range strings.Split(f, ",") {
+			tag = strings.ToLower(strings.TrimLeft(strings.TrimSpace(tag), "#"))
+			if tag == "" || seen[tag] {
+				continue
+			}
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// undoCapturedItem deletes an item just saved from a chat, keeping a copy
+// among the deleted items like /delete. It returns the new text of the
+// saved message, or "" and a short notice when the item can't be undone.
+func undoCapturedItem(itemID, chatID int64, now time.Time) (string, string) {
+	item, err := store.Get(itemID)
+	if err == errItemNotFound {
+		return fmt.Sprintf("#%d was already removed", itemID), ""
+	} else if err != nil {
+		log.Printf("Error loading item %d: %v", itemID, err)
+		return "", "Failed to remove item."
+	}
+	if item.ChatID != chatID {
+		return "", "This item wasn't saved from this chat."
+	}
+	if now.Sub(item.CreatedAt) > inboxUndoWindow {
+		return "", fmt.Sprintf("Too late to undo, use /delete after pulling #%d.", itemID)
+	}
+
+	if removed, err := store.Delete(itemID); err != nil {
+		log.Printf("Error removing item %d: %v", itemID, err)
+		return "", "Failed to remove item."
+	} else if !removed {
+		return fmt.Sprintf("#%d was already removed", itemID), ""
+	}
+
+	ldMutex.Lock()
+	lastDeleted[chatID] = struct {
+		Text      string
+		DeletedAt time.Time
+	}{
+		Text:      item.Text,
+		DeletedAt: now,
+	}
+	ldMutex.Unlock()
+	return fmt.Sprintf("↩️ Removed #%d", itemID), ""
+}
+
+// inboxKeyboard offers Undo and Tag buttons for a captured item
+func inboxKeyboard(itemID int64) tgbot.InlineKeyboardMarkup {
+	id := strconv.FormatInt(itemID, 10)
+	return tgbot.NewInlineKeyboardMarkup(tgbot.NewInlineKeyboardRow(
+		tgbot.NewInlineKeyboardButtonData("↩️ Undo", "undo:"+id),
+		tgbot.NewInlineKeyboardButtonData("🏷 Tag", "tag:"+id),
+	))
+}
+
+// savesMessage reports whether a non-command message is saved as an item.
+// Media captioned "/add ..." and voice notes to transcribe are saved even
+// outside inbox mode.
+func savesMessage(f Features, m *tgbot.Message) bool {
+	media, hasMedia := mediaFromMessage(m)
+	return f.Inbox || (hasMedia && isAddCaption(m.Caption)) || (f.Transcribe && isTranscribable(media))
+}
+
+// handleInboxMessage saves a plain, forwarded or reply message as an item,
+// or applies tags if the chat was asked for them
+func handleInboxMessage(bot *tgbot.BotAPI, m *tgbot.Message) {
+	var userID int64
+	if m.From != nil {
+		userID = m.From.ID
+	}
+	itemID, waiting := takePendingTag(m.Chat.ID, userID, time.Now())
+
+	reply := tgbot.NewMessage(m.Chat.ID, "")
+	reply.ReplyToMessageID = m.MessageID
+
+	var transcribeID int64
+	media, hasMedia := mediaFromMessage(m)
+	if waiting && !hasMedia && m.ForwardDate == 0 && m.Text != "" {
+		reply.Text = tagItem(itemID, strings.Fields(m.Text))
+	} else {
+		c, ok := captureFromMessage(m)
+		if !ok && !hasMedia {
+			return
+		}
+
+		var id int64
+		var err error
+		kind := kindText
+		if hasMedia {
+			kind = media.Kind
+			id, err = storeMediaItem(c, media, m.Chat.ID, m.MessageID)
+		} else {
+			id, err = storeCapturedItem(c, m.Chat.ID, m.MessageID)
+		}
+		if err != nil {
+			log.Printf("Error storing item: %v", err)
+			reply.Text = "Failed to store item."
+		} else {
+			reply.Text = fmt.Sprintf("📥 Saved %s#%d", kindIcon(kind), id)
+			if c.Source == sourceForward {
+				reply.Text += " (forwarded from " + c.Origin + ")"
+			}
+			if suffix := transcribingSuffix(media); suffix != "" {
+				reply.Text += suffix
+				transcribeID = id
+			}
+			reply.ReplyMarkup = inboxKeyboard(id)
+		}
+	}
+
+	sent, err := bot.Send(reply)
+	if err != nil {
+		log.Printf("Error sending message: %v", err)
+		return
+	}
+
+	// Voice notes get their transcript as text once it is ready
+	if transcribeID != 0 {
+		c, _ := captureFromMessage(m)
+		transcribeInBackground(bot, sent, transcribeID, media, c.Text)
+	}
+}
+
+// tagItem adds tags to an item and returns the reply text
+func tagItem(itemID int64, fields []string) string {
+	tags := normalizeTags(fields)
+	if len(tags) == 0 {
+		return "No tags given."
+	}
+	if err := store.AddTags(itemID, tags); err == errItemNotFound {
+		return fmt.Sprintf("No item #%d.", itemID)
+	} else if err != nil {
+		log.Printf("Error tagging item %d: %v", itemID, err)
+		return "Failed to tag item."
+	}
+
+	all, err := store.Tags(itemID)
+	if err != nil {
+		log.Printf("Error loading tags for %d: %v", itemID, err)
+		all = tags
+	}
+	return fmt.Sprintf("🏷 #%d tagged: %s", itemID, strings.Join(all, ", "))
+}
+
+// handleTag implements /tag <id> <tags...>
+func handleTag(m *tgbot.Message) string {
+	fields := strings.Fields(m.CommandArguments())
+	if len(fields) < 2 {
+		return "Usage: /tag <id> <tag> [more tags]"
+	}
+	id, err := strconv.ParseInt(strings.TrimPrefix(fields[0], "#"), 10, 64)
+	if err != nil {
+		return "Usage: /tag <id> <tag> [more tags]"
+	}
+	return tagItem(id, fields[1:])
+}
+
+// handleInboxCallback reacts to the Undo and Tag buttons
+func handleInboxCallback(bot *tgbot.BotAPI, q *tgbot.CallbackQuery) {
+	action, rawID, _ := strings.Cut(q.Data, ":")
+	itemID, err := strconv.ParseInt(rawID, 10, 64)
+	if err != nil || q.Message == nil {
+		return
+	}
+	chatID := q.Message.Chat.ID
+
+	answer := tgbot.NewCallback(q.ID, "")
+	switch action {
+	case "undo":
+		text, notice := undoCapturedItem(itemID, chatID, time.Now())
+		answer.Text = notice
+		if text == "" {
+			break
+		}
+		edit := tgbot.NewEditMessageText(chatID, q.Message.MessageID, text)
+		if _, err := bot.Send(edit); err != nil {
+			log.Printf("Error editing message: %v", err)
+		}
+	case "tag":
+		if item, err := store.Get(itemID); err != nil || item.ChatID != chatID {
+			answer.Text = fmt.Sprintf("#%d can't be tagged from this chat.", itemID)
+			break
+		}
+		ptMutex.Lock()
+		pendingTags[pendingTagKey{ChatID: chatID, UserID: q.From.ID}] = pendingTag{ItemID: itemID, AskedAt: time.Now()}
+		ptMutex.Unlock()
+		answer.Text = "Send the tags as your next message"
+		prompt := tgbot.NewMessage(chatID, fmt.Sprintf("🏷 Send tags for #%d, separated by spaces", itemID))
+		if _, err := bot.Send(prompt); err != nil {
+			log.Printf("Error sending message: %v", err)
+		}
+	default:
+		return
+	}
+
+	if _, err := bot.Request(answer); err != nil {
+		log.Printf("Error answering callback: %v", err)
+	}
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	tgbot "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestCaptureFromMessage(t *testing.T) {
+	tests := []struct {
+		name       string
+		msg        *tgbot.Message
+		wantOK     bool
+		wantSource string
+		wantOrigin string
+		wantReply  string
+	}{
+		{
+			name:       "Plain text",
+			msg:        &tgbot.Message{Text: "buy milk"},
+			wantOK:     true,
+			wantSource: sourceMessage,
+		},
+		{
+			name: "Forward from user",
+			msg: &tgbot.Message{
+				Text:        "great idea",
+				ForwardDate: 1700000000,
+				ForwardFrom: &tgbot.User{FirstName: "Ada", LastName: "Lovelace", UserName: "ada"},
+			},
+			wantOK:     true,
+			wantSource: sourceForward,
+			wantOrigin: "Ada Lovelace (@ada)",
+		},
+		{
+			name: "Forward from channel",
+			msg: &tgbot.Message{
+				Caption:         "photo caption",
+				ForwardDate:     1700000000,
+				ForwardFromChat: &tgbot.Chat{Title: "News", UserName: "news"},
+			},
+			wantOK:     true,
+			wantSource: sourceForward,
+			wantOrigin: "News (@news)",
+		},
+		{
+			name:       "Hidden forward",
+			msg:        &tgbot.Message{Text: "psst", ForwardDate: 1700000000, ForwardSenderName: "Anonymous"},
+			wantOK:     true,
+			wantSource: sourceForward,
+			wantOrigin: "Anonymous",
+		},
+		{
+			name:       "Reply",
+			msg:        &tgbot.Message{Text: "and also eggs", ReplyToMessage: &tgbot.Message{Text: "buy milk"}},
+			wantOK:     true,
+			wantSource: sourceReply,
+			wantReply:  "buy milk",
+		},
+		{
+			name:   "No text",
+			msg:    &tgbot.Message{},
+			wantOK: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := captureFromMessage(tt.msg)
+			if ok != tt.wantOK {
+				t.Fatalf("captureFromMessage() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			if got.Source != tt.wantSource || got.Origin != tt.wantOrigin || got.ReplyTo != tt.wantReply {
+				t.Errorf("captureFromMessage() = %+v", got)
+			}
+			if tt.wantSource == sourceForward && got.OriginDate == nil {
+				t.Error("captureFromMessage() did not keep the forward date")
+			}
+		})
+	}
+}
+
+func TestInboxTags(t *testing.T) {
+	if err := initDB(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if got := normalizeTags([]string{"#Work", "ideas,work", " "}); !reflect.DeepEqual(got, []string{"work", "ideas"}) {
+		t.Errorf("normalizeTags() = %v", got)
+	}
+
+	c, _ := captureFromMessage(&tgbot.Message{Text: "note", ForwardDate: 1700000000, ForwardSenderName: "Bob"})
+	id, err := storeCapturedItem(c, 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.AddTags(id, []string{"work", "ideas"}); err != nil {
+		t.Fatal(err)
+	}
+	if tags, _ := store.Tags(id); !reflect.DeepEqual(tags, []string{"ideas", "work"}) {
+		t.Errorf("store.Tags() = %v", tags)
+	}
+
+	// Undo only works from the same chat and shortly after saving
+	now := time.Now()
+	if text, notice := undoCapturedItem(id, 99, now); text != "" || notice == "" {
+		t.Errorf("undo from another chat = %q, %q", text, notice)
+	}
+	if text, notice := undoCapturedItem(id, 1, now.Add(inboxUndoWindow+time.Minute)); text != "" || !strings.Contains(notice, "Too late") {
+		t.Errorf("late undo = %q, %q", text, notice)
+	}
+	if text, notice := undoCapturedItem(id, 1, now); text != fmt.Sprintf("↩️ Removed #%d", id) || notice != "" {
+		t.Fatalf("undo = %q, %q", text, notice)
+	}
+	if err := store.AddTags(id, []string{"x"}); err != errItemNotFound {
+		t.Errorf("store.AddTags() on removed item error = %v, want errItemNotFound", err)
+	}
+	if deleted, err := store.Deleted(1); err != nil || len(deleted) != 1 || deleted[0].Text != "note" {
+		t.Errorf("Deleted() = %v, %v; want the undone item kept", deleted, err)
+	}
+	if text, _ := undoCapturedItem(id, 1, now); !strings.Contains(text, "already removed") {
+		t.Errorf("second undo = %q", text)
+	}
+}
+
+func TestPendingTags(t *testing.T) {
+	now := time.Now()
+	ptMutex.Lock()
+	pendingTags[pendingTagKey{ChatID: -5, UserID: 1}] = pendingTag{ItemID: 7, AskedAt: now}
+	pendingTags[pendingTagKey{ChatID: -5, UserID: 2}] = pendingTag{ItemID: 8, AskedAt: now.Add(-pendingTagsTimeout - time.Second)}
+	ptMutex.Unlock()
+
+	// Another member of the group doesn't answer for user 1
+	if _, ok := takePendingTag(-5, 3, now); ok {
+		t.Error("takePendingTag() for a user who wasn't asked succeeded")
+	}
+	if id, ok := takePendingTag(-5, 1, now); !ok || id != 7 {
+		t.Errorf("takePendingTag() = %d, %v; want 7", id, ok)
+	}
+	if _, ok := takePendingTag(-5, 1, now); ok {
+		t.Error("takePendingTag() returned the same request twice")
+	}
+	if _, ok := takePendingTag(-5, 2, now); ok {
+		t.Error("takePendingTag() returned an expired request")
+	}
+}
+
+func TestAddCaptionInboxOff(t *testing.T) {
+	if err := initDB(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	off := Features{}
+	photo := []tgbot.PhotoSize{{FileID: "p1", FileUniqueID: "u1"}}
+	tests := []struct {
+		name     string
+		msg      *tgbot.Message
+		wantSave bool
+		wantText string
+	}{
+		{"Caption on one line", &tgbot.Message{Photo: photo, Caption: "/add sunset"}, true, "sunset"},
+		{"Caption on the next line", &tgbot.Message{Photo: photo, Caption: "/add\nsunset at the pier"}, true, "sunset at the pier"},
+		{"Bot mention", &tgbot.Message{Photo: photo, Caption: "/add@mybot\tsunset"}, true, "sunset"},
+		{"Bare /add", &tgbot.Message{Photo: photo, Caption: "/add"}, true, ""},
+		{"Plain caption", &tgbot.Message{Photo: photo, Caption: "sunset"}, false, ""},
+		{"Text only", &tgbot.Message{Text: "/add sunset"}, false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := savesMessage(off, tt.msg); got != tt.wantSave {
+				t.Fatalf("savesMessage() = %v, want %v", got, tt.wantSave)
+			}
+			if !tt.wantSave {
+				return
+			}
+
+			media, _ := mediaFromMessage(tt.msg)
+			c, _ := captureFromMessage(tt.msg)
+			id, err := storeMediaItem(c, media, 1, 2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if item, err := store.Get(id); err != nil || item.Text != tt.wantText {
+				t.Errorf("stored item = %+v, %v; want text %q", item, err, tt.wantText)
+			}
+		})
+	}
+}
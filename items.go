@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	tgbot "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// handleAdd implements /add <text>
+func handleAdd(m *tgbot.Message) string {
+	text := m.CommandArguments()
+	if text == "" {
+		return "Usage: /add something"
+	}
+
+	// Remember the source message so editing it updates the note
+	id, err := store.Add(Item{Text: text, ChatID: m.Chat.ID, MessageID: m.MessageID})
+	if err != nil {
+		log.Printf("Error storing item: %v", err)
+		return "Failed to store item."
+	}
+	return fmt.Sprintf("Added #%d: %s ✅", id, text)
+}
+
+// handlePull implements /pull. Media items are re-sent with the text as
+// caption, in which case it returns "".
+func handlePull(bot *tgbot.BotAPI, m *tgbot.Message) string {
+	item, err := store.RandomPull()
+	if err == errItemNotFound {
+		return "No items available."
+	} else if err != nil {
+		log.Printf("Error pulling item: %v", err)
+		return "Failed to pull item."
+	}
+
+	lpMutex.Lock()
+	lastPulled[m.Chat.ID] = pulledItem{ID: item.ID, Text: item.Text}
+	lpMutex.Unlock()
+	text := fmt.Sprintf("🎲 %s (#%d)", item.Text, item.ID)
+
+	// Re-send stored media with the caption
+	if item.Kind != kindText && item.FileID != "" {
+		media, err := mediaMessage(m.Chat.ID, item.Kind, item.FileID, text)
+		if err == nil {
+			if _, err = bot.Send(media); err == nil {
+				return ""
+			}
+		}
+		log.Printf("Error sending media for item %d: %v", item.ID, err)
+	}
+	return text
+}
+
+// handleDelete implements /delete, which deletes the last pulled item
+func handleDelete(m *tgbot.Message) string {
+	lpMutex.RLock()
+	item, ok := lastPulled[m.Chat.ID]
+	lpMutex.RUnlock()
+	if !ok {
+		return "Pull an item first using /pull"
+	}
+
+	removed, err := store.Delete(item.ID)
+	if err != nil {
+		log.Printf("Error deleting item: %v", err)
+		return "Failed to delete item."
+	}
+	lpMutex.Lock()
+	delete(lastPulled, m.Chat.ID)
+	lpMutex.Unlock()
+	if !removed {
+		// Someone else deleted it since it was pulled; there is nothing to undo
+		return fmt.Sprintf("No item #%d.", item.ID)
+	}
+
+	// Store the item for undo functionality
+	ldMutex.Lock()
+	lastDeleted[m.Chat.ID] = struct {
+		Text      string
+		DeletedAt time.Time
+	}{
+		Text:      item.Text,
+		DeletedAt: time.Now(),
+	}
+	ldMutex.Unlock()
+
+	return fmt.Sprintf("Deleted: %s 🗑️", item.Text)
+}
+
+// handleList implements /list [kind], showing when each item was added in
+// the user's time zone
+func handleList(m *tgbot.Message, loc *time.Location) string {
+	kind := strings.ToLower(strings.TrimSpace(m.CommandArguments()))
+	if kind != "" && !slices.Contains(itemKinds, kind) {
+		return "Usage: /list [" + strings.Join(itemKinds, "|") + "]"
+	}
+
+	items, err := store.List(ListOptions{Kind: kind})
+	if err != nil {
+		log.Printf("Error listing items: %v", err)
+		return "Failed to list items."
+	}
+	if len(items) == 0 {
+		return "No items available."
+	}
+
+	lines := make([]string, len(items))
+	for i, item := range items {
+		text := item.Text
+		if text == "" {
+			text = "(" + item.Kind + ")"
+		}
+		lines[i] = fmt.Sprintf("• #%d %s%s · %s", item.ID, kindIcon(item.Kind), text,
+			item.CreatedAt.In(loc).Format("Jan 2 15:04"))
+	}
+	header := "Your items:"
+	if loc != time.UTC {
+		header = fmt.Sprintf("Your items (times in %s):", loc)
+	}
+	return header + "\n" + strings.Join(lines, "\n")
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	tgbot "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// commandMessage builds a command message as Telegram would send it
+func commandMessage(chatID int64, text string) *tgbot.Message {
+	command, _, _ := strings.Cut(text, " ")
+	return &tgbot.Message{
+		MessageID: 1,
+		Chat:      &tgbot.Chat{ID: chatID},
+		From:      &tgbot.User{ID: chatID},
+		Text:      text,
+		Entities:  []tgbot.MessageEntity{{Type: "bot_command", Offset: 0, Length: len(command)}},
+	}
+}
+
+func TestItemHandlers(t *testing.T) {
+	old := store
+	store = newMemoryStore()
+	defer func() { store = old }()
+
+	const chat = 42
+	steps := []struct {
+		text string
+		want string
+	}{
+		{"/pull", "No items available."},
+		{"/delete", "Pull an item first using /pull"},
+		{"/add", "Usage: /add something"},
+		{"/add water the plants", "Added #1: water the plants ✅"},
+		{"/list", "Your items:\n• #1 water the plants"},
+		{"/list sticker", "Usage: /list ["},
+		{"/list photo", "No items available."},
+		{"/pull", "🎲 water the plants (#1)"},
+		{"/delete", "Deleted: water the plants 🗑️"},
+		{"/delete", "Pull an item first using /pull"},
+		{"/list", "No items available."},
+		{"/add feed the cat", "Added #2: feed the cat ✅"},
+		{"/pull", "🎲 feed the cat (#2)"},
+		{"removed elsewhere", ""},
+		{"/delete", "No item #2."},
+		{"/delete", "Pull an item first using /pull"},
+	}
+	for _, step := range steps {
+		m := commandMessage(chat, step.text)
+		var got string
+		switch m.Command() {
+		case "add":
+			got = handleAdd(m)
+		case "pull":
+			got = handlePull(nil, m)
+		case "delete":
+			got = handleDelete(m)
+		case "list":
+			got = handleList(m, time.UTC)
+		default:
+			if _, err := store.Delete(2); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if !strings.HasPrefix(got, step.want) {
+			t.Errorf("%s = %q, want %q", step.text, got, step.want)
+		}
+	}
+
+	// Deleting an item that was already gone leaves nothing to undo
+	ldMutex.Lock()
+	last := lastDeleted[chat]
+	ldMutex.Unlock()
+	if last.Text != "water the plants" {
+		t.Errorf("lastDeleted = %q, want the item deleted by /delete", last.Text)
+	}
+}
@@ -1,9 +1,11 @@
 package main
 
 import (
-	"bufio"
+	"context"
 	"database/sql"
+	"flag"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"os"
@@ -13,48 +15,52 @@ import (
 	"time"
 
 	tgbot "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/jgabriele321/onmymind/speech"
 	timecalc "github.com/jgabriele321/onmymind/time"
 	_ "github.com/mattn/go-sqlite3"
 )
 
+// pulledItem remembers the item shown by /pull so it can be deleted or edited
+type pulledItem struct {
+	ID   int64
+	Text string
+}
+
 var (
 	db          *sql.DB
-	lastPulled  = make(map[int64]string) // chatID → last pulled text
-	lpMutex     = &sync.RWMutex{}        // Protects lastPulled map
+	lastPulled  = make(map[int64]pulledItem) // chatID → last pulled item
+	lpMutex     = &sync.RWMutex{}            // Protects lastPulled map
 	lastDeleted = make(map[int64]struct {
 		Text      string
 		DeletedAt time.Time
 	})
 	ldMutex        = &sync.RWMutex{} // Protects lastDeleted map
 	timeCalculator *timecalc.TimeCalculator
+	transcriber    speech.Transcriber // nil when transcription is disabled
 )
 
-func startHealthCheck() {
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
-	}
-
+func startHealthCheck(port int, maint *dbMaintenance) {
 	go func() {
-		http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-			w.WriteHeader(http.StatusOK)
-			w.Write([]byte("OK"))
-		})
-		log.Printf("Starting health check server on port %s", port)
-		if err := http.ListenAndServe(":"+port, nil); err != nil {
+		http.HandleFunc("/health", healthHandler(maint))
+		log.Printf("Starting health check server on port %d", port)
+		if err := http.ListenAndServe(fmt.Sprintf(":%d", port), nil); err != nil {
 			log.Printf("Health check server error: %v", err)
 		}
 	}()
 }
 
-func initDB() error {
-	// Get data directory from environment or use default
-	dataDir := os.Getenv("DATA_DIR")
-	if dataDir == "" {
-		// Local development fallback
-		dataDir = "data"
-	}
+// sqliteMaxConns caps the connection pool. SQLite allows one writer at a
+// time, so more connections only add lock contention.
+const sqliteMaxConns = 4
+
+// sqliteDSN enables WAL journaling, waits up to 5s for locks instead of
+// failing with "database is locked", enforces foreign keys and starts
+// transactions with BEGIN IMMEDIATE so two writers never deadlock
+func sqliteDSN(path string) string {
+	return "file:" + path + "?_journal_mode=WAL&_busy_timeout=5000&_foreign_keys=on&_synchronous=NORMAL&_txlock=immediate"
+}
 
+func initDB(dataDir string) error {
 	// Ensure data directory exists
 	if err := os.MkdirAll(dataDir, 0755); err != nil {
 		return fmt.Errorf("failed to create data directory: %v", err)
@@ -65,13 +71,21 @@ func initDB() error {
 	log.Printf("Using database at: %s", dbPath)
 
 	var err error
-	db, err = sql.Open("sqlite3", dbPath)
+	db, err = sql.Open("sqlite3", sqliteDSN(dbPath))
 	if err != nil {
 		return fmt.Errorf("failed to open database: %v", err)
 	}
+	// WAL lets readers run alongside the single writer; a few connections
+	// are enough for the bot, the scheduler and the health check
+	db.SetMaxOpenConns(sqliteMaxConns)
+	db.SetMaxIdleConns(sqliteMaxConns)
+	dbDialect = dialectSQLite
+	return migrateDB(sqliteSchema)
+}
 
-	// Create tables if they don't exist
-	schema := `
+// sqliteSchema creates the tables of the first release plus tables added
+// later. Columns added to existing tables are listed in addedColumns.
+const sqliteSchema = `
 	CREATE TABLE IF NOT EXISTS items (
 		id INTEGER PRIMARY KEY AUTOINCREMENT,
 		text TEXT NOT NULL,
@@ -81,89 +95,239 @@ func initDB() error {
 		id INTEGER PRIMARY KEY,
 		text TEXT NOT NULL,
 		deleted_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE TABLE IF NOT EXISTS users (
+		user_id INTEGER PRIMARY KEY,
+		username TEXT,
+		role TEXT NOT NULL DEFAULT 'user',
+		added_by INTEGER NOT NULL DEFAULT 0,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE TABLE IF NOT EXISTS access_denied (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		user_id INTEGER NOT NULL,
+		username TEXT NOT NULL DEFAULT '',
+		chat_id INTEGER NOT NULL,
+		text TEXT NOT NULL DEFAULT '',
+		attempted_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE TABLE IF NOT EXISTS llm_usage (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		chat_id INTEGER NOT NULL,
+		user_id INTEGER NOT NULL,
+		command TEXT NOT NULL,
+		model TEXT NOT NULL DEFAULT '',
+		prompt_tokens INTEGER NOT NULL DEFAULT 0,
+		completion_tokens INTEGER NOT NULL DEFAULT 0,
+		total_tokens INTEGER NOT NULL DEFAULT 0,
+		cost REAL NOT NULL DEFAULT 0,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS idx_llm_usage_created_at ON llm_usage (created_at);
+	CREATE TABLE IF NOT EXISTS item_revisions (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		item_id INTEGER NOT NULL,
+		text TEXT NOT NULL,
+		edited_by INTEGER NOT NULL DEFAULT 0,
+		source TEXT NOT NULL,
+		edited_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS idx_item_revisions_item_id ON item_revisions (item_id);
+	CREATE TABLE IF NOT EXISTS item_tags (
+		item_id INTEGER NOT NULL,
+		tag TEXT NOT NULL,
+		PRIMARY KEY (item_id, tag)
+	);
+	CREATE TABLE IF NOT EXISTS world_clocks (
+		chat_id INTEGER NOT NULL,
+		label TEXT NOT NULL,
+		zone TEXT NOT NULL,
+		position INTEGER NOT NULL,
+		PRIMARY KEY (chat_id, label)
+	);
+	CREATE TABLE IF NOT EXISTS user_settings (
+		user_id INTEGER PRIMARY KEY,
+		label TEXT NOT NULL,
+		zone TEXT NOT NULL,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
 	);`
 
+// addedColumns are the columns added after the first release
+var addedColumns = []struct{ table, column, def string }{
+	{"items", "chat_id", "INTEGER NOT NULL DEFAULT 0"},
+	{"items", "message_id", "INTEGER NOT NULL DEFAULT 0"},
+	{"items", "updated_at", "DATETIME"},
+	{"items", "source", "TEXT NOT NULL DEFAULT 'command'"},
+	{"items", "origin", "TEXT NOT NULL DEFAULT ''"},
+	{"items", "origin_date", "DATETIME"},
+	{"items", "reply_to", "TEXT NOT NULL DEFAULT ''"},
+	{"items", "kind", "TEXT NOT NULL DEFAULT 'text'"},
+	{"items", "file_id", "TEXT NOT NULL DEFAULT ''"},
+	{"items", "file_unique_id", "TEXT NOT NULL DEFAULT ''"},
+	{"items", "file_name", "TEXT NOT NULL DEFAULT ''"},
+	{"items", "mime_type", "TEXT NOT NULL DEFAULT ''"},
+	{"items", "file_size", "INTEGER NOT NULL DEFAULT 0"},
+	{"items", "duration", "INTEGER NOT NULL DEFAULT 0"},
+	{"deleted", "kind", "TEXT NOT NULL DEFAULT 'text'"},
+	{"deleted", "file_id", "TEXT NOT NULL DEFAULT ''"},
+}
+
+// migrateDB creates the tables, adds missing columns and indexes. schema
+// is the dialect's CREATE TABLE script.
+func migrateDB(schema string) error {
 	if _, err := db.Exec(schema); err != nil {
 		return fmt.Errorf("failed to create schema: %v", err)
 	}
 
-	return nil
-}
+	for _, c := range addedColumns {
+		if err := ensureColumn(c.table, c.column, c.def); err != nil {
+			return fmt.Errorf("failed to migrate %s.%s: %v", c.table, c.column, err)
+		}
+	}
 
-func loadEnv(filename string) error {
-	// Skip loading .env file in production (Render)
-	if os.Getenv("RENDER") != "" {
-		log.Printf("Running in Render, skipping .env file")
-		return nil
+	indexes := `
+	CREATE INDEX IF NOT EXISTS idx_items_message ON items (chat_id, message_id);
+	CREATE INDEX IF NOT EXISTS idx_items_kind ON items (kind);`
+	if _, err := db.Exec(indexes); err != nil {
+		return fmt.Errorf("failed to create schema: %v", err)
 	}
 
-	absPath, err := filepath.Abs(filename)
+	sqlStore, err := newSQLStore(db)
 	if err != nil {
-		return fmt.Errorf("failed to get absolute path: %v", err)
+		return fmt.Errorf("failed to prepare queries: %v", err)
 	}
+	store = sqlStore
+	return nil
+}
 
-	file, err := os.Open(absPath)
+// ensureColumn adds a column to an existing table if it is missing
+func ensureColumn(table, column, def string) error {
+	if dbDialect == dialectPostgres {
+		_, err := db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s %s", table, column, postgresType(def)))
+		return err
+	}
+
+	rows, err := db.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
 	if err != nil {
-		return fmt.Errorf("failed to open %s: %v", absPath, err)
+		return err
 	}
-	defer file.Close()
-
-	log.Printf("Loading environment from: %s", absPath)
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := scanner.Text()
-		if equal := strings.Index(line, "="); equal >= 0 {
-			if key := strings.TrimSpace(line[:equal]); len(key) > 0 {
-				value := ""
-				if len(line) > equal {
-					value = strings.TrimSpace(line[equal+1:])
-				}
-				os.Setenv(key, value)
-				// Log key but mask the value for sensitive data
-				if strings.Contains(strings.ToLower(key), "key") || strings.Contains(strings.ToLower(key), "token") {
-					log.Printf("Set %s=***masked***", key)
-				} else {
-					log.Printf("Set %s=%s", key, value)
-				}
-			}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid       int
+			name      string
+			colType   string
+			notNull   int
+			dfltValue sql.NullString
+			pk        int
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dfltValue, &pk); err != nil {
+			return err
 		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
 	}
-	return scanner.Err()
+	rows.Close()
+
+	_, err = db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, def))
+	return err
 }
 
 func main() {
+	configFile := flag.String("config", os.Getenv("CONFIG_FILE"), "optional YAML or TOML config file")
+	envFile := flag.String("env-file", ".env", "optional .env file")
+	printConfig := flag.Bool("print-config", false, "print the effective configuration and exit")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), cliUsage+"\nFlags:\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	// Administration commands work on the database without starting the bot
+	if flag.NArg() > 0 {
+		cfg, err := LoadConfig(configSources{ConfigFile: *configFile, EnvFile: *envFile, Offline: true})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid configuration:\n%v\n", err)
+			os.Exit(1)
+		}
+		log.SetOutput(io.Discard)
+		os.Exit(runCLI(cfg, flag.Args(), os.Stdout, os.Stderr))
+	}
+
+	// Resolve configuration (defaults < config file < .env < environment)
+	cfg, err := LoadConfig(configSources{ConfigFile: *configFile, EnvFile: *envFile})
+	if *printConfig {
+		if cfg != nil {
+			cfg.Print(os.Stdout)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid configuration:\n%v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+	log.Printf("Using token: %s", maskSecret(cfg.BotToken))
+	if cfg.OpenRouterKey != "" {
+		log.Printf("Using OpenRouter key: %s", maskSecret(cfg.OpenRouterKey))
+	}
+
+	features := newFeatures(cfg)
+	log.Print(features)
+
 	// Initialize database
-	if err := initDB(); err != nil {
+	if err := openDatabase(cfg); err != nil {
 		log.Fatalf("Failed to initialize database: %v", err)
 	}
 	defer db.Close()
 
-	// Start health check server
-	startHealthCheck()
-
-	// Load .env file (only in development)
-	if err := loadEnv(".env"); err != nil {
-		log.Printf("Warning: Error loading .env file: %v", err)
+	access, err := newAccessControl(db, cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize access control: %v", err)
 	}
-
-	// Get environment variables (works both in development and production)
-	token := os.Getenv("BOT_TOKEN")
-	if token == "" {
-		log.Fatal("BOT_TOKEN environment variable is not set")
+	log.Print(access)
+
+	// Rules were checked by cfg.Validate
+	chatRule, _ := parseRateRule(cfg.RateLimit)
+	commandRules, _ := parseCommandRateRules(cfg.CommandRateLimits)
+	limiter := newRateLimiter(chatRule, commandRules)
+	budget := newLLMBudget(db, cfg)
+	prefs := newUserPrefs(db)
+
+	backups := newBackupScheduler(db, cfg)
+	log.Print(backups)
+	backups.start(context.Background())
+
+	// Start database maintenance and the health check server
+	maint := newDBMaintenance(db)
+	maint.start(context.Background())
+	startHealthCheck(cfg.Port, maint)
+
+	// Initialize time calculator (without a key it only uses the local tools)
+	if features.LLM {
+		timeCalculator = timecalc.NewTimeCalculator(cfg.OpenRouterKey)
+	} else {
+		timeCalculator = timecalc.NewTimeCalculator("")
 	}
-	log.Printf("Using token: %s...[last 5 chars hidden]", token[:len(token)-5])
 
-	openRouterKey := os.Getenv("OPENROUTER_API_KEY")
-	if openRouterKey == "" {
-		log.Fatal("OPENROUTER_API_KEY environment variable is not set")
+	// /ask uses the same OpenRouter client with tools over the notes
+	if features.Ask {
+		askAssistant = timecalc.NewTimeCalculator(cfg.OpenRouterKey)
 	}
-	log.Printf("Using OpenRouter key: %s...[last 10 chars hidden]", openRouterKey[:len(openRouterKey)-10])
 
-	// Initialize time calculator
-	timeCalculator = timecalc.NewTimeCalculator(openRouterKey)
+	if features.Transcribe {
+		transcriber = speech.NewWhisperClient(cfg.STTAPIURL, cfg.STTAPIKey, cfg.STTModel, cfg.STTLanguage)
+	}
 
 	// Simple version to test that the bot works
-	bot, err := tgbot.NewBotAPI(token)
+	bot, err := tgbot.NewBotAPI(cfg.BotToken)
 	if err != nil {
 		log.Fatalf("Failed to create bot: %v", err)
 	}
@@ -180,140 +344,192 @@ func main() {
 		log.Printf("Started listening for updates...")
 
 		for update := range updates {
-			if update.Message == nil {
+			// Editing the original /add message updates the stored note
+			if m := update.EditedMessage; m != nil && m.From != nil {
+				if access.isAllowed(m.From.ID, m.Chat.ID) {
+					if reply := handleEditedMessage(m); reply != "" {
+						edited := tgbot.NewMessage(m.Chat.ID, reply)
+						edited.ReplyToMessageID = m.MessageID
+						if _, err := bot.Send(edited); err != nil {
+							log.Printf("Error sending message: %v", err)
+						}
+					}
+				}
 				continue
 			}
 
-			log.Printf("Received message: [%s] %s", update.Message.From.UserName, update.Message.Text)
+			// Inline buttons on inbox confirmations, import previews and
+			// time suggestions
+			if q := update.CallbackQuery; q != nil {
+				if q.Message != nil && access.isAllowed(q.From.ID, q.Message.Chat.ID) {
+					if strings.HasPrefix(q.Data, "import:") {
+						handleImportCallback(bot, q, access)
+					} else if strings.HasPrefix(q.Data, "time:") {
+						if features.Time {
+							handleTimeCallback(bot, q, limiter, budget, prefs)
+						}
+					} else {
+						handleInboxCallback(bot, q)
+					}
+				}
+				continue
+			}
 
-			if !update.Message.IsCommand() {
+			if update.Message == nil {
 				continue
 			}
 
-			msg := tgbot.NewMessage(update.Message.Chat.ID, "")
+			// Channel posts have no sender and are never allowed
+			if update.Message.From == nil {
+				continue
+			}
 
-			switch update.Message.Command() {
-			case "add":
-				text := update.Message.CommandArguments()
-				if text == "" {
-					msg.Text = "Usage: /add something"
-				} else {
-					// Store in database
-					if _, err := db.Exec("INSERT INTO items (text) VALUES (?)", text); err != nil {
-						log.Printf("Error storing item: %v", err)
-						msg.Text = "Failed to store item."
-					} else {
-						msg.Text = fmt.Sprintf("Added: %s ✅", text)
-					}
-				}
-			case "pull":
-				// Get random item from database
-				var text string
-				err := db.QueryRow("SELECT text FROM items ORDER BY RANDOM() LIMIT 1").Scan(&text)
-				if err == sql.ErrNoRows {
-					msg.Text = "No items available."
-				} else if err != nil {
-					log.Printf("Error pulling item: %v", err)
-					msg.Text = "Failed to pull item."
-				} else {
-					lpMutex.Lock()
-					lastPulled[update.Message.Chat.ID] = text
-					lpMutex.Unlock()
-					msg.Text = fmt.Sprintf("🎲 %s", text)
-				}
-			case "delete":
-				// Delete last pulled item
-				lpMutex.RLock()
-				text, ok := lastPulled[update.Message.Chat.ID]
-				lpMutex.RUnlock()
+			log.Printf("Received message: [%s] %s", update.Message.From.UserName, update.Message.Text)
 
-				if !ok {
-					msg.Text = "Pull an item first using /pull"
-				} else {
-					tx, err := db.Begin()
-					if err != nil {
-						log.Printf("Error starting transaction: %v", err)
-						msg.Text = "Failed to delete item."
-						break
+			if !access.isAllowed(update.Message.From.ID, update.Message.Chat.ID) {
+				access.recordDenied(update.Message)
+				if update.Message.IsCommand() {
+					reply := tgbot.NewMessage(update.Message.Chat.ID, rejectionText(update.Message.From.ID))
+					if _, err := bot.Send(reply); err != nil {
+						log.Printf("Error sending message: %v", err)
 					}
+				}
+				continue
+			}
+			access.touch(update.Message.From)
 
-					// Store the item for undo functionality
-					ldMutex.Lock()
-					lastDeleted[update.Message.Chat.ID] = struct {
-						Text      string
-						DeletedAt time.Time
-					}{
-						Text:      text,
-						DeletedAt: time.Now(),
-					}
-					ldMutex.Unlock()
-
-					// Move item to deleted table
-					if _, err := tx.Exec("INSERT INTO deleted (text) VALUES (?)", text); err != nil {
-						tx.Rollback()
-						log.Printf("Error moving item to deleted: %v", err)
-						msg.Text = "Failed to delete item."
-						break
-					}
+			if !update.Message.IsCommand() {
+				importing := wantsImport(update.Message)
+				if !importing && !savesMessage(features, update.Message) {
+					continue
+				}
 
-					// Delete from items table
-					if _, err := tx.Exec("DELETE FROM items WHERE text = ?", text); err != nil {
-						tx.Rollback()
-						log.Printf("Error deleting item: %v", err)
-						msg.Text = "Failed to delete item."
-						break
+				// Saved messages count against the chat's rate limit
+				if ok, wait := limiter.Allow(update.Message.Chat.ID, ""); !ok {
+					reply := tgbot.NewMessage(update.Message.Chat.ID, fmt.Sprintf("⏳ Slow down! Try again in %s.", formatWait(wait)))
+					if _, err := bot.Send(reply); err != nil {
+						log.Printf("Error sending message: %v", err)
 					}
+					continue
+				}
 
-					if err := tx.Commit(); err != nil {
-						log.Printf("Error committing transaction: %v", err)
-						msg.Text = "Failed to delete item."
-						break
+				if importing {
+					if text := handleImportDocument(bot, update.Message); text != "" {
+						if _, err := bot.Send(tgbot.NewMessage(update.Message.Chat.ID, text)); err != nil {
+							log.Printf("Error sending message: %v", err)
+						}
 					}
+					continue
+				}
+				handleInboxMessage(bot, update.Message)
+				continue
+			}
 
-					lpMutex.Lock()
-					delete(lastPulled, update.Message.Chat.ID)
-					lpMutex.Unlock()
+			msg := tgbot.NewMessage(update.Message.Chat.ID, "")
 
-					msg.Text = fmt.Sprintf("Deleted: %s 🗑️", text)
+			if ok, wait := limiter.Allow(update.Message.Chat.ID, update.Message.Command()); !ok {
+				msg.Text = fmt.Sprintf("⏳ Slow down! Try again in %s.", formatWait(wait))
+				if _, err := bot.Send(msg); err != nil {
+					log.Printf("Error sending message: %v", err)
 				}
-			case "list":
-				// List all items
-				rows, err := db.Query("SELECT text FROM items ORDER BY created_at DESC")
-				if err != nil {
-					log.Printf("Error listing items: %v", err)
-					msg.Text = "Failed to list items."
+				continue
+			}
+
+			switch update.Message.Command() {
+			case "allow", "revoke", "users":
+				if !access.isAdmin(update.Message.From.ID) {
+					access.recordDenied(update.Message)
+					msg.Text = "⛔ Only admins can manage access."
 					break
 				}
-				defer rows.Close()
-
-				var items []string
-				for rows.Next() {
-					var text string
-					if err := rows.Scan(&text); err != nil {
-						log.Printf("Error scanning row: %v", err)
-						continue
-					}
-					items = append(items, "• "+text)
+				switch update.Message.Command() {
+				case "allow":
+					msg.Text = access.handleAllow(update.Message)
+				case "revoke":
+					msg.Text = access.handleRevoke(update.Message)
+				case "users":
+					msg.Text = access.handleUsers()
 				}
-
-				if len(items) == 0 {
-					msg.Text = "No items available."
+			case "backups":
+				if !access.isAdmin(update.Message.From.ID) {
+					access.recordDenied(update.Message)
+					msg.Text = "⛔ Only admins can manage backups."
 				} else {
-					msg.Text = "Your items:\n" + strings.Join(items, "\n")
+					msg.Text = backups.handleBackups(update.Message.CommandArguments())
 				}
+			case "add":
+				msg.Text = handleAdd(update.Message)
+			case "pull":
+				if msg.Text = handlePull(bot, update.Message); msg.Text == "" {
+					continue
+				}
+			case "delete":
+				msg.Text = handleDelete(update.Message)
+			case "edit":
+				msg.Text = handleEdit(update.Message)
+			case "history":
+				msg.Text = handleHistory(update.Message)
+			case "tag":
+				msg.Text = handleTag(update.Message)
+			case "list":
+				msg.Text = handleList(update.Message, prefs.location(update.Message.From.ID))
+			case "export":
+				if msg.Text = handleExport(bot, update.Message); msg.Text == "" {
+					continue
+				}
+			case "import":
+				msg.Text = handleImportCommand(update.Message)
 			case "time":
 				query := update.Message.CommandArguments()
-				if query == "" {
+				if _, ok := prefs.home(update.Message.From.ID); ok && query == "" {
+					query = "What time is it?"
+				}
+				if !features.Time {
+					msg.Text = "Time calculations are disabled on this bot."
+				} else if query == "" && !features.LLM {
+					msg.Text = "Usage: /time Tokyo\n\n" + timecalc.OfflineUsage
+				} else if query == "" {
 					msg.Text = "Usage: /time what's the time in New York?"
 				} else {
-					response, err := timeCalculator.ProcessQuery(query)
-					if err != nil {
-						log.Printf("Error processing time query: %v", err)
-						msg.Text = fmt.Sprintf("Error: %v", err)
-					} else {
-						msg.Text = response
+					text, markup := runTimeQuery(budget, prefs, update.Message.Chat.ID, update.Message.From.ID, query)
+					msg.Text = text
+					if markup != nil {
+						msg.ReplyMarkup = markup
 					}
 				}
+			case "settz":
+				if !features.Time {
+					msg.Text = "Time calculations are disabled on this bot."
+				} else {
+					msg.Text = prefs.handleSetTZ(update.Message)
+				}
+			case "duration", "leaveby":
+				if !features.Time {
+					msg.Text = "Time calculations are disabled on this bot."
+				} else if update.Message.Command() == "duration" {
+					msg.Text = handleDuration(update.Message)
+				} else {
+					msg.Text = handleLeaveBy(update.Message)
+				}
+			case "worldclock", "meet":
+				if !features.Time {
+					msg.Text = "Time calculations are disabled on this bot."
+					break
+				}
+				if update.Message.Command() == "worldclock" {
+					msg.Text = handleWorldClock(update.Message)
+				} else {
+					msg.Text = handleMeet(update.Message)
+				}
+				msg.ParseMode = tgbot.ModeHTML
+			case "ask":
+				if !features.Ask {
+					msg.Text = "The assistant needs an OpenRouter API key."
+				} else {
+					msg.Text = handleAsk(update.Message, budget, prefs)
+				}
+			case "usage":
+				msg.Text = budget.handleUsage(update.Message.From.ID)
 			default:
 				msg.Text = "I don't know that command"
 			}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"unicode"
+
+	tgbot "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Item kinds
+const (
+	kindText     = "text"
+	kindPhoto    = "photo"
+	kindDocument = "document"
+	kindVoice    = "voice"
+	kindAudio    = "audio"
+	kindVideo    = "video"
+)
+
+// itemKinds lists the kinds accepted by /list <kind>
+var itemKinds = []string{kindText, kindPhoto, kindDocument, kindVoice, kindAudio, kindVideo}
+
+// maxDownloadSize is the largest file the Bot API lets bots download
+const maxDownloadSize = 20 << 20
+
+// mediaInfo holds the Telegram file reference and metadata of a media item
+type mediaInfo struct {
+	Kind         string
+	FileID       string
+	FileUniqueID string
+	FileName     string
+	MimeType     string
+	FileSize     int
+	Duration     int // seconds, for voice, audio and video
+}
+
+// mediaFromMessage extracts the attached file of a message, if any
+func mediaFromMessage(m *tgbot.Message) (*mediaInfo, bool) {
+	switch {
+	case len(m.Photo) > 0:
+		// Telegram sends several sizes; the last one is the largest
+		p := m.Photo[len(m.Photo)-1]
+		return &mediaInfo{Kind: kindPhoto, FileID: p.FileID, FileUniqueID: p.FileUniqueID,
+			MimeType: "image/jpeg", FileSize: p.FileSize}, true
+	case m.Document != nil:
+		d := m.Document
+		return &mediaInfo{Kind: kindDocument, FileID: d.FileID, FileUniqueID: d.FileUniqueID,
+			FileName: d.FileName, MimeType: d.MimeType, FileSize: d.FileSize}, true
+	case m.Voice != nil:
+		v := m.Voice
+		return &mediaInfo{Kind: kindVoice, FileID: v.FileID, FileUniqueID: v.FileUniqueID,
+			MimeType: v.MimeType, FileSize: v.FileSize, Duration: v.Duration}, true
+	case m.Audio != nil:
+		a := m.Audio
+		return &mediaInfo{Kind: kindAudio, FileID: a.FileID, FileUniqueID: a.FileUniqueID,
+			FileName: a.FileName, MimeType: a.MimeType, FileSize: a.FileSize, Duration: a.Duration}, true
+	case m.Video != nil:
+		v := m.Video
+		return &mediaInfo{Kind: kindVideo, FileID: v.FileID, FileUniqueID: v.FileUniqueID,
+			FileName: v.FileName, MimeType: v.MimeType, FileSize: v.FileSize, Duration: v.Duration}, true
+	}
+	return nil, false
+}
+
+// isAddCaption reports whether a media caption starts with /add, which
+// saves the media even when inbox mode is off
+func isAddCaption(caption string) bool {
+	fields := strings.Fields(caption)
+	if len(fields) == 0 {
+		return false
+	}
+	first, _, _ := strings.Cut(fields[0], "@")
+	return first == "/add"
+}
+
+// stripAddCaption removes a leading /add command from a caption
+func stripAddCaption(caption string) string {
+	if !isAddCaption(caption) {
+		return strings.TrimSpace(caption)
+	}
+	caption = strings.TrimSpace(caption)
+	rest := strings.TrimLeftFunc(caption, func(r rune) bool { return !unicode.IsSpace(r) })
+	return strings.TrimSpace(rest)
+}
+
+// storeMediaItem saves a media message as an item and returns its ID
+func storeMediaItem(c capturedItem, media *mediaInfo, chatID int64, messageID int) (int64, error) {
+	return store.Add(Item{
+		Text:         c.Text,
+		Kind:         media.Kind,
+		FileID:       media.FileID,
+		ChatID:       chatID,
+		MessageID:    messageID,
+		Source:       c.Source,
+		Origin:       c.Origin,
+		OriginDate:   c.OriginDate,
+		ReplyTo:      c.ReplyTo,
+		FileUniqueID: media.FileUniqueID,
+		FileName:     media.FileName,
+		MimeType:     media.MimeType,
+		FileSize:     media.FileSize,
+		Duration:     media.Duration,
+	})
+}
+
+// kindIcon is shown in front of media items in listings
+func kindIcon(kind string) string {
+	switch kind {
+	case kindPhoto:
+		return "🖼 "
+	case kindDocument:
+		return "📄 "
+	case kindVoice:
+		return "🎤 "
+	case kindAudio:
+		return "🎵 "
+	case kindVideo:
+		return "🎬 "
+	}
+	return ""
+}
+
+// mediaMessage builds the message that re-sends a stored file
+func mediaMessage(chatID int64, kind, fileID, caption string) (tgbot.Chattable, error) {
+	file := tgbot.FileID(fileID)
+	switch kind {
+	case kindPhoto:
+		c := tgbot.NewPhoto(chatID, file)
+		c.Caption = caption
+		return c, nil
+	case kindDocument:
+		c := tgbot.NewDocument(chatID, file)
+		c.Caption = caption
+		return c, nil
+	case kindVoice:
+		c := tgbot.NewVoice(chatID, file)
+		c.Caption = caption
+		return c, nil
+	case kindAudio:
+		c := tgbot.NewAudio(chatID, file)
+		c.Caption = caption
+		return c, nil
+	case kindVideo:
+		c := tgbot.NewVideo(chatID, file)
+		c.Caption = caption
+		return c, nil
+	}
+	return nil, fmt.Errorf("unknown media kind %q", kind)
+}
+
+// downloadTelegramFile fetches a file through the Bot API
+func downloadTelegramFile(bot *tgbot.BotAPI, fileID string) ([]byte, error) {
+	fileURL, err := bot.GetFileDirectURL(fileID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file URL: %v", err)
+	}
+
+	resp, err := http.Get(fileURL)
+	if err != nil {
+		// The URL contains the bot token, so never include it in errors
+		if uerr, ok := err.(*url.Error); ok {
+			err = uerr.Err
+		}
+		return nil, fmt.Errorf("failed to download file: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download file: %s", resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxDownloadSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %v", err)
+	}
+	if len(data) > maxDownloadSize {
+		return nil, fmt.Errorf("file is larger than %d MB", maxDownloadSize>>20)
+	}
+	return data, nil
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbot "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	timecalc "github.com/jgabriele321/onmymind/time"
+)
+
+// Meeting planner limits and defaults
+const (
+	defaultMeetDays      = 5
+	maxMeetDays          = 14
+	defaultMeetLength    = 30 * time.Minute
+	maxMeetParticipants  = 8
+	maxMeetWindowsListed = 15
+)
+
+const meetUsage = `Usage: /meet New York 9-17; London; Tokyo 10-18
+Separate places with ";" (or "," if no place name has a comma). Hours
+default to 9-17 on weekdays. Add "7 days" to look further ahead (up to
+14) and "1h" or "45m" for the shortest useful window (30m by default).
+Without places, the /worldclock places are used.`
+
+var (
+	// "7 days", "1 day"
+	meetDaysPattern = regexp.MustCompile(`(?i)^(\d+)\s*days?$`)
+	// "45m", "1h", "90 min"
+	meetLengthPattern = regexp.MustCompile(`(?i)^(\d+)\s*(m|min|h|hours?)$`)
+	// "London 8:30-16:30" → place and working hours
+	meetHoursPattern = regexp.MustCompile(`^(.+?)\s+(\d{1,2}(?::\d{2})?\s*-\s*\d{1,2}(?::\d{2})?)$`)
+)
+
+// meetRequest is a parsed /meet command
+type meetRequest struct {
+	Participants []timecalc.Participant
+	Days         int
+	MinLength    time.Duration
+}
+
+// parseMeetRequest reads the places, hours and options of /meet
+func parseMeetRequest(args string) (meetRequest, error) {
+	req := meetRequest{Days: defaultMeetDays, MinLength: defaultMeetLength}
+	sep := ","
+	if strings.Contains(args, ";") {
+		sep = ";"
+	}
+
+	for _, part := range strings.Split(args, sep) {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if m := meetDaysPattern.FindStringSubmatch(part); m != nil {
+			req.Days, _ = strconv.Atoi(m[1])
+			if req.Days < 1 || req.Days > maxMeetDays {
+				return req, fmt.Errorf("days must be between 1 and %d", maxMeetDays)
+			}
+			continue
+		}
+		if m := meetLengthPattern.FindStringSubmatch(part); m != nil {
+			n, _ := strconv.Atoi(m[1])
+			req.MinLength = time.Duration(n) * time.Minute
+			if strings.HasPrefix(strings.ToLower(m[2]), "h") {
+				req.MinLength = time.Duration(n) * time.Hour
+			}
+			continue
+		}
+
+		name, hours := part, timecalc.DefaultWorkingHours
+		if m := meetHoursPattern.FindStringSubmatch(part); m != nil {
+			wh, err := timecalc.ParseWorkingHours(m[2])
+			if err != nil {
+				return req, err
+			}
+			name, hours = m[1], wh
+		}
+		place, err := timecalc.ResolveLocation(name)
+		if err != nil {
+			return req, err
+		}
+		loc, err := place.Location()
+		if err != nil {
+			return req, err
+		}
+		req.Participants = append(req.Participants, timecalc.Participant{
+			Name:  worldClockLabel(place),
+			Loc:   loc,
+			Hours: hours,
+		})
+	}
+	if len(req.Participants) > maxMeetParticipants {
+		return req, fmt.Errorf("at most %d places can be compared", maxMeetParticipants)
+	}
+	return req, nil
+}
+
+// handleMeet handles /meet and returns an HTML reply
+func handleMeet(m *tgbot.Message) string {
+	return meetReply(m.Chat.ID, m.CommandArguments(), time.Now())
+}
+
+func meetReply(chatID int64, args string, now time.Time) string {
+	req, err := parseMeetRequest(args)
+	if err != nil {
+		return "❌ " + html.EscapeString(err.Error())
+	}
+
+	// Fall back to the saved world clock
+	if len(req.Participants) == 0 {
+		clocks, err := worldClocks(chatID)
+		if err != nil {
+			return "Error loading world clock: " + html.EscapeString(err.Error())
+		}
+		for _, c := range clocks {
+			loc, err := time.LoadLocation(c.Zone)
+			if err != nil {
+				continue
+			}
+			req.Participants = append(req.Participants, timecalc.Participant{
+				Name: c.Label, Loc: loc, Hours: timecalc.DefaultWorkingHours,
+			})
+		}
+		if len(req.Participants) > maxMeetParticipants {
+			req.Participants = req.Participants[:maxMeetParticipants]
+		}
+	}
+	if len(req.Participants) < 2 {
+		return html.EscapeString(meetUsage)
+	}
+
+	windows := timecalc.PlanMeeting(req.Participants, now, req.Days, req.MinLength)
+	if len(windows) == 0 {
+		return fmt.Sprintf("No time in the next %d days suits everyone's working hours. Try wider hours or more days.", req.Days)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "📅 Times that suit everyone in the next %d days", req.Days)
+	for _, p := range req.Participants {
+		if p.Hours != timecalc.DefaultWorkingHours {
+			fmt.Fprintf(&b, "\n%s works %s", html.EscapeString(p.Name), p.Hours)
+		}
+	}
+	listed := windows
+	if len(listed) > maxMeetWindowsListed {
+		listed = listed[:maxMeetWindowsListed]
+	}
+	b.WriteString("\n<pre>")
+	b.WriteString(html.EscapeString(timecalc.FormatMeetingWindows(req.Participants, listed)))
+	b.WriteString("</pre>")
+	if len(windows) > len(listed) {
+		fmt.Fprintf(&b, "\n…and %d more", len(windows)-len(listed))
+	}
+	return b.String()
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	tgbot "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	timecalc "github.com/jgabriele321/onmymind/time"
+)
+
+const setTZUsage = `Usage:
+/settz New York — set your home city, country or time zone
+/settz — show your home time zone
+/settz clear — forget it
+
+Your home time zone is used when a /time query doesn't name a place and
+for the dates in /list.`
+
+// homeZone is a user's home time zone set with /settz
+type homeZone struct {
+	Label string // place the user named, e.g. "Portland, Maine"
+	Zone  string // IANA time zone
+}
+
+// userPrefs stores per-user settings in the user_settings table. A nil
+// *userPrefs has no settings, which keeps handlers usable without a
+// database.
+type userPrefs struct {
+	db *sql.DB
+}
+
+func newUserPrefs(db *sql.DB) *userPrefs {
+	return &userPrefs{db: db}
+}
+
+// home returns the user's home time zone, if set
+func (p *userPrefs) home(userID int64) (homeZone, bool) {
+	if p == nil {
+		return homeZone{}, false
+	}
+	var h homeZone
+	err := p.db.QueryRow("SELECT label, zone FROM user_settings WHERE user_id = ?", userID).Scan(&h.Label, &h.Zone)
+	if err == sql.ErrNoRows {
+		return homeZone{}, false
+	} else if err != nil {
+		log.Printf("Error loading settings of user %d: %v", userID, err)
+		return homeZone{}, false
+	}
+	return h, true
+}
+
+// location returns the user's home time zone, or UTC if none is set
+func (p *userPrefs) location(userID int64) *time.Location {
+	h, ok := p.home(userID)
+	if !ok {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(h.Zone)
+	if err != nil {
+		log.Printf("Error loading time zone of user %d: %v", userID, err)
+		return time.UTC
+	}
+	return loc
+}
+
+// queryContext describes the user to the time calculator
+func (p *userPrefs) queryContext(userID int64) timecalc.QueryContext {
+	h, ok := p.home(userID)
+	if !ok {
+		return timecalc.QueryContext{}
+	}
+	return timecalc.QueryContext{HomeName: h.Label, HomeZone: h.Zone}
+}
+
+func (p *userPrefs) setHome(userID int64, h homeZone) error {
+	_, err := p.db.Exec(`
+		INSERT INTO user_settings (user_id, label, zone, updated_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT(user_id) DO UPDATE SET label = excluded.label, zone = excluded.zone, updated_at = excluded.updated_at`,
+		userID, h.Label, h.Zone, time.Now().UTC())
+	return err
+}
+
+func (p *userPrefs) clearHome(userID int64) (bool, error) {
+	res, err := p.db.Exec("DELETE FROM user_settings WHERE user_id = ?", userID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// handleSetTZ implements /settz [place|clear]
+func (p *userPrefs) handleSetTZ(m *tgbot.Message) string {
+	userID := m.From.ID
+	arg := strings.TrimSpace(m.CommandArguments())
+
+	switch strings.ToLower(arg) {
+	case "":
+		h, ok := p.home(userID)
+		if !ok {
+			return "You haven't set a home time zone.\n\n" + setTZUsage
+		}
+		return fmt.Sprintf("🏠 Your home time zone is %s (%s), where it is now %s.",
+			h.Label, h.Zone, describeLocalNow(p.location(userID)))
+	case "clear", "reset", "none":
+		removed, err := p.clearHome(userID)
+		if err != nil {
+			log.Printf("Error clearing home time zone: %v", err)
+			return "Failed to clear your home time zone."
+		}
+		if !removed {
+			return "You haven't set a home time zone."
+		}
+		return "Home time zone cleared. Times are shown in UTC."
+	}
+
+	place, err := timecalc.ResolveLocation(arg)
+	if err != nil {
+		return "❌ " + err.Error()
+	}
+	h := homeZone{Label: worldClockLabel(place), Zone: place.Zone}
+	if err := p.setHome(userID, h); err != nil {
+		log.Printf("Error saving home time zone: %v", err)
+		return "Failed to save your home time zone."
+	}
+	loc, err := place.Location()
+	if err != nil {
+		return "❌ " + err.Error()
+	}
+	return fmt.Sprintf("🏠 Home time zone set to %s (%s), where it is now %s.", h.Label, h.Zone, describeLocalNow(loc))
+}
+
+// describeLocalNow shows the current time in loc, e.g. "Mon 10:30 EDT (UTC-04:00)"
+func describeLocalNow(loc *time.Location) string {
+	now := time.Now().In(loc)
+	return fmt.Sprintf("%s (%s)", now.Format("Mon 15:04 MST"), timecalc.FormatOffset(timecalc.ZoneOffset(now)))
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSetTZ(t *testing.T) {
+	if err := initDB(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	prefs := newUserPrefs(db)
+	const user = 9
+	steps := []struct {
+		text string
+		want string
+	}{
+		{"/settz", "You haven't set a home time zone."},
+		{"/settz Portland", "❌"},
+		{"/settz Kolkata", "🏠 Home time zone set to Kolkata (Asia/Kolkata)"},
+		{"/settz Portland, Maine", "🏠 Home time zone set to Portland, Maine (America/New_York)"},
+		{"/settz", "🏠 Your home time zone is Portland, Maine (America/New_York)"},
+		{"/settz clear", "Home time zone cleared."},
+		{"/settz clear", "You haven't set a home time zone."},
+	}
+	for _, step := range steps {
+		if got := prefs.handleSetTZ(commandMessage(user, step.text)); !strings.HasPrefix(got, step.want) {
+			t.Errorf("%s = %q, want %q", step.text, got, step.want)
+		}
+	}
+
+	if loc := prefs.location(user); loc != time.UTC {
+		t.Errorf("location() without a home zone = %v, want UTC", loc)
+	}
+	prefs.handleSetTZ(commandMessage(user, "/settz Tokyo"))
+	if loc := prefs.location(user); loc.String() != "Asia/Tokyo" {
+		t.Errorf("location() = %v, want Asia/Tokyo", loc)
+	}
+	if qc := prefs.queryContext(user); qc.HomeZone != "Asia/Tokyo" || qc.HomeName != "Tokyo" {
+		t.Errorf("queryContext() = %+v", qc)
+	}
+	if loc := prefs.location(user + 1); loc != time.UTC {
+		t.Errorf("location() of another user = %v, want UTC", loc)
+	}
+
+	var none *userPrefs
+	if _, ok := none.home(user); ok {
+		t.Error("nil userPrefs has a home zone")
+	}
+}
+
+func TestListInHomeZone(t *testing.T) {
+	old := store
+	store = newMemoryStore()
+	defer func() { store = old }()
+
+	const chat = 42
+	handleAdd(commandMessage(chat, "/add water the plants"))
+	items, err := store.List(ListOptions{})
+	if err != nil || len(items) != 1 {
+		t.Fatalf("List() = %v, %v", items, err)
+	}
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := handleList(commandMessage(chat, "/list"), tokyo)
+	want := "Your items (times in Asia/Tokyo):\n• #1 water the plants · " + items[0].CreatedAt.In(tokyo).Format("Jan 2 15:04")
+	if got != want {
+		t.Errorf("/list = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rateRule allows Burst events per Per, refilled continuously
+type rateRule struct {
+	Burst int
+	Per   time.Duration
+}
+
+// parseRateRule parses "N/unit" where unit is s, m, h or d. An empty
+// string or "0" disables the limit.
+func parseRateRule(s string) (*rateRule, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || s == "0" {
+		return nil, nil
+	}
+	count, unit, ok := strings.Cut(s, "/")
+	if !ok {
+		return nil, fmt.Errorf("%q must look like 10/m", s)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(count))
+	if err != nil || n <= 0 {
+		return nil, fmt.Errorf("%q: count must be a positive number", s)
+	}
+
+	var per time.Duration
+	switch strings.TrimSpace(unit) {
+	case "s":
+		per = time.Second
+	case "m":
+		per = time.Minute
+	case "h":
+		per = time.Hour
+	case "d":
+		per = 24 * time.Hour
+	default:
+		return nil, fmt.Errorf("%q: unit must be s, m, h or d", s)
+	}
+	return &rateRule{Burst: n, Per: per}, nil
+}
+
+// parseCommandRateRules parses "cmd=N/unit,cmd2=N/unit"
+func parseCommandRateRules(s string) (map[string]*rateRule, error) {
+	rules := make(map[string]*rateRule)
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		cmd, spec, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("%q must look like time=10/h", part)
+		}
+		rule, err := parseRateRule(spec)
+		if err != nil {
+			return nil, err
+		}
+		if rule != nil {
+			rules[strings.TrimPrefix(strings.TrimSpace(cmd), "/")] = rule
+		}
+	}
+	return rules, nil
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// refill adds the tokens earned since the last call
+func (b *tokenBucket) refill(rule *rateRule, now time.Time) {
+	elapsed := now.Sub(b.last)
+	b.last = now
+	b.tokens += elapsed.Seconds() / rule.Per.Seconds() * float64(rule.Burst)
+	if b.tokens > float64(rule.Burst) {
+		b.tokens = float64(rule.Burst)
+	}
+}
+
+// wait returns how long until the bucket holds one token
+func (b *tokenBucket) wait(rule *rateRule) time.Duration {
+	missing := 1 - b.tokens
+	return time.Duration(missing / float64(rule.Burst) * float64(rule.Per))
+}
+
+type bucketKey struct {
+	chatID  int64
+	command string // "" for the per-chat bucket
+}
+
+// bucketPruneInterval is how often the rate limiter forgets full buckets
+const bucketPruneInterval = 10 * time.Minute
+
+// rateLimiter keeps token buckets per chat and per chat+command
+type rateLimiter struct {
+	mu        sync.Mutex
+	chat      *rateRule
+	commands  map[string]*rateRule
+	buckets   map[bucketKey]*tokenBucket
+	lastPrune time.Time
+	now       func() time.Time
+}
+
+func newRateLimiter(chat *rateRule, commands map[string]*rateRule) *rateLimiter {
+	return &rateLimiter{
+		chat:     chat,
+		commands: commands,
+		buckets:  make(map[bucketKey]*tokenBucket),
+		now:      time.Now,
+	}
+}
+
+// Allow consumes a token for the command in the chat. If either the chat
+// or the command bucket is empty nothing is consumed and the time until
+// the next token is returned.
+func (l *rateLimiter) Allow(chatID int64, command string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if now.Sub(l.lastPrune) >= bucketPruneInterval {
+		l.prune(now)
+	}
+	type check struct {
+		bucket *tokenBucket
+		rule   *rateRule
+	}
+	var checks []check
+	if l.chat != nil {
+		checks = append(checks, check{l.bucket(bucketKey{chatID, ""}, l.chat, now), l.chat})
+	}
+	if rule, ok := l.commands[command]; ok {
+		checks = append(checks, check{l.bucket(bucketKey{chatID, command}, rule, now), rule})
+	}
+
+	var wait time.Duration
+	for _, c := range checks {
+		if c.bucket.tokens < 1 {
+			if w := c.bucket.wait(c.rule); w > wait {
+				wait = w
+			}
+		}
+	}
+	if wait > 0 {
+		return false, wait
+	}
+
+	for _, c := range checks {
+		c.bucket.tokens--
+	}
+	return true, 0
+}
+
+// prune drops the buckets that have refilled completely, since bucket
+// recreates them full
+func (l *rateLimiter) prune(now time.Time) {
+	l.lastPrune = now
+	for key, b := range l.buckets {
+		rule := l.chat
+		if key.command != "" {
+			rule = l.commands[key.command]
+		}
+		if rule == nil {
+			delete(l.buckets, key)
+			continue
+		}
+		b.refill(rule, now)
+		if b.tokens >= float64(rule.Burst) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// bucket returns the refilled bucket for key, creating a full one if needed
+func (l *rateLimiter) bucket(key bucketKey, rule *rateRule, now time.Time) *tokenBucket {
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: float64(rule.Burst), last: now}
+		l.buckets[key] = b
+		return b
+	}
+	b.refill(rule, now)
+	return b
+}
+
+// formatWait renders a retry delay for users, rounded up to whole seconds
+func formatWait(d time.Duration) string {
+	return (d + time.Second - 1).Truncate(time.Second).String()
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRateRule(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    *rateRule
+		wantErr bool
+	}{
+		{input: "30/m", want: &rateRule{Burst: 30, Per: time.Minute}},
+		{input: "5 / h", want: &rateRule{Burst: 5, Per: time.Hour}},
+		{input: "", want: nil},
+		{input: "0", want: nil},
+		{input: "10", wantErr: true},
+		{input: "x/m", wantErr: true},
+		{input: "10/w", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := parseRateRule(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseRateRule(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+				t.Errorf("parseRateRule(%q) = %+v, want %+v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	l := newRateLimiter(&rateRule{Burst: 3, Per: time.Minute}, map[string]*rateRule{
+		"time": {Burst: 1, Per: time.Hour},
+	})
+	l.now = func() time.Time { return now }
+
+	// The command bucket runs out first
+	if ok, _ := l.Allow(1, "time"); !ok {
+		t.Fatal("first /time should be allowed")
+	}
+	ok, wait := l.Allow(1, "time")
+	if ok {
+		t.Fatal("second /time should be limited")
+	}
+	if wait <= 59*time.Minute || wait > time.Hour {
+		t.Errorf("wait = %v, want about an hour", wait)
+	}
+
+	// A denied call must not consume the chat bucket
+	if ok, _ := l.Allow(1, "add"); !ok {
+		t.Error("/add should be allowed")
+	}
+	if ok, _ := l.Allow(1, "add"); !ok {
+		t.Error("/add should be allowed")
+	}
+	if ok, _ := l.Allow(1, "add"); ok {
+		t.Error("chat bucket should be empty after 3 commands")
+	}
+
+	// Other chats have their own buckets
+	if ok, _ := l.Allow(2, "time"); !ok {
+		t.Error("another chat should not be limited")
+	}
+
+	// Tokens refill over time
+	now = now.Add(20 * time.Second)
+	if ok, _ := l.Allow(1, "add"); !ok {
+		t.Error("chat bucket should refill after 20s")
+	}
+}
+
+func TestRateLimiterPrune(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	l := newRateLimiter(&rateRule{Burst: 3, Per: time.Minute}, map[string]*rateRule{
+		"time": {Burst: 1, Per: time.Hour},
+	})
+	l.now = func() time.Time { return now }
+
+	for chat := int64(1); chat <= 100; chat++ {
+		l.Allow(chat, "add")
+	}
+	l.Allow(1, "time")
+	if len(l.buckets) != 101 {
+		t.Fatalf("%d buckets, want 101", len(l.buckets))
+	}
+
+	// Chat buckets are full again after a minute, the /time bucket isn't
+	now = now.Add(bucketPruneInterval)
+	l.Allow(2, "add")
+	if _, ok := l.buckets[bucketKey{1, "time"}]; !ok || len(l.buckets) != 2 {
+		t.Errorf("buckets after prune = %d, want the /time bucket and chat 2", len(l.buckets))
+	}
+	if ok, _ := l.Allow(1, "time"); ok {
+		t.Error("pruning must keep buckets that are still limiting")
+	}
+}
@@ -9,10 +9,21 @@ COMMANDS='[
   {"command":"pull","description":"Get a random item"},
   {"command":"delete","description":"Delete the last pulled item"},
   {"command":"list","description":"Show all stored items"},
+  {"command":"edit","description":"Edit an item: /edit <id> <new text>"},
+  {"command":"history","description":"Show the edit history of an item"},
+  {"command":"tag","description":"Tag an item: /tag <id> <tags>"},
   {"command":"deleted","description":"Show deleted items"},
-  {"command":"export","description":"Download a backup of all your data"},
+  {"command":"export","description":"Download your data: /export [json|zip|md|csv|obsidian]"},
+  {"command":"import","description":"Restore items from an /export backup file"},
   {"command":"time","description":"Calculate times, convert formats, or check time zones"},
+  {"command":"settz","description":"Set your home time zone: /settz New York"},
+  {"command":"duration","description":"Time arithmetic: /duration 3:15 PM + 2.5h"},
+  {"command":"leaveby","description":"Plan backwards: /leaveby 7:45 AM; drive 1h, security 25m"},
+  {"command":"worldclock","description":"Show the time in saved places: /worldclock add Tokyo"},
+  {"command":"meet","description":"Find meeting times: /meet New York; London; Tokyo"},
   {"command":"undo","description":"Restore the last deleted item (within 1 hour)"},
+  {"command":"ask","description":"Ask about your notes: /ask what did I save about onboarding?"},
+  {"command":"usage","description":"Show AI usage and remaining budget"},
   {"command":"help","description":"Show help message"}
 ]'
 
@@ -0,0 +1,138 @@
+package speech
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Transcriber turns recorded speech into text
+type Transcriber interface {
+	// Transcribe converts audio to text. filename hints the audio format
+	// (e.g. "voice.ogg").
+	Transcribe(ctx context.Context, audio []byte, filename string) (string, error)
+}
+
+// WhisperClient talks to an OpenAI-compatible /audio/transcriptions
+// endpoint, such as OpenAI Whisper or a self-hosted whisper server
+type WhisperClient struct {
+	baseURL  string
+	apiKey   string
+	model    string
+	language string
+	client   *http.Client
+}
+
+// NewWhisperClient creates a new WhisperClient. baseURL is the API root,
+// e.g. https://api.openai.com/v1. language may be empty to auto-detect.
+func NewWhisperClient(baseURL, apiKey, model, language string) *WhisperClient {
+	return &WhisperClient{
+		baseURL:  strings.TrimRight(baseURL, "/"),
+		apiKey:   apiKey,
+		model:    model,
+		language: language,
+		client:   &http.Client{Timeout: 2 * time.Minute},
+	}
+}
+
+type transcriptionResponse struct {
+	Text  string `json:"text"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// Transcribe uploads the audio and returns the recognized text
+func (w *WhisperClient) Transcribe(ctx context.Context, audio []byte, filename string) (string, error) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+
+	part, err := mw.CreateFormFile("file", filename)
+	if err != nil {
+		return "", fmt.Errorf("error creating request: %v", err)
+	}
+	if _, err := part.Write(audio); err != nil {
+		return "", fmt.Errorf("error creating request: %v", err)
+	}
+	fields := map[string]string{
+		"model":           w.model,
+		"response_format": "json",
+	}
+	if w.language != "" {
+		fields["language"] = w.language
+	}
+	for k, v := range fields {
+		if err := mw.WriteField(k, v); err != nil {
+			return "", fmt.Errorf("error creating request: %v", err)
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return "", fmt.Errorf("error creating request: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", w.baseURL+"/audio/transcriptions", &body)
+	if err != nil {
+		return "", fmt.Errorf("error creating request: %v", err)
+	}
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	if w.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+w.apiKey)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("error making request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("error reading response: %v", err)
+	}
+
+	var result transcriptionResponse
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		log.Printf("Error decoding transcription response: %v, Body: %s", err, string(respBody))
+		return "", fmt.Errorf("error decoding response: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		msg := resp.Status
+		if result.Error != nil && result.Error.Message != "" {
+			msg = result.Error.Message
+		}
+		return "", fmt.Errorf("transcription API error: %d %s", resp.StatusCode, msg)
+	}
+
+	return strings.TrimSpace(result.Text), nil
+}
+
+// Fake is a Transcriber for tests. It returns Text, or Err if set, and
+// records the calls it received.
+type Fake struct {
+	Text  string
+	Err   error
+	Calls []FakeCall
+}
+
+// FakeCall is one call received by Fake
+type FakeCall struct {
+	Audio    []byte
+	Filename string
+}
+
+// Transcribe records the call and returns the canned result
+func (f *Fake) Transcribe(ctx context.Context, audio []byte, filename string) (string, error) {
+	f.Calls = append(f.Calls, FakeCall{Audio: audio, Filename: filename})
+	if f.Err != nil {
+		return "", f.Err
+	}
+	return f.Text, nil
+}
@@ -0,0 +1,60 @@
+package speech
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWhisperClientTranscribe(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/audio/transcriptions" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer test-key" {
+			t.Errorf("Authorization = %q", got)
+		}
+		if got := r.FormValue("model"); got != "whisper-1" {
+			t.Errorf("model = %q", got)
+		}
+		if got := r.FormValue("language"); got != "en" {
+			t.Errorf("language = %q", got)
+		}
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			t.Errorf("missing file: %v", err)
+			http.Error(w, "missing file", http.StatusBadRequest)
+			return
+		}
+		data, _ := io.ReadAll(file)
+		if header.Filename != "voice.ogg" || string(data) != "audio-bytes" {
+			t.Errorf("file = %s %q", header.Filename, data)
+		}
+		w.Write([]byte(`{"text": " remember to call mom "}`))
+	}))
+	defer server.Close()
+
+	client := NewWhisperClient(server.URL+"/v1/", "test-key", "whisper-1", "en")
+	got, err := client.Transcribe(context.Background(), []byte("audio-bytes"), "voice.ogg")
+	if err != nil {
+		t.Fatalf("Transcribe() error = %v", err)
+	}
+	if got != "remember to call mom" {
+		t.Errorf("Transcribe() = %q", got)
+	}
+}
+
+func TestWhisperClientError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error": {"message": "invalid api key"}}`))
+	}))
+	defer server.Close()
+
+	client := NewWhisperClient(server.URL, "bad", "whisper-1", "")
+	if _, err := client.Transcribe(context.Background(), []byte("x"), "voice.ogg"); err == nil {
+		t.Error("Transcribe() expected error for 401 response")
+	}
+}
@@ -0,0 +1,402 @@
+package main
+
+import (
+	"maps"
+	"math/rand"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Item is a stored note
+type Item struct {
+	ID        int64
+	Text      string
+	Kind      string
+	FileID    string
+	ChatID    int64
+	MessageID int
+	Source    string
+	CreatedAt time.Time
+
+	// Set on notes captured from forwards and replies
+	Origin     string     // original sender or channel of a forward
+	OriginDate *time.Time // when the forwarded message was first sent
+	ReplyTo    string     // text of the message being replied to
+
+	// Telegram file details of media items
+	FileUniqueID string
+	FileName     string
+	MimeType     string
+	FileSize     int
+	Duration     int // seconds, for voice, audio and video
+}
+
+// Revision is a previous version of an item's text
+type Revision struct {
+	Text     string
+	Source   string
+	EditedAt time.Time
+}
+
+// DeletedItem is an item moved out of the items table by Delete
+type DeletedItem struct {
+	ID        int64
+	Text      string
+	Kind      string
+	FileID    string
+	DeletedAt time.Time
+}
+
+// ListOptions filters List and Search. Zero values mean no filter.
+type ListOptions struct {
+	Kind  string
+	Limit int
+}
+
+// ItemStore is the storage behind the notes commands
+type ItemStore interface {
+	// Add stores a new item and returns its ID. Empty Kind and Source
+	// default to text and command; a zero CreatedAt means now.
+	Add(item Item) (int64, error)
+	// Get returns an item or errItemNotFound
+	Get(id int64) (Item, error)
+	// RandomPull returns a random item or errItemNotFound if there are none
+	RandomPull() (Item, error)
+	// Delete moves an item to the deleted items. It returns false if the
+	// item doesn't exist.
+	Delete(id int64) (bool, error)
+	// Restore moves a deleted item back and returns it with its new ID
+	Restore(deletedID int64) (Item, error)
+	// List returns items, newest first
+	List(opts ListOptions) ([]Item, error)
+	// Search returns items containing query (case-insensitive), newest first
+	Search(query string, opts ListOptions) ([]Item, error)
+	// Deleted returns deleted items, most recently deleted first
+	Deleted(limit int) ([]DeletedItem, error)
+	// AddDeleted stores an item as already deleted, e.g. from a backup. A
+	// zero DeletedAt means now.
+	AddDeleted(d DeletedItem) error
+
+	// Edit replaces the text of an item, keeping the old text as a
+	// revision, and returns the old text or errItemNotFound
+	Edit(id int64, text string, editedBy int64, source string) (string, error)
+	// ReplaceText replaces the text of an item without keeping a revision,
+	// but only while the text is still old. It returns false if the item
+	// doesn't exist or its text has changed.
+	ReplaceText(id int64, old, text string) (bool, error)
+	// Revisions returns the previous versions of an item, newest first
+	Revisions(id int64) ([]Revision, error)
+	// FindByMessage returns the item saved from a chat message or
+	// errItemNotFound
+	FindByMessage(chatID int64, messageID int) (Item, error)
+
+	// AddTags attaches tags to an item or returns errItemNotFound
+	AddTags(id int64, tags []string) error
+	// Tags returns the tags of an item, sorted
+	Tags(id int64) ([]string, error)
+	// AllTags returns the sorted tags of every tagged item keyed by item ID
+	AllTags() (map[int64][]string, error)
+
+	// Clear removes all items with their tags and revisions, and all
+	// deleted items. It returns how many items and deleted items there were.
+	Clear() (items, deleted int, err error)
+	// Transaction runs fn with a store whose changes are kept only if fn
+	// returns nil
+	Transaction(fn func(ItemStore) error) error
+}
+
+// store is the ItemStore used by the bot and CLI, set up by initDB
+var store ItemStore
+
+// withDefaults fills in the defaults documented on ItemStore.Add
+func (item Item) withDefaults(now time.Time) Item {
+	if item.Kind == "" {
+		item.Kind = kindText
+	}
+	if item.Source == "" {
+		item.Source = sourceCommand
+	}
+	if item.CreatedAt.IsZero() {
+		item.CreatedAt = now
+	}
+	return item
+}
+
+// memoryStore is an ItemStore kept in memory, for tests
+type memoryStore struct {
+	mu        sync.Mutex
+	items     map[int64]Item
+	deleted   []DeletedItem
+	revisions map[int64][]Revision // oldest first
+	tags      map[int64][]string   // sorted
+	nextID    int64
+	now       func() time.Time
+}
+
+// newMemoryStore creates an empty memoryStore
+func newMemoryStore() *memoryStore {
+	return &memoryStore{
+		items:     make(map[int64]Item),
+		revisions: make(map[int64][]Revision),
+		tags:      make(map[int64][]string),
+		now:       time.Now,
+	}
+}
+
+func (s *memoryStore) Add(item Item) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextID++
+	item = item.withDefaults(s.now().UTC())
+	item.ID = s.nextID
+	s.items[item.ID] = item
+	return item.ID, nil
+}
+
+func (s *memoryStore) Get(id int64) (Item, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	item, ok := s.items[id]
+	if !ok {
+		return Item{}, errItemNotFound
+	}
+	return item, nil
+}
+
+func (s *memoryStore) RandomPull() (Item, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.items) == 0 {
+		return Item{}, errItemNotFound
+	}
+	n := rand.Intn(len(s.items))
+	for _, item := range s.items {
+		if n == 0 {
+			return item, nil
+		}
+		n--
+	}
+	return Item{}, errItemNotFound
+}
+
+func (s *memoryStore) Delete(id int64) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	item, ok := s.items[id]
+	if !ok {
+		return false, nil
+	}
+	delete(s.items, id)
+	delete(s.revisions, id)
+	delete(s.tags, id)
+	s.deleted = append(s.deleted, DeletedItem{
+		ID:        int64(len(s.deleted) + 1),
+		Text:      item.Text,
+		Kind:      item.Kind,
+		FileID:    item.FileID,
+		DeletedAt: s.now().UTC(),
+	})
+	return true, nil
+}
+
+func (s *memoryStore) Restore(deletedID int64) (Item, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, d := range s.deleted {
+		if d.ID != deletedID {
+			continue
+		}
+		s.deleted = append(s.deleted[:i], s.deleted[i+1:]...)
+		s.nextID++
+		item := Item{ID: s.nextID, Text: d.Text, Kind: d.Kind, FileID: d.FileID}.withDefaults(s.now().UTC())
+		s.items[item.ID] = item
+		return item, nil
+	}
+	return Item{}, errItemNotFound
+}
+
+func (s *memoryStore) List(opts ListOptions) ([]Item, error) {
+	return s.Search("", opts)
+}
+
+func (s *memoryStore) Search(query string, opts ListOptions) ([]Item, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	query = strings.ToLower(query)
+
+	var items []Item
+	for _, item := range s.items {
+		if opts.Kind != "" && item.Kind != opts.Kind {
+			continue
+		}
+		if query != "" && !strings.Contains(strings.ToLower(item.Text), query) {
+			continue
+		}
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if !items[i].CreatedAt.Equal(items[j].CreatedAt) {
+			return items[i].CreatedAt.After(items[j].CreatedAt)
+		}
+		return items[i].ID > items[j].ID
+	})
+	if opts.Limit > 0 && len(items) > opts.Limit {
+		items = items[:opts.Limit]
+	}
+	return items, nil
+}
+
+func (s *memoryStore) Deleted(limit int) ([]DeletedItem, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	deleted := slices.Clone(s.deleted)
+	sort.Slice(deleted, func(i, j int) bool {
+		if !deleted[i].DeletedAt.Equal(deleted[j].DeletedAt) {
+			return deleted[i].DeletedAt.After(deleted[j].DeletedAt)
+		}
+		return deleted[i].ID > deleted[j].ID
+	})
+	if limit > 0 && len(deleted) > limit {
+		deleted = deleted[:limit]
+	}
+	return deleted, nil
+}
+
+func (s *memoryStore) AddDeleted(d DeletedItem) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if d.DeletedAt.IsZero() {
+		d.DeletedAt = s.now().UTC()
+	}
+	d.ID = int64(len(s.deleted) + 1)
+	for _, other := range s.deleted {
+		if other.ID >= d.ID {
+			d.ID = other.ID + 1
+		}
+	}
+	s.deleted = append(s.deleted, d)
+	return nil
+}
+
+func (s *memoryStore) Edit(id int64, text string, editedBy int64, source string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	item, ok := s.items[id]
+	if !ok {
+		return "", errItemNotFound
+	}
+	old := item.Text
+	if old == text {
+		return old, nil
+	}
+	s.revisions[id] = append(s.revisions[id], Revision{Text: old, Source: source, EditedAt: s.now().UTC()})
+	item.Text = text
+	s.items[id] = item
+	return old, nil
+}
+
+func (s *memoryStore) ReplaceText(id int64, old, text string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	item, ok := s.items[id]
+	if !ok || item.Text != old {
+		return false, nil
+	}
+	item.Text = text
+	s.items[id] = item
+	return true, nil
+}
+
+func (s *memoryStore) Revisions(id int64) ([]Revision, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var revisions []Revision
+	for i := len(s.revisions[id]) - 1; i >= 0; i-- {
+		revisions = append(revisions, s.revisions[id][i])
+	}
+	return revisions, nil
+}
+
+func (s *memoryStore) FindByMessage(chatID int64, messageID int) (Item, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, item := range s.items {
+		if item.ChatID == chatID && item.MessageID == messageID {
+			return item, nil
+		}
+	}
+	return Item{}, errItemNotFound
+}
+
+func (s *memoryStore) AddTags(id int64, tags []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.items[id]; !ok {
+		return errItemNotFound
+	}
+	for _, tag := range tags {
+		if !slices.Contains(s.tags[id], tag) {
+			s.tags[id] = append(s.tags[id], tag)
+		}
+	}
+	sort.Strings(s.tags[id])
+	return nil
+}
+
+func (s *memoryStore) Tags(id int64) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.tags[id]), nil
+}
+
+func (s *memoryStore) AllTags() (map[int64][]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tags := make(map[int64][]string)
+	for id, t := range s.tags {
+		if len(t) > 0 {
+			tags[id] = slices.Clone(t)
+		}
+	}
+	return tags, nil
+}
+
+func (s *memoryStore) Clear() (int, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	items, deleted := len(s.items), len(s.deleted)
+	s.items = make(map[int64]Item)
+	s.deleted = nil
+	s.revisions = make(map[int64][]Revision)
+	s.tags = make(map[int64][]string)
+	return items, deleted, nil
+}
+
+// Transaction restores a snapshot of the store if fn fails. Unlike the
+// SQL store it doesn't isolate fn from other callers.
+func (s *memoryStore) Transaction(fn func(ItemStore) error) error {
+	s.mu.Lock()
+	items := maps.Clone(s.items)
+	deleted := slices.Clone(s.deleted)
+	revisions := make(map[int64][]Revision)
+	for id, r := range s.revisions {
+		revisions[id] = slices.Clone(r)
+	}
+	tags := make(map[int64][]string)
+	for id, t := range s.tags {
+		tags[id] = slices.Clone(t)
+	}
+	nextID := s.nextID
+	s.mu.Unlock()
+
+	if err := fn(s); err != nil {
+		s.mu.Lock()
+		s.items, s.deleted, s.revisions, s.tags, s.nextID = items, deleted, revisions, tags, nextID
+		s.mu.Unlock()
+		return err
+	}
+	return nil
+}
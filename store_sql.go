@@ -0,0 +1,397 @@
+package main
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+)
+
+// sqlStore is the ItemStore backed by the bot database. The queries work
+// on both SQLite and Postgres.
+type sqlStore struct {
+	db *sql.DB
+	tx *sql.Tx // set on the store passed to a Transaction function
+
+	// Prepared once for the queries run on almost every message
+	addStmt    *sql.Stmt
+	addAtStmt  *sql.Stmt
+	getStmt    *sql.Stmt
+	randomStmt *sql.Stmt
+}
+
+const itemColumns = `id, text, kind, file_id, chat_id, message_id, source, created_at,
+	origin, origin_date, reply_to, file_unique_id, file_name, mime_type, file_size, duration`
+
+// addColumns are the columns Add sets, apart from created_at
+const addColumns = `text, kind, file_id, chat_id, message_id, source,
+	origin, origin_date, reply_to, file_unique_id, file_name, mime_type, file_size, duration`
+
+// querier runs queries on the database or in a transaction
+type querier interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// newSQLStore creates an ItemStore on an initialized database
+func newSQLStore(db *sql.DB) (*sqlStore, error) {
+	s := &sqlStore{db: db}
+	queries := []struct {
+		stmt  **sql.Stmt
+		query string
+	}{
+		{&s.addStmt, "INSERT INTO items (" + addColumns + ") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING id"},
+		{&s.addAtStmt, "INSERT INTO items (" + addColumns + ", created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING id"},
+		{&s.getStmt, "SELECT " + itemColumns + " FROM items WHERE id = ?"},
+		{&s.randomStmt, "SELECT " + itemColumns + " FROM items ORDER BY RANDOM() LIMIT 1"},
+	}
+	for _, q := range queries {
+		stmt, err := db.Prepare(q.query)
+		if err != nil {
+			return nil, err
+		}
+		*q.stmt = stmt
+	}
+	return s, nil
+}
+
+// q returns the transaction of the store, or the database
+func (s *sqlStore) q() querier {
+	if s.tx != nil {
+		return s.tx
+	}
+	return s.db
+}
+
+// stmt returns a prepared statement usable in the store's transaction
+func (s *sqlStore) stmt(stmt *sql.Stmt) *sql.Stmt {
+	if s.tx != nil {
+		return s.tx.Stmt(stmt)
+	}
+	return stmt
+}
+
+// update runs fn in the store's transaction, or in a new one committed if
+// fn succeeds
+func (s *sqlStore) update(fn func(tx *sql.Tx) error) error {
+	if s.tx != nil {
+		return fn(s.tx)
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *sqlStore) Transaction(fn func(ItemStore) error) error {
+	return s.update(func(tx *sql.Tx) error {
+		txStore := *s
+		txStore.tx = tx
+		return fn(&txStore)
+	})
+}
+
+// scanItem reads a row selected with itemColumns
+func scanItem(row interface{ Scan(...any) error }) (Item, error) {
+	var item Item
+	err := row.Scan(&item.ID, &item.Text, &item.Kind, &item.FileID, &item.ChatID, &item.MessageID, &item.Source, &item.CreatedAt,
+		&item.Origin, &item.OriginDate, &item.ReplyTo, &item.FileUniqueID, &item.FileName, &item.MimeType, &item.FileSize, &item.Duration)
+	if err == sql.ErrNoRows {
+		return item, errItemNotFound
+	}
+	return item, err
+}
+
+// scanItems reads the rows of a query selecting itemColumns
+func scanItems(rows *sql.Rows, err error) ([]Item, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []Item
+	for rows.Next() {
+		item, err := scanItem(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+func (s *sqlStore) Add(item Item) (int64, error) {
+	// Leave created_at to the column default unless it is given
+	createdAt := item.CreatedAt
+	item = item.withDefaults(time.Time{})
+	args := []any{item.Text, item.Kind, item.FileID, item.ChatID, item.MessageID, item.Source,
+		item.Origin, item.OriginDate, item.ReplyTo, item.FileUniqueID, item.FileName, item.MimeType, item.FileSize, item.Duration}
+
+	var id int64
+	var err error
+	if createdAt.IsZero() {
+		err = s.stmt(s.addStmt).QueryRow(args...).Scan(&id)
+	} else {
+		err = s.stmt(s.addAtStmt).QueryRow(append(args, createdAt.UTC())...).Scan(&id)
+	}
+	return id, err
+}
+
+func (s *sqlStore) Get(id int64) (Item, error) {
+	return scanItem(s.stmt(s.getStmt).QueryRow(id))
+}
+
+func (s *sqlStore) RandomPull() (Item, error) {
+	return scanItem(s.stmt(s.randomStmt).QueryRow())
+}
+
+func (s *sqlStore) Delete(id int64) (bool, error) {
+	var n int64
+	err := s.update(func(tx *sql.Tx) error {
+		if _, err := tx.Exec("INSERT INTO deleted (text, kind, file_id) SELECT text, kind, file_id FROM items WHERE id = ?", id); err != nil {
+			return err
+		}
+		res, err := tx.Exec("DELETE FROM items WHERE id = ?", id)
+		if err != nil {
+			return err
+		}
+		for _, table := range []string{"item_tags", "item_revisions"} {
+			if _, err := tx.Exec("DELETE FROM "+table+" WHERE item_id = ?", id); err != nil {
+				return err
+			}
+		}
+		n, _ = res.RowsAffected()
+		return nil
+	})
+	return n > 0, err
+}
+
+func (s *sqlStore) Restore(deletedID int64) (Item, error) {
+	var item Item
+	err := s.update(func(tx *sql.Tx) error {
+		var d DeletedItem
+		err := tx.QueryRow("SELECT text, kind, file_id FROM deleted WHERE id = ?", deletedID).Scan(&d.Text, &d.Kind, &d.FileID)
+		if err == sql.ErrNoRows {
+			return errItemNotFound
+		} else if err != nil {
+			return err
+		}
+
+		var id int64
+		if err := tx.QueryRow("INSERT INTO items (text, kind, file_id) VALUES (?, ?, ?) RETURNING id", d.Text, d.Kind, d.FileID).Scan(&id); err != nil {
+			return err
+		}
+		if _, err := tx.Exec("DELETE FROM deleted WHERE id = ?", deletedID); err != nil {
+			return err
+		}
+		item, err = scanItem(tx.QueryRow("SELECT "+itemColumns+" FROM items WHERE id = ?", id))
+		return err
+	})
+	if err != nil {
+		return Item{}, err
+	}
+	return item, nil
+}
+
+func (s *sqlStore) List(opts ListOptions) ([]Item, error) {
+	return s.Search("", opts)
+}
+
+func (s *sqlStore) Search(query string, opts ListOptions) ([]Item, error) {
+	var where []string
+	var args []any
+	if opts.Kind != "" {
+		where = append(where, "kind = ?")
+		args = append(args, opts.Kind)
+	}
+	if query != "" {
+		// LIKE is case-sensitive on Postgres
+		where = append(where, `LOWER(text) LIKE ? ESCAPE '\'`)
+		args = append(args, "%"+escapeLike(strings.ToLower(query))+"%")
+	}
+
+	q := "SELECT " + itemColumns + " FROM items"
+	if len(where) > 0 {
+		q += " WHERE " + strings.Join(where, " AND ")
+	}
+	q += " ORDER BY created_at DESC, id DESC"
+	if opts.Limit > 0 {
+		q += " LIMIT ?"
+		args = append(args, opts.Limit)
+	}
+
+	return scanItems(s.q().Query(q, args...))
+}
+
+// escapeLike escapes the LIKE wildcards in s
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+func (s *sqlStore) Deleted(limit int) ([]DeletedItem, error) {
+	q := "SELECT id, text, kind, file_id, deleted_at FROM deleted ORDER BY deleted_at DESC, id DESC"
+	var args []any
+	if limit > 0 {
+		q += " LIMIT ?"
+		args = append(args, limit)
+	}
+
+	rows, err := s.q().Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deleted []DeletedItem
+	for rows.Next() {
+		var d DeletedItem
+		if err := rows.Scan(&d.ID, &d.Text, &d.Kind, &d.FileID, &d.DeletedAt); err != nil {
+			return nil, err
+		}
+		deleted = append(deleted, d)
+	}
+	return deleted, rows.Err()
+}
+
+func (s *sqlStore) AddDeleted(d DeletedItem) error {
+	if d.DeletedAt.IsZero() {
+		_, err := s.q().Exec("INSERT INTO deleted (text, kind, file_id) VALUES (?, ?, ?)", d.Text, d.Kind, d.FileID)
+		return err
+	}
+	_, err := s.q().Exec("INSERT INTO deleted (text, kind, file_id, deleted_at) VALUES (?, ?, ?, ?)",
+		d.Text, d.Kind, d.FileID, d.DeletedAt.UTC())
+	return err
+}
+
+func (s *sqlStore) Edit(id int64, text string, editedBy int64, source string) (string, error) {
+	var old string
+	err := s.update(func(tx *sql.Tx) error {
+		err := tx.QueryRow("SELECT text FROM items WHERE id = ?", id).Scan(&old)
+		if err == sql.ErrNoRows {
+			return errItemNotFound
+		} else if err != nil || old == text {
+			return err
+		}
+
+		if _, err := tx.Exec("INSERT INTO item_revisions (item_id, text, edited_by, source) VALUES (?, ?, ?, ?)",
+			id, old, editedBy, source); err != nil {
+			return err
+		}
+		_, err = tx.Exec("UPDATE items SET text = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?", text, id)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	return old, nil
+}
+
+func (s *sqlStore) ReplaceText(id int64, old, text string) (bool, error) {
+	res, err := s.q().Exec("UPDATE items SET text = ? WHERE id = ? AND text = ?", text, id, old)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (s *sqlStore) Revisions(id int64) ([]Revision, error) {
+	rows, err := s.q().Query("SELECT text, source, edited_at FROM item_revisions WHERE item_id = ? ORDER BY edited_at DESC, id DESC", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var revisions []Revision
+	for rows.Next() {
+		var r Revision
+		if err := rows.Scan(&r.Text, &r.Source, &r.EditedAt); err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, r)
+	}
+	return revisions, rows.Err()
+}
+
+func (s *sqlStore) FindByMessage(chatID int64, messageID int) (Item, error) {
+	return scanItem(s.q().QueryRow("SELECT "+itemColumns+" FROM items WHERE chat_id = ? AND message_id = ? ORDER BY id LIMIT 1", chatID, messageID))
+}
+
+func (s *sqlStore) AddTags(id int64, tags []string) error {
+	return s.update(func(tx *sql.Tx) error {
+		var exists int
+		if err := tx.QueryRow("SELECT 1 FROM items WHERE id = ?", id).Scan(&exists); err == sql.ErrNoRows {
+			return errItemNotFound
+		} else if err != nil {
+			return err
+		}
+
+		for _, tag := range tags {
+			if _, err := tx.Exec("INSERT INTO item_tags (item_id, tag) VALUES (?, ?) ON CONFLICT DO NOTHING", id, tag); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *sqlStore) Tags(id int64) ([]string, error) {
+	rows, err := s.q().Query("SELECT tag FROM item_tags WHERE item_id = ? ORDER BY tag", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tags []string
+	for rows.Next() {
+		var tag string
+		if err := rows.Scan(&tag); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+func (s *sqlStore) AllTags() (map[int64][]string, error) {
+	rows, err := s.q().Query("SELECT item_id, tag FROM item_tags ORDER BY item_id, tag")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := make(map[int64][]string)
+	for rows.Next() {
+		var id int64
+		var tag string
+		if err := rows.Scan(&id, &tag); err != nil {
+			return nil, err
+		}
+		tags[id] = append(tags[id], tag)
+	}
+	return tags, rows.Err()
+}
+
+func (s *sqlStore) Clear() (int, int, error) {
+	var items, deleted int
+	err := s.update(func(tx *sql.Tx) error {
+		if err := tx.QueryRow("SELECT COUNT(*) FROM items").Scan(&items); err != nil {
+			return err
+		}
+		if err := tx.QueryRow("SELECT COUNT(*) FROM deleted").Scan(&deleted); err != nil {
+			return err
+		}
+		for _, table := range []string{"items", "item_tags", "item_revisions", "deleted"} {
+			if _, err := tx.Exec("DELETE FROM " + table); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return items, deleted, err
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testItemStore checks the ItemStore contract on an empty store
+func testItemStore(t *testing.T, s ItemStore) {
+	if _, err := s.RandomPull(); err != errItemNotFound {
+		t.Fatalf("RandomPull() on empty store error = %v, want errItemNotFound", err)
+	}
+
+	old := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	milk, err := s.Add(Item{Text: "Buy milk", CreatedAt: old})
+	if err != nil {
+		t.Fatal(err)
+	}
+	photo, err := s.Add(Item{Text: "whiteboard 100%", Kind: kindPhoto, FileID: "f1", ChatID: 5, MessageID: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := s.Get(photo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Kind != kindPhoto || got.FileID != "f1" || got.ChatID != 5 || got.MessageID != 7 || got.Source != sourceCommand {
+		t.Errorf("Get() = %+v", got)
+	}
+	if _, err := s.Get(999); err != errItemNotFound {
+		t.Errorf("Get(999) error = %v, want errItemNotFound", err)
+	}
+	if item, err := s.RandomPull(); err != nil || (item.ID != milk && item.ID != photo) {
+		t.Errorf("RandomPull() = %+v, %v", item, err)
+	}
+
+	items, err := s.List(ListOptions{})
+	if err != nil || len(items) != 2 || items[0].ID != photo {
+		t.Errorf("List() = %+v, %v; want newest first", items, err)
+	}
+	if items, _ := s.List(ListOptions{Kind: kindPhoto}); len(items) != 1 || items[0].ID != photo {
+		t.Errorf("List(photo) = %+v", items)
+	}
+	if items, _ := s.List(ListOptions{Limit: 1}); len(items) != 1 {
+		t.Errorf("List(limit 1) returned %d items", len(items))
+	}
+	if items, _ := s.Search("MILK", ListOptions{}); len(items) != 1 || items[0].ID != milk {
+		t.Errorf("Search(MILK) = %+v", items)
+	}
+	if items, _ := s.Search("0%", ListOptions{}); len(items) != 1 || items[0].ID != photo {
+		t.Errorf("Search(0%%) = %+v, want wildcards matched literally", items)
+	}
+	if items, _ := s.Search("_", ListOptions{}); len(items) != 0 {
+		t.Errorf("Search(_) = %+v, want no match", items)
+	}
+
+	if ok, err := s.Delete(milk); !ok || err != nil {
+		t.Fatalf("Delete() = %v, %v", ok, err)
+	}
+	if ok, _ := s.Delete(milk); ok {
+		t.Error("Delete() of a deleted item should report false")
+	}
+	deleted, err := s.Deleted(0)
+	if err != nil || len(deleted) != 1 || deleted[0].Text != "Buy milk" {
+		t.Fatalf("Deleted() = %+v, %v", deleted, err)
+	}
+
+	restored, err := s.Restore(deleted[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.Text != "Buy milk" || restored.ID == milk {
+		t.Errorf("Restore() = %+v, want the text under a new ID", restored)
+	}
+	if deleted, _ := s.Deleted(0); len(deleted) != 0 {
+		t.Errorf("Deleted() after restore = %+v", deleted)
+	}
+	if _, err := s.Restore(deleted[0].ID); err != errItemNotFound {
+		t.Errorf("second Restore() error = %v, want errItemNotFound", err)
+	}
+
+	// Media and capture details
+	forwarded := time.Date(2024, 2, 3, 4, 5, 6, 0, time.UTC)
+	voice, err := s.Add(Item{Kind: kindVoice, FileID: "v1", FileUniqueID: "u1", FileName: "memo.ogg", MimeType: "audio/ogg",
+		FileSize: 1234, Duration: 9, ChatID: 5, MessageID: 8, Origin: "Alice", OriginDate: &forwarded, ReplyTo: "hi"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err = s.FindByMessage(5, 8)
+	if err != nil || got.ID != voice || got.FileUniqueID != "u1" || got.FileName != "memo.ogg" || got.MimeType != "audio/ogg" ||
+		got.FileSize != 1234 || got.Duration != 9 || got.Origin != "Alice" || got.OriginDate == nil ||
+		!got.OriginDate.Equal(forwarded) || got.ReplyTo != "hi" {
+		t.Errorf("FindByMessage() = %+v, %v", got, err)
+	}
+	if _, err := s.FindByMessage(5, 99); err != errItemNotFound {
+		t.Errorf("FindByMessage() of an unknown message error = %v, want errItemNotFound", err)
+	}
+
+	// Edits, tags and their removal with the item
+	if old, err := s.Edit(voice, "first", 1, editSourceCommand); err != nil || old != "" {
+		t.Fatalf("Edit() = %q, %v", old, err)
+	}
+	if old, err := s.Edit(voice, "second", 1, editSourceCommand); err != nil || old != "first" {
+		t.Fatalf("second Edit() = %q, %v", old, err)
+	}
+	if _, err := s.Edit(999, "x", 1, editSourceCommand); err != errItemNotFound {
+		t.Errorf("Edit(999) error = %v, want errItemNotFound", err)
+	}
+	if revisions, err := s.Revisions(voice); err != nil || len(revisions) != 2 || revisions[0].Text != "first" {
+		t.Errorf("Revisions() = %+v, %v", revisions, err)
+	}
+	if ok, err := s.ReplaceText(voice, "second", "transcript"); !ok || err != nil {
+		t.Errorf("ReplaceText() = %v, %v", ok, err)
+	}
+	if ok, _ := s.ReplaceText(voice, "second", "stale"); ok {
+		t.Error("ReplaceText() of a changed text should report false")
+	}
+	if ok, _ := s.ReplaceText(999, "", "x"); ok {
+		t.Error("ReplaceText() of a missing item should report false")
+	}
+	if err := s.AddTags(voice, []string{"work", "audio"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddTags(voice, []string{"work"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddTags(999, []string{"x"}); err != errItemNotFound {
+		t.Errorf("AddTags(999) error = %v, want errItemNotFound", err)
+	}
+	if tags, err := s.Tags(voice); err != nil || strings.Join(tags, ",") != "audio,work" {
+		t.Errorf("Tags() = %v, %v", tags, err)
+	}
+	if all, err := s.AllTags(); err != nil || len(all) != 1 || len(all[voice]) != 2 {
+		t.Errorf("AllTags() = %v, %v", all, err)
+	}
+	if _, err := s.Delete(voice); err != nil {
+		t.Fatal(err)
+	}
+	if revisions, _ := s.Revisions(voice); len(revisions) != 0 {
+		t.Errorf("Revisions() of a deleted item = %+v", revisions)
+	}
+	if all, _ := s.AllTags(); len(all) != 0 {
+		t.Errorf("AllTags() after delete = %v", all)
+	}
+
+	// Transactions keep changes only on success
+	boom := errors.New("boom")
+	err = s.Transaction(func(tx ItemStore) error {
+		if _, err := tx.Add(Item{Text: "rolled back"}); err != nil {
+			return err
+		}
+		if _, _, err := tx.Clear(); err != nil {
+			return err
+		}
+		return boom
+	})
+	if err != boom {
+		t.Errorf("Transaction() error = %v, want the error of fn", err)
+	}
+	if items, _ := s.Search("rolled back", ListOptions{}); len(items) != 0 {
+		t.Errorf("item added in a failed transaction was kept: %+v", items)
+	}
+	if items, _ := s.List(ListOptions{}); len(items) != 2 {
+		t.Errorf("List() after a failed transaction = %+v", items)
+	}
+
+	when := time.Date(2023, 5, 6, 7, 8, 9, 0, time.UTC)
+	err = s.Transaction(func(tx ItemStore) error {
+		return tx.AddDeleted(DeletedItem{Text: "old note", Kind: kindText, DeletedAt: when})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted, _ := s.Deleted(0); len(deleted) != 2 || deleted[1].Text != "old note" || !deleted[1].DeletedAt.Equal(when) {
+		t.Errorf("Deleted() after AddDeleted() = %+v", deleted)
+	}
+
+	if nItems, nDeleted, err := s.Clear(); err != nil || nItems != 2 || nDeleted != 2 {
+		t.Errorf("Clear() = %d, %d, %v", nItems, nDeleted, err)
+	}
+	if items, _ := s.List(ListOptions{}); len(items) != 0 {
+		t.Errorf("List() after Clear() = %+v", items)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	testItemStore(t, newMemoryStore())
+}
+
+func TestSQLiteStore(t *testing.T) {
+	if err := initDB(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	s, err := newSQLStore(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testItemStore(t, s)
+}
@@ -0,0 +1,518 @@
+package time
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DurationPart is one step of a list such as "security 25 min, drive 1h"
+type DurationPart struct {
+	Label    string // what the time is for, may be empty
+	Duration time.Duration
+}
+
+var (
+	// "2.5 hours", "45 min", "1h", "30s", "2 days"
+	durationTokenPattern = regexp.MustCompile(`(?i)(\d+(?:\.\d+)?)\s*(days?|d|hours?|hrs?|hr|h|minutes?|mins?|m|seconds?|secs?|sec|s)\b`)
+	// "1:30" meaning an hour and a half
+	durationClockPattern = regexp.MustCompile(`\b(\d+):([0-5]\d)\b`)
+	// Steps are separated by commas, semicolons, "+" or "then"
+	durationStepSeparator = regexp.MustCompile(`(?i)\s*(?:[,;+]|\bthen\b)\s*`)
+	// "and" separates steps too, unless it joins parts of one duration
+	durationAndSeparator = regexp.MustCompile(`(?i)\s+and\s+`)
+	// "1h30m" is read as "1h 30m"
+	durationUnitBoundary = regexp.MustCompile(`([a-zA-Z])(\d)`)
+	// "1.5.5h" is not a number
+	durationMalformedNumber = regexp.MustCompile(`\d+\.\d*\.`)
+)
+
+// maxDuration bounds parsed durations, well below the ~292 years a
+// time.Duration can hold
+const maxDuration = 10 * 365 * 24 * time.Hour
+
+var errDurationTooLong = fmt.Errorf("durations longer than 10 years are not supported")
+
+// durationUnits maps the first letter of a unit to its length
+var durationUnits = map[byte]time.Duration{
+	'd': 24 * time.Hour,
+	'h': time.Hour,
+	'm': time.Minute,
+	's': time.Second,
+}
+
+// ParseDurationList reads a list of durations such as "security 25
+// minutes, drive 1 hour and 10 min, breakfast 40m". Words around a
+// duration become its label; "and" followed by a bare duration extends
+// the previous step.
+func ParseDurationList(s string) ([]DurationPart, error) {
+	var parts []DurationPart
+	for _, step := range durationStepSeparator.Split(s, -1) {
+		for i, piece := range durationAndSeparator.Split(step, -1) {
+			if strings.TrimSpace(piece) == "" {
+				continue
+			}
+			part, err := parseDurationPart(piece)
+			if err != nil {
+				return nil, err
+			}
+			if i > 0 && part.Label == "" && len(parts) > 0 {
+				parts[len(parts)-1].Duration += part.Duration
+			} else {
+				parts = append(parts, part)
+			}
+			// Each part is bounded, so the running total cannot overflow
+			if sumDurations(parts) > maxDuration {
+				return nil, errDurationTooLong
+			}
+		}
+	}
+	if len(parts) == 0 {
+		return nil, fmt.Errorf("no duration given, try e.g. 1h 30m or 45 minutes")
+	}
+	return parts, nil
+}
+
+// parseDurationPart reads one step such as "drive 1 hour 10 min"
+func parseDurationPart(s string) (DurationPart, error) {
+	if m := durationMalformedNumber.FindString(s); m != "" {
+		return DurationPart{}, fmt.Errorf("invalid number in %q", strings.TrimSpace(s))
+	}
+
+	var total time.Duration
+	var tooLong bool
+	found := false
+	// add keeps total within maxDuration, so it never overflows
+	add := func(n float64, unit time.Duration) {
+		if n*float64(unit) > float64(maxDuration-total) {
+			tooLong = true
+			return
+		}
+		total += time.Duration(n * float64(unit))
+	}
+	s = durationUnitBoundary.ReplaceAllString(s, "$1 $2")
+	rest := durationTokenPattern.ReplaceAllStringFunc(s, func(token string) string {
+		m := durationTokenPattern.FindStringSubmatch(token)
+		n, err := strconv.ParseFloat(m[1], 64)
+		if err != nil {
+			return token
+		}
+		add(n, durationUnits[strings.ToLower(m[2])[0]])
+		found = true
+		return " "
+	})
+	rest = durationClockPattern.ReplaceAllStringFunc(rest, func(token string) string {
+		m := durationClockPattern.FindStringSubmatch(token)
+		h, _ := strconv.ParseFloat(m[1], 64)
+		min, _ := strconv.Atoi(m[2])
+		add(h, time.Hour)
+		add(float64(min), time.Minute)
+		found = true
+		return " "
+	})
+	if tooLong {
+		return DurationPart{}, errDurationTooLong
+	}
+	if !found {
+		return DurationPart{}, fmt.Errorf("no duration in %q, try e.g. 1h 30m or 45 minutes", strings.TrimSpace(s))
+	}
+
+	label := strings.Join(strings.Fields(rest), " ")
+	for _, filler := range []string{"for ", "of ", "a ", "and "} {
+		label = strings.TrimPrefix(label, filler)
+	}
+	label = strings.TrimSpace(strings.TrimSuffix(label, " for"))
+	return DurationPart{Label: label, Duration: total}, nil
+}
+
+// ParseDuration reads a duration such as "2.5 hours", "1h 20m" or "1:30".
+// Lists are added up.
+func ParseDuration(s string) (time.Duration, error) {
+	parts, err := ParseDurationList(s)
+	if err != nil {
+		return 0, err
+	}
+	return sumDurations(parts), nil
+}
+
+func sumDurations(parts []DurationPart) time.Duration {
+	var total time.Duration
+	for _, p := range parts {
+		total += p.Duration
+	}
+	return total
+}
+
+// FormatDuration writes a duration as "2h 35m", "1d 3h" or "-45m"
+func FormatDuration(d time.Duration) string {
+	sign := ""
+	if d < 0 {
+		sign = "-"
+		// -math.MinInt64 overflows back to itself
+		if d = -d; d < 0 {
+			d = math.MaxInt64
+		}
+	}
+	d = d.Round(time.Second)
+	days := d / (24 * time.Hour)
+	d -= days * 24 * time.Hour
+	hours := d / time.Hour
+	d -= hours * time.Hour
+	minutes := d / time.Minute
+	seconds := (d - minutes*time.Minute) / time.Second
+
+	var parts []string
+	if days > 0 {
+		parts = append(parts, fmt.Sprintf("%dd", days))
+	}
+	if hours > 0 {
+		parts = append(parts, fmt.Sprintf("%dh", hours))
+	}
+	if minutes > 0 {
+		parts = append(parts, fmt.Sprintf("%dm", minutes))
+	}
+	if seconds > 0 {
+		parts = append(parts, fmt.Sprintf("%ds", seconds))
+	}
+	if len(parts) == 0 {
+		return "0m"
+	}
+	return sign + strings.Join(parts, " ")
+}
+
+// parseClock reads "7pm", "7:45 AM" or "19:45"
+func parseClock(s string) (time.Time, error) {
+	normalized, err := normalizeClockTime(s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q: please use either 12-hour (e.g., 2:00 PM) or 24-hour (e.g., 14:00) format", strings.TrimSpace(s))
+	}
+	return parseClockTime(normalized)
+}
+
+var (
+	// "7:45 AM", "7:45 AM tomorrow", "7pm on March 10"
+	clockFirstPattern = regexp.MustCompile(`(?i)^(\d{1,2}(?::\d{2})?\s*(?:am|pm)?)(?:\s+(.+))?$`)
+	// "March 10 7:45 AM", "tomorrow 19:00"
+	clockLastPattern = regexp.MustCompile(`(?i)^(.+?)\s+(\d{1,2}(?::\d{2})?\s*(?:am|pm)?)$`)
+)
+
+// ParseDateTime reads a clock time with an optional date before or after
+// it, e.g. "7:45 AM", "7pm tomorrow" or "2025-03-10 19:00", as shown by the
+// clocks in loc. The date is read by ParseDate relative to now; a time
+// skipped by a DST change is moved forward as ResolveLocalTime does.
+func ParseDateTime(s string, loc *time.Location, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	// "10 March 7pm" looks like a clock first too, so try both readings
+	var readings [][2]string // clock, date
+	if m := clockFirstPattern.FindStringSubmatch(s); m != nil {
+		readings = append(readings, [2]string{m[1], m[2]})
+	}
+	if m := clockLastPattern.FindStringSubmatch(s); m != nil {
+		readings = append(readings, [2]string{m[2], m[1]})
+	}
+
+	err := fmt.Errorf("invalid time %q: please use e.g. 7:45 AM, 19:45 or 7pm tomorrow", s)
+	for _, r := range readings {
+		clock, clockErr := parseClock(r[0])
+		if clockErr != nil {
+			err = clockErr
+			continue
+		}
+		date, dateErr := ParseDate(r[1], now.In(loc))
+		if dateErr != nil {
+			err = dateErr
+			continue
+		}
+		instants, _ := ResolveLocalTime(date.Year(), date.Month(), date.Day(), clock.Hour(), clock.Minute(), loc)
+		return instants[0], nil
+	}
+	return time.Time{}, err
+}
+
+// clockLayout is how times are shown in arithmetic results
+const clockLayout = "3:04 PM (15:04)"
+
+// describeInstant shows a time with its date and zone when it is not a
+// plain clock time in UTC
+func describeInstant(t time.Time, zoned bool) string {
+	if !zoned {
+		return t.Format(clockLayout)
+	}
+	return t.Format("Mon Jan 2, " + clockLayout + " MST")
+}
+
+// offsetChangeNote explains a result that crosses a DST change, when the
+// clocks move by more or less than the time that passes, or returns ""
+func offsetChangeNote(from, to time.Time) string {
+	change := ZoneOffset(to) - ZoneOffset(from)
+	if change == 0 {
+		return ""
+	}
+	elapsed := to.Sub(from)
+	return fmt.Sprintf("\nNote: the clocks change from %s to %s in between, so they move by %s while %s pass.",
+		FormatOffset(ZoneOffset(from)), FormatOffset(ZoneOffset(to)),
+		FormatDuration((elapsed + change).Abs()), FormatDuration(elapsed.Abs()))
+}
+
+// shiftClock adds d to a time. Without a location the time is a plain
+// clock time today in UTC; with one it is read by ParseDateTime and the
+// result counts real elapsed time across DST changes.
+func shiftClock(timeStr, location string, d time.Duration) (from, to time.Time, zoned bool, err error) {
+	loc := time.UTC
+	if strings.TrimSpace(location) != "" {
+		if loc, err = loadLocation(location); err != nil {
+			return from, to, false, fmt.Errorf("invalid location: %w", err)
+		}
+		zoned = true
+	}
+	from, err = ParseDateTime(timeStr, loc, time.Now())
+	if err != nil {
+		return from, to, false, err
+	}
+	return from, from.Add(d), zoned, nil
+}
+
+// formatShift shows "start ± duration = result" with the day shift
+func formatShift(from, to time.Time, op string, d time.Duration, zoned bool) string {
+	shift := describeDayShift(daysBetween(from, to))
+	if shift != "" {
+		shift = " (" + shift + ")"
+	}
+	return fmt.Sprintf("%s %s %s = %s%s%s",
+		describeInstant(from, zoned), op, FormatDuration(d),
+		describeInstant(to, zoned), shift, offsetChangeNote(from, to))
+}
+
+// AddDurationWithTools adds durations such as "2.5 hours" or "1h, 20m" to
+// a time. The location is optional.
+func AddDurationWithTools(timeStr, durations, location string) (string, error) {
+	d, err := ParseDuration(durations)
+	if err != nil {
+		return "", err
+	}
+	from, to, zoned, err := shiftClock(timeStr, location, d)
+	if err != nil {
+		return "", err
+	}
+	return formatShift(from, to, "+", d, zoned), nil
+}
+
+// SubtractDurationWithTools subtracts durations from a time. The location
+// is optional.
+func SubtractDurationWithTools(timeStr, durations, location string) (string, error) {
+	d, err := ParseDuration(durations)
+	if err != nil {
+		return "", err
+	}
+	from, to, zoned, err := shiftClock(timeStr, location, -d)
+	if err != nil {
+		return "", err
+	}
+	return formatShift(from, to, "-", d, zoned), nil
+}
+
+// SumDurationsWithTools adds up a list of durations
+func SumDurationsWithTools(durations string) (string, error) {
+	parts, err := ParseDurationList(durations)
+	if err != nil {
+		return "", err
+	}
+	var terms []string
+	for _, p := range parts {
+		terms = append(terms, FormatDuration(p.Duration))
+	}
+	return fmt.Sprintf("%s = %s", strings.Join(terms, " + "), FormatDuration(sumDurations(parts))), nil
+}
+
+// TimeDifferenceWithTools returns the real time elapsed between a time in
+// one location and a time in another, e.g. between a departure and an
+// arrival. Each time may include a date.
+func TimeDifferenceWithTools(fromTime, fromLocation, toTime, toLocation string) (string, error) {
+	fromLoc, err := loadLocation(fromLocation)
+	if err != nil {
+		return "", fmt.Errorf("invalid source location: %w", err)
+	}
+	toLoc, err := loadLocation(toLocation)
+	if err != nil {
+		return "", fmt.Errorf("invalid target location: %w", err)
+	}
+	now := time.Now()
+	from, err := ParseDateTime(fromTime, fromLoc, now)
+	if err != nil {
+		return "", err
+	}
+	to, err := ParseDateTime(toTime, toLoc, now)
+	if err != nil {
+		return "", err
+	}
+
+	diff := to.Sub(from)
+	result := fmt.Sprintf("From %s in %s to %s in %s is %s",
+		describeInstant(from, true), strings.TrimSpace(fromLocation),
+		describeInstant(to, true), strings.TrimSpace(toLocation), FormatDuration(diff.Abs()))
+	if diff < 0 {
+		result += " (the second time is earlier)"
+	}
+	return result, nil
+}
+
+// LeaveByWithTools plans backwards from an arrival time: it subtracts the
+// steps, taken in the order given, and says when to leave. The arrival
+// time is read in arrivalLocation and the departure shown in
+// departureLocation; both are optional and default to plain clock times,
+// and the departure location defaults to the arrival one.
+func LeaveByWithTools(arrival, durations, arrivalLocation, departureLocation string) (string, error) {
+	parts, err := ParseDurationList(durations)
+	if err != nil {
+		return "", err
+	}
+
+	arriveLoc, departLoc := time.UTC, time.UTC
+	zoned := strings.TrimSpace(arrivalLocation) != ""
+	if zoned {
+		if arriveLoc, err = loadLocation(arrivalLocation); err != nil {
+			return "", fmt.Errorf("invalid arrival location: %w", err)
+		}
+		departLoc = arriveLoc
+	}
+	if strings.TrimSpace(departureLocation) != "" {
+		if departLoc, err = loadLocation(departureLocation); err != nil {
+			return "", fmt.Errorf("invalid departure location: %w", err)
+		}
+		zoned = true
+	}
+
+	arrive, err := ParseDateTime(arrival, arriveLoc, time.Now())
+	if err != nil {
+		return "", err
+	}
+	total := sumDurations(parts)
+	leave := arrive.Add(-total).In(departLoc)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Leave at %s", describeInstant(leave, zoned))
+	if shift := describeDayShift(daysBetween(arrive, leave)); shift != "" {
+		fmt.Fprintf(&b, " (%s)", shift)
+	}
+	fmt.Fprintf(&b, " to arrive by %s, %s in total", describeInstant(arrive, zoned), FormatDuration(total))
+
+	// Show when each step starts, in the departure zone
+	if len(parts) > 1 {
+		at := leave
+		for _, p := range parts {
+			label := p.Label
+			if label == "" {
+				label = "step"
+			}
+			fmt.Fprintf(&b, "\n• %s %s: %s", at.Format("3:04 PM"), label, FormatDuration(p.Duration))
+			at = at.Add(p.Duration)
+		}
+		fmt.Fprintf(&b, "\n• %s arrive", arrive.Format("3:04 PM"))
+	}
+	return b.String(), nil
+}
+
+var (
+	// "+" or "-" between the terms of an expression
+	expressionOperator = regexp.MustCompile(`\s*([+-])\s*`)
+	// "9am New York", "17:30 in London", "22:00"
+	zonedClockPattern = regexp.MustCompile(`(?i)^(\d{1,2}(?::\d{2})?\s*(?:am|pm)?)(?:\s+(?:in\s+)?(.+))?$`)
+	// "9am New York to 5pm London", "22:00 until 6:00"
+	differencePattern = regexp.MustCompile(`(?i)^(.+?)\s+(?:to|until)\s+(.+)$`)
+)
+
+// EvaluateTimeExpression works out expressions such as "3:15 PM + 2.5h",
+// "7pm - 45 min", "1h 20m + 45m - 10m" and "9am New York to 5pm London".
+// A clock time may only come first; the result is a time if there is one
+// and a duration otherwise.
+func EvaluateTimeExpression(expr string) (string, error) {
+	expr = strings.TrimSpace(expr)
+	if m := differencePattern.FindStringSubmatch(expr); m != nil {
+		return clockDifference(m[1], m[2])
+	}
+
+	// Split into terms, remembering the sign of each
+	ops := expressionOperator.FindAllStringSubmatch(expr, -1)
+	terms := expressionOperator.Split(expr, -1)
+	if len(terms) == 0 || strings.TrimSpace(terms[0]) == "" {
+		return "", fmt.Errorf("nothing to calculate, try 3:15 PM + 2.5h or 1h 20m + 45m")
+	}
+
+	var start *time.Time
+	first := 0
+	if clock, err := parseClock(terms[0]); err == nil {
+		start = &clock
+		first = 1
+	}
+	var total time.Duration
+	var steps []string
+	for i := first; i < len(terms); i++ {
+		d, err := ParseDuration(terms[i])
+		if err != nil {
+			return "", err
+		}
+		sign := "+"
+		if i > 0 {
+			sign = ops[i-1][1]
+		}
+		if sign == "-" {
+			d = -d
+		}
+		total += d
+		if len(steps) > 0 || start != nil || sign == "-" {
+			steps = append(steps, sign+" "+FormatDuration(d.Abs()))
+		} else {
+			steps = append(steps, FormatDuration(d))
+		}
+	}
+
+	if start == nil {
+		return fmt.Sprintf("%s = %s", strings.Join(steps, " "), FormatDuration(total)), nil
+	}
+	if len(steps) == 0 {
+		return "", fmt.Errorf("nothing to add to %s, try %s + 2h", strings.TrimSpace(terms[0]), strings.TrimSpace(terms[0]))
+	}
+	// Plain clock arithmetic, wrapping around midnight
+	day := time.Date(2000, 1, 1, start.Hour(), start.Minute(), 0, 0, time.UTC)
+	end := day.Add(total)
+	shift := describeDayShift(daysBetween(day, end))
+	if shift != "" {
+		shift = " (" + shift + ")"
+	}
+	return fmt.Sprintf("%s %s = %s%s", day.Format(clockLayout), strings.Join(steps, " "), end.Format(clockLayout), shift), nil
+}
+
+// clockDifference measures from one clock time to the next time the clocks
+// show another. With locations it is the real time between them today.
+func clockDifference(fromStr, toStr string) (string, error) {
+	from := zonedClockPattern.FindStringSubmatch(strings.TrimSpace(fromStr))
+	to := zonedClockPattern.FindStringSubmatch(strings.TrimSpace(toStr))
+	if from == nil || to == nil {
+		return "", fmt.Errorf("try e.g. 9am New York to 5pm London or 22:00 to 6:00")
+	}
+	if from[2] != "" || to[2] != "" {
+		if from[2] == "" || to[2] == "" {
+			return "", fmt.Errorf("give a location for both times or neither")
+		}
+		return TimeDifferenceWithTools(from[1], from[2], to[1], to[2])
+	}
+
+	start, err := parseClock(from[1])
+	if err != nil {
+		return "", err
+	}
+	end, err := parseClock(to[1])
+	if err != nil {
+		return "", err
+	}
+	diff := end.Sub(start)
+	overnight := ""
+	if diff < 0 {
+		diff += 24 * time.Hour
+		overnight = " (overnight)"
+	}
+	return fmt.Sprintf("From %s to %s is %s%s", start.Format(clockLayout), end.Format(clockLayout), FormatDuration(diff), overnight), nil
+}
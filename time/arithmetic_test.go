@@ -0,0 +1,220 @@
+package time
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseDurationList(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    []DurationPart
+		wantErr bool
+	}{
+		{input: "2.5 hours", want: []DurationPart{{"", 150 * time.Minute}}},
+		{input: "1h30m", want: []DurationPart{{"", 90 * time.Minute}}},
+		{input: "1:45", want: []DurationPart{{"", 105 * time.Minute}}},
+		{input: "2 days 3 hrs", want: []DurationPart{{"", 51 * time.Hour}}},
+		{input: "45 min, 1 hour and 20 minutes + 90s", want: []DurationPart{
+			{"", 45 * time.Minute}, {"", 80 * time.Minute}, {"", 90 * time.Second},
+		}},
+		{input: "security 25 minutes, drive 1 hour and 10 min, breakfast takes 40m", want: []DurationPart{
+			{"security", 25 * time.Minute}, {"drive", 70 * time.Minute}, {"breakfast takes", 40 * time.Minute},
+		}},
+		{input: "coffee 20 min and commute 30 min", want: []DurationPart{
+			{"coffee", 20 * time.Minute}, {"commute", 30 * time.Minute},
+		}},
+		{input: "a while", wantErr: true},
+		{input: "1.5.5h", wantErr: true},
+		{input: "99999999999 days", wantErr: true},
+		{input: "99999999999:00", wantErr: true},
+		{input: "3650 days", want: []DurationPart{{"", 3650 * 24 * time.Hour}}},
+		{input: "3651 days", wantErr: true},
+		{input: "2000 days, 2000 days", wantErr: true},
+		{input: "1h, soon", wantErr: true},
+		{input: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseDurationList(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseDurationList(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("ParseDurationList(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("part %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{0, "0m"},
+		{45 * time.Minute, "45m"},
+		{150 * time.Minute, "2h 30m"},
+		{26*time.Hour + 5*time.Minute, "1d 2h 5m"},
+		{90 * time.Second, "1m 30s"},
+		{-45 * time.Minute, "-45m"},
+		{math.MinInt64, "-106751d 23h 47m 16s"},
+		{math.MaxInt64, "106751d 23h 47m 16s"},
+	}
+	for _, tt := range tests {
+		if got := FormatDuration(tt.d); got != tt.want {
+			t.Errorf("FormatDuration(%v) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
+
+func TestEvaluateTimeExpression(t *testing.T) {
+	tests := []struct {
+		expr    string
+		want    string
+		wantErr bool
+	}{
+		{expr: "3:15 PM + 2.5 hours", want: "3:15 PM (15:15) + 2h 30m = 5:45 PM (17:45)"},
+		{expr: "7pm - 45 min", want: "7:00 PM (19:00) - 45m = 6:15 PM (18:15)"},
+		{expr: "14:00 - 25 minutes", want: "2:00 PM (14:00) - 25m = 1:35 PM (13:35)"},
+		{expr: "11:30 PM + 2h", want: "11:30 PM (23:30) + 2h = 1:30 AM (01:30) (next day)"},
+		{expr: "1:00 AM - 3h", want: "1:00 AM (01:00) - 3h = 10:00 PM (22:00) (previous day)"},
+		{expr: "1h 20m + 45m + 2.5 hours", want: "1h 20m + 45m + 2h 30m = 4h 35m"},
+		{expr: "1h - 90m", want: "1h - 1h 30m = -30m"},
+		{expr: "22:00 to 6:00", want: "From 10:00 PM (22:00) to 6:00 AM (06:00) is 8h (overnight)"},
+		{expr: "9am to 5:30pm", want: "From 9:00 AM (09:00) to 5:30 PM (17:30) is 8h 30m"},
+		{expr: "3pm", wantErr: true},
+		{expr: "99999999999 days", wantErr: true},
+		{expr: "1h + 99999999999 days", wantErr: true},
+		{expr: "3pm + soon", wantErr: true},
+		{expr: "9am New York to 5pm", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			got, err := EvaluateTimeExpression(tt.expr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("EvaluateTimeExpression(%q) error = %v, wantErr %v", tt.expr, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("EvaluateTimeExpression(%q) = %q, want %q", tt.expr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShiftAcrossDST(t *testing.T) {
+	got, err := AddDurationWithTools("1:00 AM 2024-03-10", "2h", "New York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"Sun Mar 10, 1:00 AM (01:00) EST + 2h", "4:00 AM (04:00) EDT", "move by 3h while 2h pass"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("AddDurationWithTools() = %q, want it to contain %q", got, want)
+		}
+	}
+
+	// 9 AM EST less 9 hours is the first 1 AM, still on EDT
+	got, err = SubtractDurationWithTools("9:00 AM 2024-11-03", "9 hours", "America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"= Sun Nov 3, 1:00 AM (01:00) EDT", "move by 8h while 9h pass"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("SubtractDurationWithTools() = %q, want it to contain %q", got, want)
+		}
+	}
+}
+
+func TestTimeDifferenceWithTools(t *testing.T) {
+	got, err := TimeDifferenceWithTools("2024-06-10 6:00 PM", "New York", "2024-06-11 6:30 AM", "London")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(got, "is 7h 30m") {
+		t.Errorf("TimeDifferenceWithTools() = %q, want 7h 30m", got)
+	}
+
+	got, err = TimeDifferenceWithTools("2024-06-10 9:00", "London", "2024-06-10 9:00", "Tokyo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(got, "is 8h (the second time is earlier)") {
+		t.Errorf("TimeDifferenceWithTools() = %q", got)
+	}
+}
+
+func TestLeaveByWithTools(t *testing.T) {
+	got, err := LeaveByWithTools("7:45 AM", "breakfast 40 minutes, drive 1 hour, security 25 minutes", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "Leave at 5:40 AM (05:40) to arrive by 7:45 AM (07:45), 2h 5m in total\n" +
+		"• 5:40 AM breakfast: 40m\n" +
+		"• 6:20 AM drive: 1h\n" +
+		"• 7:20 AM security: 25m\n" +
+		"• 7:45 AM arrive"
+	if got != want {
+		t.Errorf("LeaveByWithTools() =\n%s\nwant\n%s", got, want)
+	}
+
+	got, err = LeaveByWithTools("1:00 AM", "2 hours", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(got, "Leave at 11:00 PM (23:00) (previous day)") {
+		t.Errorf("LeaveByWithTools() across midnight = %q", got)
+	}
+
+	// A flight from New York landing in London
+	got, err = LeaveByWithTools("2024-06-10 6:00 PM", "flight 7 hours", "London", "New York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(got, "Leave at Mon Jun 10, 6:00 AM (06:00) EDT to arrive by Mon Jun 10, 6:00 PM (18:00) BST") {
+		t.Errorf("LeaveByWithTools() across zones = %q", got)
+	}
+
+	if _, err := LeaveByWithTools("7:45 AM", "a while", "", ""); err == nil {
+		t.Error("expected an error without durations")
+	}
+}
+
+func TestExecuteArithmeticTools(t *testing.T) {
+	tc := NewTimeCalculator("")
+	tests := []struct {
+		name    string
+		args    []string
+		want    string
+		wantErr bool
+	}{
+		{name: "AddDuration", args: []string{"3:15 PM", "2.5 hours"}, want: "5:45 PM"},
+		{name: "SubtractDuration", args: []string{"7:00 PM", "45 minutes"}, want: "6:15 PM"},
+		{name: "SumDurations", args: []string{"1h 20m, 45 min"}, want: "= 2h 5m"},
+		{name: "LeaveBy", args: []string{"9:00 AM", "commute 30 min, coffee 20 min"}, want: "Leave at 8:10 AM"},
+		{name: "TimeDifference", args: []string{"9:00 AM", "London"}, wantErr: true},
+		{name: "SumDurations", args: nil, wantErr: true},
+		{name: "AddDuration", args: []string{"3:15 PM", "99999999999 days"}, wantErr: true},
+		{name: "SumDurations", args: []string{"99999999999 days"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tc.executeTool(tt.name, tt.args...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("executeTool(%s) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+			if !strings.Contains(got, tt.want) {
+				t.Errorf("executeTool(%s) = %q, want it to contain %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
@@ -7,36 +7,18 @@ import (
 	"io/ioutil"
 	"log"
 	"net/http"
-	"regexp"
 	"strings"
 	"time"
 )
 
-// Common city names to IANA time zone mappings
-var timeZoneMap = map[string]string{
-	"london":    "Europe/London",
-	"austin":    "America/Chicago", // Austin uses Central Time
-	"new york":  "America/New_York",
-	"tokyo":     "Asia/Tokyo",
-	"paris":     "Europe/Paris",
-	"sydney":    "Australia/Sydney",
-	"singapore": "Asia/Singapore",
-	"dubai":     "Asia/Dubai",
-	"moscow":    "Europe/Moscow",
-	"berlin":    "Europe/Berlin",
-	"nyc":       "America/New_York",
-	"la":        "America/Los_Angeles",
-	"sf":        "America/Los_Angeles",
-}
-
 // TimeZoneInfo holds information about a location's time zone
 type TimeZoneInfo struct {
 	Location       string
 	CurrentTime    time.Time
 	ZoneName       string
-	Offset         int        // offset in hours from UTC
-	IsDST          bool       // whether DST is in effect
-	NextTransition *time.Time // next DST transition, if any
+	Offset         time.Duration // offset from UTC, not always whole hours
+	IsDST          bool          // whether DST is in effect
+	NextTransition *Transition   // next offset change, if any
 }
 
 // GetDetailedTimeZoneInfo returns detailed time zone information for a location
@@ -44,28 +26,21 @@ func GetDetailedTimeZoneInfo(location string) (*TimeZoneInfo, error) {
 	// Clean up input
 	location = strings.ToLower(strings.TrimSpace(location))
 
-	// Try to find the time zone name
-	zoneName, ok := timeZoneMap[location]
-	if !ok {
-		// If not found in our map, try using the input directly
-		zoneName = location
-	}
-
-	// Load the location from the time zone database
-	loc, err := time.LoadLocation(zoneName)
+	// Resolve the name and load its time zone
+	loc, err := loadLocation(location)
 	if err != nil {
-		return nil, fmt.Errorf("unknown location %q: %v", location, err)
+		return nil, err
 	}
 
 	// Get current time in that location
 	now := time.Now().In(loc)
 
-	// Get zone name and offset
-	name, offset := now.Zone()
+	// Get zone name
+	name, _ := now.Zone()
 
 	// Get next transition (if any)
-	var nextTransition *time.Time
-	if trans, ok := getNextTransition(now, loc); ok {
+	var nextTransition *Transition
+	if trans, ok := NextTransition(loc, now); ok {
 		nextTransition = &trans
 	}
 
@@ -73,14 +48,20 @@ func GetDetailedTimeZoneInfo(location string) (*TimeZoneInfo, error) {
 		Location:       location,
 		CurrentTime:    now,
 		ZoneName:       name,
-		Offset:         offset / 3600, // Convert seconds to hours
+		Offset:         ZoneOffset(now),
 		IsDST:          now.IsDST(),
 		NextTransition: nextTransition,
 	}, nil
 }
 
-// ConvertTimeZones converts a time between two locations
+// ConvertTimeZones converts a time today between two locations
 func ConvertTimeZones(timeStr, fromLocation, toLocation string) (string, error) {
+	return ConvertTimeZonesOnDate(timeStr, "", fromLocation, toLocation)
+}
+
+// ConvertTimeZonesOnDate converts a time on a date between two locations.
+// The date is read by ParseDate relative to today in the source location.
+func ConvertTimeZonesOnDate(timeStr, dateStr, fromLocation, toLocation string) (string, error) {
 	// Get time zone info for both locations
 	fromInfo, err := GetDetailedTimeZoneInfo(fromLocation)
 	if err != nil {
@@ -92,168 +73,203 @@ func ConvertTimeZones(timeStr, fromLocation, toLocation string) (string, error)
 		return "", fmt.Errorf("invalid destination location: %v", err)
 	}
 
-	// Parse the input time
+	// Parse the input time and date
 	t, err := time.Parse("3:04 PM", timeStr)
 	if err != nil {
 		return "", fmt.Errorf("invalid time format: %v", err)
 	}
-
-	// Create a time in the source location
-	sourceTime := time.Date(
-		time.Now().Year(),
-		time.Now().Month(),
-		time.Now().Day(),
-		t.Hour(),
-		t.Minute(),
-		0, 0,
-		time.Local,
-	).In(fromInfo.CurrentTime.Location())
-
-	// Convert to destination time zone
-	destTime := sourceTime.In(toInfo.CurrentTime.Location())
-
-	// Check if it's the next/previous day
-	dayDiff := destTime.Day() - sourceTime.Day()
-	nextDay := ""
-	if dayDiff == 1 {
-		nextDay = " (next day)"
-	} else if dayDiff == -1 {
-		nextDay = " (previous day)"
+	date, err := ParseDate(dateStr, fromInfo.CurrentTime)
+	if err != nil {
+		return "", fmt.Errorf("invalid date: %v", err)
 	}
 
-	return fmt.Sprintf("%s %s (UTC%+d) → %s %s (UTC%+d)%s",
-		sourceTime.Format("3:04 PM (15:04)"),
-		fromInfo.ZoneName,
-		fromInfo.Offset,
-		destTime.Format("3:04 PM (15:04)"),
-		toInfo.ZoneName,
-		toInfo.Offset,
-		nextDay,
-	), nil
-}
-
-// ValidateLocationName checks if a location is valid and returns suggestions if not
-func ValidateLocationName(location string) (bool, []string) {
-	location = strings.ToLower(strings.TrimSpace(location))
-
-	// Check common locations first
-	if _, ok := timeZoneMap[location]; ok {
-		return true, nil
-	}
+	// Convert the time as shown by the clocks in the source location
+	conv := ConvertLocalTime(date.Year(), date.Month(), date.Day(), t.Hour(), t.Minute(),
+		fromInfo.CurrentTime.Location(), toInfo.CurrentTime.Location())
 
-	// Try loading the location directly
-	_, err := time.LoadLocation(location)
-	if err == nil {
-		return true, nil
-	}
+	return formatLocationConversion(conv) + dstNote(conv, t, date, fromLocation, formatLocationConversion), nil
+}
 
-	// If not found, look for similar locations
-	var suggestions []string
-	for loc := range timeZoneMap {
-		if strings.Contains(loc, location) || strings.Contains(location, loc) {
-			suggestions = append(suggestions, loc)
-		}
+// formatLocationConversion shows both sides of a conversion on one line
+func formatLocationConversion(conv Conversion) string {
+	shift := describeDayShift(conv.DayShift)
+	if shift != "" {
+		shift = " (" + shift + ")"
 	}
 
-	return false, suggestions
+	return fmt.Sprintf("%s %s (%s) → %s %s (%s)%s",
+		conv.From.Format("Jan 2, 3:04 PM (15:04)"),
+		zoneAbbreviation(conv.From),
+		FormatOffset(ZoneOffset(conv.From)),
+		conv.To.Format("Jan 2, 3:04 PM (15:04)"),
+		zoneAbbreviation(conv.To),
+		FormatOffset(ZoneOffset(conv.To)),
+		shift,
+	)
 }
 
-// Helper function to get the next DST transition
-func getNextTransition(t time.Time, loc *time.Location) (time.Time, bool) {
-	// Look ahead up to a year for the next transition
-	for i := 0; i < 365; i++ {
-		t = t.AddDate(0, 0, 1)
-		_, offset1 := t.Zone()
-		tomorrow := t.AddDate(0, 0, 1)
-		_, offset2 := tomorrow.Zone()
-		if offset1 != offset2 {
-			return t, true
-		}
+// ValidateLocationName checks if a location is valid and returns suggestions if not
+func ValidateLocationName(location string) (bool, []string) {
+	valid, suggestions := validateLocation(location)
+	var names []string
+	for _, s := range suggestions {
+		names = append(names, strings.ToLower(s.Place.Name))
 	}
-	return t, false
+	return valid, names
 }
 
 type OpenRouterRequest struct {
-	Model    string    `json:"model"`
-	Messages []Message `json:"messages"`
+	Model      string        `json:"model"`
+	Messages   []Message     `json:"messages"`
+	Tools      []ToolSchema  `json:"tools,omitempty"`
+	ToolChoice string        `json:"tool_choice,omitempty"`
+	Usage      *UsageOptions `json:"usage,omitempty"`
+}
+
+// UsageOptions asks OpenRouter to include token counts and cost in the response
+type UsageOptions struct {
+	Include bool `json:"include"`
 }
 
 type Message struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role       string     `json:"role"`
+	Content    string     `json:"content"`
+	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string     `json:"tool_call_id,omitempty"` // for role "tool"
+}
+
+// ToolCall is a native tool call requested by the model
+type ToolCall struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"` // JSON object
+	} `json:"function"`
 }
 
 type OpenRouterResponse struct {
 	Choices []struct {
-		Message struct {
-			Content string `json:"content"`
-		} `json:"message"`
+		Message Message `json:"message"`
 	} `json:"choices"`
+	Usage Usage `json:"usage"`
+}
+
+// Usage reports the tokens and cost (in USD credits) of one LLM call
+type Usage struct {
+	Model            string  `json:"-"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	TotalTokens      int     `json:"total_tokens"`
+	Cost             float64 `json:"cost"`
 }
 
 // TimeCalculator handles time-related calculations and queries
 type TimeCalculator struct {
 	openRouterKey string
+	baseURL       string
 	client        *http.Client
+	tools         *ToolRegistry
 }
 
+const (
+	// openRouterURL is the root of the OpenRouter API
+	openRouterURL = "https://openrouter.ai/api/v1"
+	// We'll use Claude 3.5 Sonnet for its strong reasoning capabilities
+	openRouterModel = "anthropic/claude-3.5-sonnet"
+)
+
 // NewTimeCalculator creates a new TimeCalculator instance
 func NewTimeCalculator(openRouterKey string) *TimeCalculator {
+	tools := NewToolRegistry()
+	tools.MustRegister(timeTools()...)
 	return &TimeCalculator{
 		openRouterKey: openRouterKey,
+		baseURL:       openRouterURL,
 		client:        &http.Client{},
+		tools:         tools,
 	}
 }
 
-// ProcessQuery handles time-related queries using OpenRouter
-func (tc *TimeCalculator) ProcessQuery(query string) (string, error) {
-	if tc.openRouterKey == "" {
-		log.Printf("Error: OpenRouter API key is not set")
-		return "", fmt.Errorf("OpenRouter API key is not configured")
-	}
+// Tools returns the tools offered to the assistant. Other packages may
+// register more.
+func (tc *TimeCalculator) Tools() *ToolRegistry {
+	return tc.tools
+}
 
-	// We'll use Claude 3.5 Sonnet for its strong reasoning capabilities
-	model := "anthropic/claude-3.5-sonnet"
+// WithBaseURL points the calculator at another OpenRouter compatible API
+// root, e.g. a test server
+func (tc *TimeCalculator) WithBaseURL(baseURL string) *TimeCalculator {
+	tc.baseURL = strings.TrimRight(baseURL, "/")
+	return tc
+}
 
-	systemPrompt := `You are a time calculation assistant. To perform time calculations, you MUST use the exact tool call format:
+// HasLLM reports whether queries are answered by the OpenRouter assistant
+func (tc *TimeCalculator) HasLLM() bool {
+	return tc.openRouterKey != ""
+}
 
-Tool: GetCurrentTime("location")
-Tool: ConvertTimeZones("time", "fromZone", "toZone")
-Tool: GetDetailedTimeZoneInfo("location")
-Tool: ValidateLocationName("location")
+// ProcessQuery handles time-related queries using OpenRouter. Without an
+// API key it falls back to the deterministic tools.
+func (tc *TimeCalculator) ProcessQuery(query string) (string, error) {
+	response, _, err := tc.ProcessQueryWithUsage(query)
+	return response, err
+}
 
-The tools available are:
+// QueryContext describes the user asking a query
+type QueryContext struct {
+	HomeName string // place the user lives, e.g. "Portland, Maine"
+	HomeZone string // IANA time zone of HomeName, empty if unknown
+}
 
-1. GetCurrentTime(location)
-   Input: City or location name in quotes
-   Returns: Current time, zone name, and DST status
-   Example: Tool: GetCurrentTime("New York")
+// describe tells the assistant about the user, or returns "" if nothing
+// is known
+func (qc QueryContext) describe(now time.Time) string {
+	if qc.HomeZone == "" {
+		return ""
+	}
+	loc, err := time.LoadLocation(qc.HomeZone)
+	if err != nil {
+		return ""
+	}
+	local := now.In(loc)
+	return fmt.Sprintf("User's home time zone: %s (%s), local time %s %s (%s). "+
+		"Use it when the query doesn't name a place, e.g. as the zone to convert from.",
+		qc.HomeName, qc.HomeZone, local.Format("Monday 2006-01-02 15:04"),
+		zoneAbbreviation(local), FormatOffset(ZoneOffset(local)))
+}
 
-2. ConvertTimeZones(time, fromZone, toZone)
-   Input: Time expression and location names in quotes
-   Returns: Converted time with zone details
-   Example: Tool: ConvertTimeZones("2:30 PM", "New York", "Tokyo")
+// ProcessQueryWithUsage is like ProcessQuery but also reports the LLM usage
+// of the call. The usage is zero when the deterministic tools were used.
+func (tc *TimeCalculator) ProcessQueryWithUsage(query string) (string, Usage, error) {
+	return tc.ProcessQueryWithContext(query, QueryContext{})
+}
 
-3. GetDetailedTimeZoneInfo(location)
-   Input: City or location name in quotes
-   Returns: Zone name, offset, and DST information
-   Example: Tool: GetDetailedTimeZoneInfo("London")
+// ProcessQueryWithContext is like ProcessQueryWithUsage for a user whose
+// home time zone may be known
+func (tc *TimeCalculator) ProcessQueryWithContext(query string, qc QueryContext) (string, Usage, error) {
+	if !tc.HasLLM() {
+		response, err := processQueryOffline(query, qc)
+		return response, Usage{}, err
+	}
 
-4. ValidateLocationName(location)
-   Input: City or location name in quotes
-   Returns: Whether location is valid and suggestions if not
-   Example: Tool: ValidateLocationName("NYC")
+	model := openRouterModel
+
+	systemPrompt := `You are a time calculation assistant. To perform time calculations, you MUST use the exact tool call format.
+
+` + tc.tools.Prompt() + `
 
 IMPORTANT RULES:
 1. ALWAYS use the EXACT tool call format shown above
 2. NEVER perform manual time calculations
 3. NEVER assume time zones or offsets
 4. NEVER use hardcoded example times - always use the tools
-5. Validate locations before using them
+5. Validate locations before using them; if a location is ambiguous, ask which one is meant
 6. Show both 12h and 24h time formats
 7. Include DST information when relevant
 8. For queries about current time, ALWAYS use GetCurrentTime
-9. For time conversions, ALWAYS use ConvertTimeZones
+9. For time conversions, ALWAYS use ConvertTimeZones, passing the date whenever the query mentions one
+10. For adding, subtracting or totalling durations, and for planning backwards from an arrival time, ALWAYS use the duration tools
 
 Example Usage:
 
@@ -278,66 +294,62 @@ A: Let me check both time zones.
    Tool: GetDetailedTimeZoneInfo("Paris")
    Tool: GetDetailedTimeZoneInfo("Sydney")
 
+Q: "If my flight boards at 7:45 AM, security takes 25 minutes and the drive takes 1 hour, when should I leave?"
+A: Let me plan backwards from boarding.
+   Tool: LeaveBy("7:45 AM", "drive 1 hour, security 25 minutes")
+
 For any time-related query:
 1. Always validate locations first using Tool: ValidateLocationName("location")
 2. For current time, use Tool: GetCurrentTime("location")
 3. For conversions, use Tool: ConvertTimeZones("time", "from", "to")
 4. For zone info, use Tool: GetDetailedTimeZoneInfo("location")
-5. Format responses clearly with both 12h and 24h times
-6. Include relevant DST information
-7. Show step-by-step calculations when needed`
-
-	// Extract tool calls from the response and execute them
-	toolPattern := regexp.MustCompile(`Tool: (\w+)\("([^"]+)"(?:, "([^"]+)")?(?:, "([^"]+)")?\)`)
+5. For time arithmetic, use AddDuration, SubtractDuration, SumDurations, TimeDifference or LeaveBy
+6. Format responses clearly with both 12h and 24h times
+7. Include relevant DST information
+8. Show step-by-step calculations when needed`
 
 	// Process any tool calls in the query first
-	toolCalls := toolPattern.FindAllStringSubmatch(query, -1)
-	for _, call := range toolCalls {
-		toolName := call[1]
-		args := call[2:]
-
-		// Remove empty args
-		var validArgs []string
-		for _, arg := range args {
-			if arg != "" {
-				validArgs = append(validArgs, arg)
-			}
-		}
-
-		// Execute the tool and replace the call with its result
-		result, err := tc.executeTool(toolName, validArgs...)
-		if err != nil {
-			log.Printf("Error executing tool %s: %v", toolName, err)
-			continue
-		}
-
-		// Replace the tool call with its result
-		query = strings.Replace(query, call[0], result, 1)
-	}
+	query = tc.tools.ExpandCalls(query)
 
-	// Add current time to user's query
-	queryWithTime := fmt.Sprintf("Current time: %s UTC\n\nQuery: %s",
-		time.Now().Format("15:04"),
-		query)
+	// Add current time and what we know about the user to the query
+	now := time.Now()
+	queryWithTime := fmt.Sprintf("Current time: %s UTC\n", now.UTC().Format("Monday 2006-01-02 15:04"))
+	if home := qc.describe(now); home != "" {
+		queryWithTime += home + "\n"
+	}
+	queryWithTime += "\nQuery: " + query
 
 	messages := []Message{
 		{Role: "system", Content: systemPrompt},
 		{Role: "user", Content: queryWithTime},
 	}
 
-	reqBody := OpenRouterRequest{
-		Model:    model,
-		Messages: messages,
+	openRouterResp, err := tc.complete(OpenRouterRequest{Model: model, Messages: messages})
+	if err != nil {
+		return "", Usage{}, err
 	}
 
+	// Process any tool calls in the response
+	response := tc.tools.ExpandCalls(openRouterResp.Choices[0].Message.Content)
+
+	usage := openRouterResp.Usage
+	usage.Model = model
+
+	return strings.TrimSpace(response), usage, nil
+}
+
+// complete sends a chat completion request to OpenRouter. The response
+// has at least one choice.
+func (tc *TimeCalculator) complete(reqBody OpenRouterRequest) (OpenRouterResponse, error) {
+	reqBody.Usage = &UsageOptions{Include: true}
 	jsonBody, err := json.Marshal(reqBody)
 	if err != nil {
-		return "", fmt.Errorf("error marshaling request: %v", err)
+		return OpenRouterResponse{}, fmt.Errorf("error marshaling request: %v", err)
 	}
 
-	req, err := http.NewRequest("POST", "https://openrouter.ai/api/v1/chat/completions", bytes.NewBuffer(jsonBody))
+	req, err := http.NewRequest("POST", tc.baseURL+"/chat/completions", bytes.NewBuffer(jsonBody))
 	if err != nil {
-		return "", fmt.Errorf("error creating request: %v", err)
+		return OpenRouterResponse{}, fmt.Errorf("error creating request: %v", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
@@ -348,111 +360,37 @@ For any time-related query:
 	resp, err := tc.client.Do(req)
 	if err != nil {
 		log.Printf("Error making request to OpenRouter: %v", err)
-		return "", fmt.Errorf("error making request: %v", err)
+		return OpenRouterResponse{}, fmt.Errorf("error making request: %v", err)
 	}
 	defer resp.Body.Close()
 
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
 		log.Printf("Error reading response body: %v", err)
-		return "", fmt.Errorf("error reading response: %v", err)
+		return OpenRouterResponse{}, fmt.Errorf("error reading response: %v", err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
 		log.Printf("OpenRouter API error: Status %d, Body: %s", resp.StatusCode, string(body))
 		log.Printf("Request URL: %s", req.URL.String())
-		log.Printf("Request Model: %s", model)
-		return "", fmt.Errorf("OpenRouter API error: %d %s - %s", resp.StatusCode, resp.Status, string(body))
+		log.Printf("Request Model: %s", reqBody.Model)
+		return OpenRouterResponse{}, fmt.Errorf("OpenRouter API error: %d %s - %s", resp.StatusCode, resp.Status, string(body))
 	}
 
 	var openRouterResp OpenRouterResponse
 	if err := json.Unmarshal(body, &openRouterResp); err != nil {
 		log.Printf("Error decoding response: %v, Body: %s", err, string(body))
-		return "", fmt.Errorf("error decoding response: %v", err)
+		return OpenRouterResponse{}, fmt.Errorf("error decoding response: %v", err)
 	}
 
 	if len(openRouterResp.Choices) == 0 {
 		log.Printf("No choices in response. Full response: %s", string(body))
-		return "", fmt.Errorf("no response from OpenRouter")
+		return OpenRouterResponse{}, fmt.Errorf("no response from OpenRouter")
 	}
-
-	response := openRouterResp.Choices[0].Message.Content
-
-	// Process any tool calls in the response
-	toolCalls = toolPattern.FindAllStringSubmatch(response, -1)
-	for _, call := range toolCalls {
-		toolName := call[1]
-		args := call[2:]
-
-		// Remove empty args
-		var validArgs []string
-		for _, arg := range args {
-			if arg != "" {
-				validArgs = append(validArgs, arg)
-			}
-		}
-
-		// Execute the tool and replace the call with its result
-		result, err := tc.executeTool(toolName, validArgs...)
-		if err != nil {
-			log.Printf("Error executing tool %s: %v", toolName, err)
-			continue
-		}
-
-		// Replace the tool call with its result
-		response = strings.Replace(response, call[0], result, 1)
-	}
-
-	return strings.TrimSpace(response), nil
+	return openRouterResp, nil
 }
 
 // executeTool executes a tool function with the given arguments
 func (tc *TimeCalculator) executeTool(name string, args ...string) (string, error) {
-	switch name {
-	case "GetCurrentTime":
-		if len(args) != 1 {
-			return "", fmt.Errorf("GetCurrentTime requires exactly 1 argument")
-		}
-		result, err := GetCurrentTimeWithTools(args[0])
-		if err != nil {
-			return "", err
-		}
-		return result, nil
-
-	case "ConvertTimeZones":
-		if len(args) != 3 {
-			return "", fmt.Errorf("ConvertTimeZones requires exactly 3 arguments")
-		}
-		result, err := ConvertTimeZonesWithTools(args[0], args[1], args[2])
-		if err != nil {
-			return "", err
-		}
-		return result, nil
-
-	case "GetDetailedTimeZoneInfo":
-		if len(args) != 1 {
-			return "", fmt.Errorf("GetDetailedTimeZoneInfo requires exactly 1 argument")
-		}
-		result, err := GetDetailedTimeZoneInfoWithTools(args[0])
-		if err != nil {
-			return "", err
-		}
-		return result, nil
-
-	case "ValidateLocationName":
-		if len(args) != 1 {
-			return "", fmt.Errorf("ValidateLocationName requires exactly 1 argument")
-		}
-		valid, suggestions := ValidateLocationNameWithTools(args[0])
-		if valid {
-			return "true", nil
-		}
-		if len(suggestions) > 0 {
-			return fmt.Sprintf("false, suggestions: %s", strings.Join(suggestions, ", ")), nil
-		}
-		return "false", nil
-
-	default:
-		return "", fmt.Errorf("unknown tool: %s", name)
-	}
+	return tc.tools.Execute(name, args...)
 }
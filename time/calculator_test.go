@@ -184,10 +184,18 @@ func TestValidateLocationNameWithTools(t *testing.T) {
 			wantSuggCount: 0,
 		},
 		{
-			name:          "Invalid with suggestions",
+			// The city dataset knows York, England, so "york" is no longer
+			// a misspelling of New York
+			name:          "Smaller city",
 			location:      "york",
+			wantValid:     true,
+			wantSuggCount: 0,
+		},
+		{
+			name:          "Invalid with suggestions",
+			location:      "new yor",
 			wantValid:     false,
-			wantSuggCount: 1, // Should suggest "New York"
+			wantSuggCount: 1, // Should suggest "New York City"
 		},
 		{
 			name:          "Completely invalid",
@@ -208,6 +216,10 @@ func TestValidateLocationNameWithTools(t *testing.T) {
 			}
 		})
 	}
+
+	if p, err := ResolveLocation("york"); err != nil || p.Name != "York" || p.Country != "GB" || p.Zone != "Europe/London" {
+		t.Errorf("ResolveLocation(york) = %v, %v; want York, GB in Europe/London", p, err)
+	}
 }
 
 func TestIsDSTForLocation(t *testing.T) {
@@ -249,27 +261,27 @@ func TestGetNextDSTTransition(t *testing.T) {
 	}
 
 	tests := []struct {
-		name     string
-		time     time.Time
-		wantNull bool
+		name string
+		time time.Time
+		want time.Time
 	}{
 		{
-			name:     "Before spring transition",
-			time:     time.Date(2024, 3, 1, 12, 0, 0, 0, loc),
-			wantNull: false,
+			name: "Before spring transition",
+			time: time.Date(2024, 3, 1, 12, 0, 0, 0, loc),
+			want: time.Date(2024, 3, 10, 7, 0, 0, 0, time.UTC),
 		},
 		{
-			name:     "Before fall transition",
-			time:     time.Date(2024, 11, 1, 12, 0, 0, 0, loc),
-			wantNull: false,
+			name: "Before fall transition",
+			time: time.Date(2024, 11, 1, 12, 0, 0, 0, loc),
+			want: time.Date(2024, 11, 3, 6, 0, 0, 0, time.UTC),
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := getNextDSTTransition(tt.time, loc)
-			if (got == nil) != tt.wantNull {
-				t.Errorf("getNextDSTTransition() returned nil = %v, want %v", got == nil, tt.wantNull)
+			got, ok := NextTransition(loc, tt.time)
+			if !ok || !got.At.Equal(tt.want) {
+				t.Errorf("NextTransition() = %v, %v, want %v", got.At, ok, tt.want)
 			}
 		})
 	}
@@ -0,0 +1,64 @@
+package time
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// maxToolRounds limits how often the model may call tools before it has
+// to answer
+const maxToolRounds = 6
+
+// ChatWithTools answers a message with the OpenRouter assistant, which may
+// call the tools in the registry through native tool calling. The usage
+// covers every request made.
+func (tc *TimeCalculator) ChatWithTools(systemPrompt, message string, tools *ToolRegistry) (string, Usage, error) {
+	if !tc.HasLLM() {
+		return "", Usage{}, fmt.Errorf("no OpenRouter API key configured")
+	}
+
+	messages := []Message{
+		{Role: "system", Content: systemPrompt},
+		{Role: "user", Content: message},
+	}
+	usage := Usage{Model: openRouterModel}
+	for round := 0; ; round++ {
+		req := OpenRouterRequest{Model: openRouterModel, Messages: messages, Tools: tools.Schemas()}
+		if round == maxToolRounds {
+			// Out of rounds, make the model answer with what it has
+			req.ToolChoice = "none"
+		}
+		resp, err := tc.complete(req)
+		if err != nil {
+			return "", usage, err
+		}
+		usage.add(resp.Usage)
+
+		reply := resp.Choices[0].Message
+		if len(reply.ToolCalls) == 0 || round == maxToolRounds {
+			if strings.TrimSpace(reply.Content) == "" {
+				return "", usage, fmt.Errorf("empty response from OpenRouter")
+			}
+			return strings.TrimSpace(reply.Content), usage, nil
+		}
+
+		messages = append(messages, Message{Role: "assistant", Content: reply.Content, ToolCalls: reply.ToolCalls})
+		for _, call := range reply.ToolCalls {
+			result, err := tools.ExecuteJSON(call.Function.Name, call.Function.Arguments)
+			if err != nil {
+				log.Printf("Error executing tool %s: %v", call.Function.Name, err)
+				result = "Error: " + err.Error()
+			}
+			messages = append(messages, Message{Role: "tool", ToolCallID: call.ID, Content: result})
+		}
+	}
+}
+
+// add accumulates the usage of another request
+func (u *Usage) add(other Usage) {
+	u.PromptTokens += other.PromptTokens
+	u.CompletionTokens += other.CompletionTokens
+	u.TotalTokens += other.TotalTokens
+	u.Cost += other.Cost
+}
@@ -0,0 +1,125 @@
+package time
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// fakeOpenRouter answers chat completions with reply, which sees the
+// request and its index
+func fakeOpenRouter(t *testing.T, reply func(n int, req OpenRouterRequest) Message) *httptest.Server {
+	n := 0
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/chat/completions" || r.Header.Get("Authorization") != "Bearer key" {
+			t.Errorf("unexpected request %s with %q", r.URL.Path, r.Header.Get("Authorization"))
+		}
+		var req OpenRouterRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatal(err)
+		}
+		var resp OpenRouterResponse
+		resp.Choices = append(resp.Choices, struct {
+			Message Message `json:"message"`
+		}{reply(n, req)})
+		resp.Usage = Usage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15, Cost: 0.001}
+		n++
+		json.NewEncoder(w).Encode(resp)
+	}))
+}
+
+func toolCall(id, name, args string) ToolCall {
+	var c ToolCall
+	c.ID, c.Type = id, "function"
+	c.Function.Name, c.Function.Arguments = name, args
+	return c
+}
+
+func TestChatWithTools(t *testing.T) {
+	server := fakeOpenRouter(t, func(n int, req OpenRouterRequest) Message {
+		switch n {
+		case 0:
+			if len(req.Tools) != 1 || req.Tools[0].Function.Name != "Repeat" {
+				t.Errorf("request tools = %+v", req.Tools)
+			}
+			if req.Messages[0].Role != "system" || req.Messages[1].Content != "say hi" {
+				t.Errorf("request messages = %+v", req.Messages)
+			}
+			return Message{Role: "assistant", ToolCalls: []ToolCall{
+				toolCall("a", "Repeat", `{"word": "hi", "times": 3}`),
+				toolCall("b", "Repeat", `{"times": 3}`),
+			}}
+		default:
+			tool := req.Messages[len(req.Messages)-2:]
+			if tool[0].ToolCallID != "a" || tool[0].Content != "hi hi hi" {
+				t.Errorf("first tool result = %+v", tool[0])
+			}
+			if tool[1].ToolCallID != "b" || !strings.HasPrefix(tool[1].Content, "Error: ") {
+				t.Errorf("second tool result = %+v", tool[1])
+			}
+			return Message{Role: "assistant", Content: " Hi three times. "}
+		}
+	})
+	defer server.Close()
+
+	tools := NewToolRegistry()
+	tools.MustRegister(repeatTool())
+	tc := NewTimeCalculator("key").WithBaseURL(server.URL + "/")
+	got, usage, err := tc.ChatWithTools("be nice", "say hi", tools)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "Hi three times." {
+		t.Errorf("ChatWithTools() = %q", got)
+	}
+	if usage.TotalTokens != 30 || usage.Model != openRouterModel {
+		t.Errorf("usage = %+v, want the total of two requests", usage)
+	}
+}
+
+func TestChatWithToolsRoundLimit(t *testing.T) {
+	requests := 0
+	server := fakeOpenRouter(t, func(n int, req OpenRouterRequest) Message {
+		requests++
+		if req.ToolChoice == "none" {
+			return Message{Role: "assistant", Content: "best effort"}
+		}
+		return Message{Role: "assistant", ToolCalls: []ToolCall{toolCall(fmt.Sprint(n), "Repeat", `{"word": "again"}`)}}
+	})
+	defer server.Close()
+
+	tools := NewToolRegistry()
+	tools.MustRegister(repeatTool())
+	got, _, err := NewTimeCalculator("key").WithBaseURL(server.URL).ChatWithTools("", "loop", tools)
+	if err != nil || got != "best effort" {
+		t.Errorf("ChatWithTools() = %q, %v", got, err)
+	}
+	if requests != maxToolRounds+1 {
+		t.Errorf("made %d requests, want %d", requests, maxToolRounds+1)
+	}
+
+	if _, _, err := NewTimeCalculator("").ChatWithTools("", "hi", tools); err == nil {
+		t.Error("expected an error without an API key")
+	}
+}
+
+func TestProcessQueryExpandsToolCalls(t *testing.T) {
+	server := fakeOpenRouter(t, func(n int, req OpenRouterRequest) Message {
+		if len(req.Tools) != 0 || !strings.Contains(req.Messages[0].Content, `Tool: SumDurations("durations")`) {
+			t.Errorf("unexpected request %+v", req)
+		}
+		return Message{Role: "assistant", Content: `Total: Tool: SumDurations("1h, 45m")`}
+	})
+	defer server.Close()
+
+	got, usage, err := NewTimeCalculator("key").WithBaseURL(server.URL).ProcessQueryWithUsage("1h plus 45m?")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(got, "Total: ") || !strings.HasSuffix(got, "= 1h 45m") || usage.TotalTokens != 15 {
+		t.Errorf("ProcessQueryWithUsage() = %q, %+v", got, usage)
+	}
+}
@@ -0,0 +1,220 @@
+package time
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LocalTimeStatus says how a wall-clock time maps to instants in a zone
+type LocalTimeStatus int
+
+const (
+	LocalTimeNormal   LocalTimeStatus = iota // happens exactly once
+	LocalTimeSkipped                         // falls in a gap when clocks move forward
+	LocalTimeRepeated                        // happens twice when clocks move back
+)
+
+// Conversion is a wall-clock time in one zone and the same instant in
+// another
+type Conversion struct {
+	From     time.Time // the instant in the source zone
+	To       time.Time // the same instant in the target zone
+	Status   LocalTimeStatus
+	Later    *Conversion // the second occurrence of a repeated time
+	DayShift int         // calendar days from From's date to To's date
+}
+
+// ResolveLocalTime returns the instants at which the clocks in loc show
+// the given date and time, earliest first. A skipped time has none, so
+// it is moved forward by the length of the gap the way clocks are, e.g.
+// 2:30 AM on the day New York springs forward becomes 3:30 AM EDT.
+func ResolveLocalTime(year int, month time.Month, day, hour, min int, loc *time.Location) ([]time.Time, LocalTimeStatus) {
+	wall := time.Date(year, month, day, hour, min, 0, 0, time.UTC)
+
+	// Zones change their offset at most a few times a year, so the offsets
+	// a day either side cover every reading of the wall time
+	var matches []time.Time
+	seen := make(map[time.Duration]bool)
+	for _, probe := range []time.Time{wall.Add(-24 * time.Hour), wall, wall.Add(24 * time.Hour)} {
+		offset := ZoneOffset(probe.In(loc))
+		if seen[offset] {
+			continue
+		}
+		seen[offset] = true
+		t := wall.Add(-offset).In(loc)
+		if sameWallTime(t, wall) {
+			matches = append(matches, t)
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i].Before(matches[j]) })
+
+	switch len(matches) {
+	case 0:
+		before := ZoneOffset(wall.Add(-24 * time.Hour).In(loc))
+		return []time.Time{wall.Add(-before).In(loc)}, LocalTimeSkipped
+	case 1:
+		return matches, LocalTimeNormal
+	default:
+		return matches, LocalTimeRepeated
+	}
+}
+
+func sameWallTime(t, wall time.Time) bool {
+	y, m, d := t.Date()
+	wy, wm, wd := wall.Date()
+	return y == wy && m == wm && d == wd && t.Hour() == wall.Hour() && t.Minute() == wall.Minute()
+}
+
+// ConvertLocalTime converts the date and time shown by the clocks in from
+// to the time in to. A repeated time is converted at its first occurrence,
+// with the second one in Later.
+func ConvertLocalTime(year int, month time.Month, day, hour, min int, from, to *time.Location) Conversion {
+	instants, status := ResolveLocalTime(year, month, day, hour, min, from)
+	conv := newConversion(instants[0], to, status)
+	if status == LocalTimeRepeated {
+		later := newConversion(instants[1], to, status)
+		conv.Later = &later
+	}
+	return conv
+}
+
+func newConversion(t time.Time, to *time.Location, status LocalTimeStatus) Conversion {
+	target := t.In(to)
+	return Conversion{
+		From:     t,
+		To:       target,
+		Status:   status,
+		DayShift: daysBetween(t, target),
+	}
+}
+
+// daysBetween counts calendar days from a's date to b's date, each in its
+// own zone, so it works across month and year ends
+func daysBetween(a, b time.Time) int {
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+	da := time.Date(ay, am, ad, 0, 0, 0, 0, time.UTC)
+	db := time.Date(by, bm, bd, 0, 0, 0, 0, time.UTC)
+	return int(db.Sub(da) / (24 * time.Hour))
+}
+
+// describeDayShift returns "next day", "2 days earlier" and so on, or ""
+// for the same day
+func describeDayShift(days int) string {
+	switch {
+	case days == 0:
+		return ""
+	case days == 1:
+		return "next day"
+	case days == -1:
+		return "previous day"
+	case days > 0:
+		return fmt.Sprintf("%d days later", days)
+	default:
+		return fmt.Sprintf("%d days earlier", -days)
+	}
+}
+
+var (
+	// "in 3 days", "in 1 day"
+	relativeDaysPattern = regexp.MustCompile(`^in (\d+) days?$`)
+	// Day-first dates, e.g. "10 March" or "10 March 2025"
+	dayFirstPattern = regexp.MustCompile(`^(\d{1,2})(?:st|nd|rd|th)? ([a-z]+)(?: (\d{4}))?$`)
+	// Month-first dates, e.g. "March 10" or "Mar 10th, 2025"
+	monthFirstPattern = regexp.MustCompile(`^([a-z]+) (\d{1,2})(?:st|nd|rd|th)?(?:,? (\d{4}))?$`)
+)
+
+// ParseDate reads a date such as "2025-03-10", "March 10", "10 Mar 2025",
+// "tomorrow", "in 3 days" or "friday" relative to today. An empty string
+// means today. Dates without a year are in today's year; weekdays are the
+// next such day, or today if it is one unless it says "next".
+func ParseDate(s string, today time.Time) (time.Time, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	s = strings.TrimPrefix(s, "on ")
+	y, m, d := today.Date()
+	date := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 0, 0, 0, 0, today.Location())
+	}
+
+	switch s {
+	case "", "today":
+		return date(y, m, d), nil
+	case "tomorrow":
+		return date(y, m, d+1), nil
+	case "yesterday":
+		return date(y, m, d-1), nil
+	}
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return date(t.Date()), nil
+	}
+	if match := relativeDaysPattern.FindStringSubmatch(s); match != nil {
+		n, _ := strconv.Atoi(match[1])
+		return date(y, m, d+n), nil
+	}
+	weekday := strings.TrimPrefix(s, "next ")
+	for wd := time.Sunday; wd <= time.Saturday; wd++ {
+		name := strings.ToLower(wd.String())
+		if weekday == name || weekday == name[:3] {
+			ahead := (int(wd) - int(today.Weekday()) + 7) % 7
+			if ahead == 0 && weekday != s {
+				ahead = 7 // "next friday" on a Friday
+			}
+			return date(y, m, d+ahead), nil
+		}
+	}
+
+	var monthName, dayStr, yearStr string
+	if match := dayFirstPattern.FindStringSubmatch(s); match != nil {
+		dayStr, monthName, yearStr = match[1], match[2], match[3]
+	} else if match := monthFirstPattern.FindStringSubmatch(s); match != nil {
+		monthName, dayStr, yearStr = match[1], match[2], match[3]
+	} else {
+		return time.Time{}, fmt.Errorf("unknown date %q, try 2025-03-10, March 10 or tomorrow", s)
+	}
+	month, ok := parseMonth(monthName)
+	if !ok {
+		return time.Time{}, fmt.Errorf("unknown month %q", monthName)
+	}
+	day, _ := strconv.Atoi(dayStr)
+	year := y
+	if yearStr != "" {
+		year, _ = strconv.Atoi(yearStr)
+	}
+	if day < 1 || day > daysIn(month, year) {
+		return time.Time{}, fmt.Errorf("%s has no day %d", month, day)
+	}
+	return date(year, month, day), nil
+}
+
+// parseMonth accepts full month names and three-letter abbreviations
+func parseMonth(name string) (time.Month, bool) {
+	for m := time.January; m <= time.December; m++ {
+		full := strings.ToLower(m.String())
+		if name == full || len(name) >= 3 && strings.HasPrefix(full, name) {
+			return m, true
+		}
+	}
+	return 0, false
+}
+
+func daysIn(month time.Month, year int) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+// parseClockTime reads "2:30 PM" or "14:30"
+func parseClockTime(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	s = strings.TrimSuffix(s, " UTC") // Remove UTC suffix if present
+	t, err := time.Parse("3:04 PM", s)
+	if err != nil {
+		t, err = time.Parse("15:04", s)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid time format: please use either 12-hour (e.g., 2:00 PM) or 24-hour (e.g., 14:00) format")
+		}
+	}
+	return t, nil
+}
@@ -0,0 +1,247 @@
+package time
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func mustLoad(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loc
+}
+
+func TestResolveLocalTime(t *testing.T) {
+	tests := []struct {
+		name       string
+		zone       string
+		date       time.Time // wall time, read in zone
+		wantStatus LocalTimeStatus
+		wantUTC    []time.Time
+	}{
+		{
+			name:       "Ordinary time",
+			zone:       "America/New_York",
+			date:       time.Date(2024, 3, 10, 15, 0, 0, 0, time.UTC),
+			wantStatus: LocalTimeNormal,
+			wantUTC:    []time.Time{time.Date(2024, 3, 10, 19, 0, 0, 0, time.UTC)},
+		},
+		{
+			name:       "Spring forward gap",
+			zone:       "America/New_York",
+			date:       time.Date(2024, 3, 10, 2, 30, 0, 0, time.UTC),
+			wantStatus: LocalTimeSkipped,
+			wantUTC:    []time.Time{time.Date(2024, 3, 10, 7, 30, 0, 0, time.UTC)},
+		},
+		{
+			name:       "Fall back overlap",
+			zone:       "America/New_York",
+			date:       time.Date(2024, 11, 3, 1, 30, 0, 0, time.UTC),
+			wantStatus: LocalTimeRepeated,
+			wantUTC: []time.Time{
+				time.Date(2024, 11, 3, 5, 30, 0, 0, time.UTC),
+				time.Date(2024, 11, 3, 6, 30, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:       "Half-hour overlap on Lord Howe Island",
+			zone:       "Australia/Lord_Howe",
+			date:       time.Date(2024, 4, 7, 1, 45, 0, 0, time.UTC),
+			wantStatus: LocalTimeRepeated,
+			wantUTC: []time.Time{
+				time.Date(2024, 4, 6, 14, 45, 0, 0, time.UTC),
+				time.Date(2024, 4, 6, 15, 15, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:       "Southern hemisphere gap",
+			zone:       "Australia/Sydney",
+			date:       time.Date(2024, 10, 6, 2, 15, 0, 0, time.UTC),
+			wantStatus: LocalTimeSkipped,
+			wantUTC:    []time.Time{time.Date(2024, 10, 5, 16, 15, 0, 0, time.UTC)},
+		},
+		{
+			// Samoa skipped December 30, 2011 entirely
+			name:       "Skipped day in Samoa",
+			zone:       "Pacific/Apia",
+			date:       time.Date(2011, 12, 30, 12, 0, 0, 0, time.UTC),
+			wantStatus: LocalTimeSkipped,
+			wantUTC:    []time.Time{time.Date(2011, 12, 30, 22, 0, 0, 0, time.UTC)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loc := mustLoad(t, tt.zone)
+			got, status := ResolveLocalTime(tt.date.Year(), tt.date.Month(), tt.date.Day(),
+				tt.date.Hour(), tt.date.Minute(), loc)
+			if status != tt.wantStatus {
+				t.Errorf("status = %v, want %v", status, tt.wantStatus)
+			}
+			if len(got) != len(tt.wantUTC) {
+				t.Fatalf("got %v, want %v", got, tt.wantUTC)
+			}
+			for i := range got {
+				if !got[i].Equal(tt.wantUTC[i]) {
+					t.Errorf("instant %d = %v, want %v", i, got[i].UTC(), tt.wantUTC[i])
+				}
+			}
+		})
+	}
+}
+
+func TestConvertLocalTimeDayShift(t *testing.T) {
+	tests := []struct {
+		name     string
+		from, to string
+		wall     time.Time
+		want     time.Time // wall time in to
+		wantDays int
+	}{
+		{"Across a year end", "America/New_York", "Asia/Tokyo",
+			time.Date(2024, 12, 31, 20, 0, 0, 0, time.UTC), time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC), 1},
+		{"Back across a month end", "Asia/Tokyo", "America/Los_Angeles",
+			time.Date(2024, 3, 1, 8, 0, 0, 0, time.UTC), time.Date(2024, 2, 29, 15, 0, 0, 0, time.UTC), -1},
+		{"Two days apart", "Pacific/Kiritimati", "Pacific/Pago_Pago",
+			time.Date(2025, 1, 1, 0, 30, 0, 0, time.UTC), time.Date(2024, 12, 30, 23, 30, 0, 0, time.UTC), -2},
+		{"Same day", "Europe/London", "Europe/Paris",
+			time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC), time.Date(2024, 6, 1, 13, 0, 0, 0, time.UTC), 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conv := ConvertLocalTime(tt.wall.Year(), tt.wall.Month(), tt.wall.Day(),
+				tt.wall.Hour(), tt.wall.Minute(), mustLoad(t, tt.from), mustLoad(t, tt.to))
+			if !sameWallTime(conv.To, tt.want) {
+				t.Errorf("To = %v, want %v", conv.To, tt.want.Format("2006-01-02 15:04"))
+			}
+			if conv.DayShift != tt.wantDays {
+				t.Errorf("DayShift = %d, want %d", conv.DayShift, tt.wantDays)
+			}
+		})
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	// A Wednesday
+	today := time.Date(2025, 1, 15, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{input: "", want: "2025-01-15"},
+		{input: "today", want: "2025-01-15"},
+		{input: "tomorrow", want: "2025-01-16"},
+		{input: "yesterday", want: "2025-01-14"},
+		{input: "in 20 days", want: "2025-02-04"},
+		{input: "2024-03-10", want: "2024-03-10"},
+		{input: "March 10", want: "2025-03-10"},
+		{input: "on Mar 10th, 2026", want: "2026-03-10"},
+		{input: "10 March 2024", want: "2024-03-10"},
+		{input: "friday", want: "2025-01-17"},
+		{input: "wed", want: "2025-01-15"},
+		{input: "next wednesday", want: "2025-01-22"},
+		{input: "February 29", wantErr: true},
+		{input: "Smarch 3", wantErr: true},
+		{input: "someday", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseDate(tt.input, today)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseDate(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if !tt.wantErr && got.Format("2006-01-02") != tt.want {
+				t.Errorf("ParseDate(%q) = %s, want %s", tt.input, got.Format("2006-01-02"), tt.want)
+			}
+		})
+	}
+}
+
+func TestConvertTimeZonesOnDateWithTools(t *testing.T) {
+	tests := []struct {
+		name     string
+		timeStr  string
+		date     string
+		contains []string
+	}{
+		{
+			name:     "Date before DST starts in New York only",
+			timeStr:  "3:00 PM",
+			date:     "2024-03-10",
+			contains: []string{"Sun Mar 10 2024, 3:00 PM", "UTC-04:00", "7:00 PM", "UTC+00:00"},
+		},
+		{
+			name:     "Skipped time",
+			timeStr:  "2:30 AM",
+			date:     "2024-03-10",
+			contains: []string{"3:30 AM (03:30)", "doesn't exist", "3:30 AM EDT is used"},
+		},
+		{
+			name:     "Repeated time",
+			timeStr:  "1:30 AM",
+			date:     "2024-11-03",
+			contains: []string{"5:30 AM (05:30)", "happens twice", "6:30 AM (06:30)"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ConvertTimeZonesOnDateWithTools(tt.timeStr, tt.date, "America/New_York", "Europe/London")
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range tt.contains {
+				if !strings.Contains(got, want) {
+					t.Errorf("got %q, want it to contain %q", got, want)
+				}
+			}
+		})
+	}
+
+	if _, err := ConvertTimeZonesOnDateWithTools("3:00 PM", "someday", "America/New_York", "Europe/London"); err == nil {
+		t.Error("expected an error for an unknown date")
+	}
+}
+
+func TestConvertTimeZonesOnDate(t *testing.T) {
+	tests := []struct {
+		name     string
+		timeStr  string
+		date     string
+		contains []string
+	}{
+		{
+			name:     "Skipped time",
+			timeStr:  "2:30 AM",
+			date:     "2025-03-09",
+			contains: []string{"Mar 9, 3:30 AM (03:30) EDT", "doesn't exist in New York", "3:30 AM EDT is used"},
+		},
+		{
+			name:     "Repeated time",
+			timeStr:  "1:30 AM",
+			date:     "2025-11-02",
+			contains: []string{"Nov 2, 1:30 AM (01:30) EDT", "happens twice in New York", "Nov 2, 1:30 AM (01:30) EST"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ConvertTimeZonesOnDate(tt.timeStr, tt.date, "New York", "London")
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range tt.contains {
+				if !strings.Contains(got, want) {
+					t.Errorf("got %q, want it to contain %q", got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package time
+
+import (
+	"sort"
+	"strings"
+)
+
+// Suggestion is a place offered for a name that didn't resolve
+type Suggestion struct {
+	Place Place
+	Score float64 // confidence between 0 and 1
+}
+
+// Fuzzy matching thresholds
+const (
+	minSuggestionScore = 0.65 // weaker matches are not worth offering
+	phoneticBonus      = 0.1  // added when the names sound alike
+	zoneMatchPenalty   = 0.9  // prefer "New York City" over America/New_York
+)
+
+// Suggest returns up to limit places whose names are close to the query,
+// best first and at most one per time zone. Names are compared by
+// Damerau–Levenshtein distance, with a bonus for names that sound alike
+// and for names the query is the beginning of.
+func (r *Resolver) Suggest(query string, limit int) []Suggestion {
+	term := normalizeName(query)
+	if term == "" || limit <= 0 {
+		return nil
+	}
+	key := phoneticKey(term)
+
+	var matches []Suggestion
+	consider := func(name string, place Place, penalty float64) {
+		if score := similarity(term, key, name) * penalty; score >= minSuggestionScore {
+			matches = append(matches, Suggestion{Place: place, Score: score})
+		}
+	}
+	for name, cities := range r.cities {
+		consider(name, pickCities(cities)[0], 1)
+	}
+	for name, countries := range r.countries {
+		if len(name) > 2 {
+			consider(name, countries[0], 1)
+		}
+	}
+	for _, zone := range r.zones {
+		place := Place{Name: zone, Zone: zone, Kind: KindZone}
+		if strings.Contains(term, "/") {
+			consider(normalizeName(zone), place, 1)
+		} else if i := strings.LastIndex(zone, "/"); i >= 0 {
+			consider(normalizeName(zone[i+1:]), place, zoneMatchPenalty)
+		}
+	}
+
+	// Keep the best match per zone, preferring named places to zone IDs
+	best := make(map[string]Suggestion)
+	for _, m := range matches {
+		prev, ok := best[m.Place.Zone]
+		named, prevNamed := m.Place.Kind != KindZone, prev.Place.Kind != KindZone
+		if !ok || named && !prevNamed || named == prevNamed && betterSuggestion(m, prev) {
+			best[m.Place.Zone] = m
+		}
+	}
+	suggestions := make([]Suggestion, 0, len(best))
+	for _, m := range best {
+		suggestions = append(suggestions, m)
+	}
+	sort.Slice(suggestions, func(i, j int) bool {
+		return betterSuggestion(suggestions[i], suggestions[j])
+	})
+	if len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+	return suggestions
+}
+
+// betterSuggestion orders suggestions by score, then by prominence
+func betterSuggestion(a, b Suggestion) bool {
+	if a.Score != b.Score {
+		return a.Score > b.Score
+	}
+	if a.Place.served != b.Place.served || a.Place.rank != b.Place.rank {
+		return moreProminent(a.Place, b.Place)
+	}
+	return a.Place.Name < b.Place.Name
+}
+
+// similarity scores how well a normalized name matches the query term
+func similarity(term, termKey, name string) float64 {
+	a, b := []rune(term), []rune(name)
+	longest := max(len(a), len(b))
+	prefix := len(a) >= 3 && strings.HasPrefix(name, term)
+	// Names of very different length can't score high enough
+	if !prefix && abs(len(a)-len(b))*2 > longest {
+		return 0
+	}
+
+	score := 1 - float64(damerauLevenshtein(a, b))/float64(longest)
+	if prefix {
+		// Partly typed names, e.g. "new yor"
+		score = max(score, 0.6+0.4*float64(len(a))/float64(len(b)))
+	}
+	if score > 0 && phoneticKey(name) == termKey {
+		score = min(score+phoneticBonus, 1)
+	}
+	return score
+}
+
+// damerauLevenshtein counts the insertions, deletions, substitutions and
+// transpositions of adjacent letters that turn a into b (the optimal
+// string alignment variant)
+func damerauLevenshtein(a, b []rune) int {
+	// Three rows of the distance matrix are enough
+	prev2 := make([]int, len(b)+1)
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return prev[len(b)]
+}
+
+// soundexCodes groups consonants that sound alike
+var soundexCodes = map[rune]byte{
+	'b': '1', 'f': '1', 'p': '1', 'v': '1',
+	'c': '2', 'g': '2', 'j': '2', 'k': '2', 'q': '2', 's': '2', 'x': '2', 'z': '2',
+	'd': '3', 't': '3',
+	'l': '4',
+	'm': '5', 'n': '5',
+	'r': '6',
+}
+
+// phoneticKey returns the Soundex code of each word of a normalized name,
+// so "Tokio" and "Tokyo" or "Sidney" and "Sydney" share a key. Unlike
+// plain Soundex a leading consonant is coded too, so "Kairo" sounds like
+// "Cairo".
+func phoneticKey(name string) string {
+	var words []string
+	for _, word := range strings.Fields(name) {
+		var code []byte
+		var last byte
+		for i, c := range word {
+			digit, ok := soundexCodes[c]
+			switch {
+			case i == 0 && ok:
+				code = append(code, digit)
+			case i == 0:
+				code = append(code, byte(c))
+			case ok && digit != last:
+				code = append(code, digit)
+			}
+			if c != 'h' && c != 'w' {
+				last = digit // vowels reset, so "Tatum" keeps both t's
+			}
+			if len(code) == 4 {
+				break
+			}
+		}
+		for len(code) < 4 {
+			code = append(code, '0')
+		}
+		words = append(words, string(code))
+	}
+	return strings.Join(words, " ")
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,93 @@
+package time
+
+import "testing"
+
+func TestDamerauLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"tokyo", "tokyo", 0},
+		{"tokio", "tokyo", 1},
+		{"sydeny", "sydney", 1}, // transposition
+		{"londn", "london", 1},
+		{"kitten", "sitting", 3},
+		{"", "abc", 3},
+	}
+	for _, tt := range tests {
+		if got := damerauLevenshtein([]rune(tt.a), []rune(tt.b)); got != tt.want {
+			t.Errorf("damerauLevenshtein(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestPhoneticKey(t *testing.T) {
+	tests := []struct {
+		a, b string
+		same bool
+	}{
+		{"tokio", "tokyo", true},
+		{"sidney", "sydney", true},
+		{"kairo", "cairo", true},
+		{"new york", "nu yorc", true},
+		{"paris", "tokyo", false},
+	}
+	for _, tt := range tests {
+		if got := phoneticKey(tt.a) == phoneticKey(tt.b); got != tt.same {
+			t.Errorf("phoneticKey(%q) = %q, phoneticKey(%q) = %q, same = %v, want %v",
+				tt.a, phoneticKey(tt.a), tt.b, phoneticKey(tt.b), got, tt.same)
+		}
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string // zone of the top suggestion, "" for none
+	}{
+		{"Tokio", "Asia/Tokyo"},
+		{"Sidney", "Australia/Sydney"},
+		{"Lundon", "Europe/London"},
+		{"Kairo", "Africa/Cairo"},
+		{"Franfurt", "Europe/Berlin"},
+		{"America/New_Yrok", "America/New_York"},
+		{"new yor", "America/New_York"},
+		{"xyzabc", ""},
+	}
+	r, err := NewResolver()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			got := r.Suggest(tt.query, 3)
+			if tt.want == "" {
+				if len(got) != 0 {
+					t.Errorf("Suggest(%q) = %v, want none", tt.query, got)
+				}
+				return
+			}
+			if len(got) == 0 || got[0].Place.Zone != tt.want {
+				t.Fatalf("Suggest(%q) = %v, want %s first", tt.query, got, tt.want)
+			}
+			seen := make(map[string]bool)
+			for i, s := range got {
+				if s.Score < minSuggestionScore || s.Score > 1 {
+					t.Errorf("Suggest(%q)[%d] score = %.2f", tt.query, i, s.Score)
+				}
+				if i > 0 && s.Score > got[i-1].Score {
+					t.Errorf("Suggest(%q) not sorted by score", tt.query)
+				}
+				if seen[s.Place.Zone] {
+					t.Errorf("Suggest(%q) repeats zone %s", tt.query, s.Place.Zone)
+				}
+				seen[s.Place.Zone] = true
+			}
+		})
+	}
+
+	if _, err := r.Resolve("Tokio"); err == nil || LocationSuggestions(err)[0].Place.Name != "Tokyo" {
+		t.Errorf("Resolve(Tokio) error = %v, want a suggestion of Tokyo", err)
+	}
+}
@@ -0,0 +1,8 @@
+module github.com/jgabriele321/onmymind/time/internal/gen
+
+go 1.22
+
+require (
+	github.com/tidwall/cities v0.1.0
+	github.com/zsefvlol/timezonemapper v1.0.0
+)
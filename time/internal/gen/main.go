@@ -0,0 +1,205 @@
+// Command gen builds data/cities.tsv and data/countries.tsv from the public
+// domain city list in github.com/tidwall/cities, the coordinate lookup in
+// github.com/zsefvlol/timezonemapper and the tzdata country tables. It is a
+// separate module so that its dependencies stay out of the bot's go.mod.
+//
+//	go run . [-zoneinfo /usr/share/zoneinfo] [-out ../../data]
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/tidwall/cities"
+	"github.com/zsefvlol/timezonemapper"
+)
+
+// countryFixes maps names used by the city list to ISO 3166 codes where
+// they differ from iso3166.tab
+var countryFixes = map[string]string{
+	"United Kingdom":                    "GB",
+	"Korea, South":                      "KR",
+	"Korea, North":                      "KP",
+	"Bahamas, The":                      "BS",
+	"Gambia, The":                       "GM",
+	"Congo, Democratic Republic of the": "CD",
+	"Congo, Republic of the":            "CG",
+	"Burma":                             "MM",
+	"Central African Republic":          "CF",
+	"Samoa":                             "WS",
+	"Myanmar":                           "MM",
+	"Czech Republic":                    "CZ",
+	"Macedonia":                         "MK",
+	"Swaziland":                         "SZ",
+	"Cote d'Ivoire":                     "CI",
+	"Ivory Coast":                       "CI",
+	"Nepa":                              "NP",
+	"Vatican City":                      "VA",
+	"Micronesia":                        "FM",
+	"East Timor":                        "TL",
+	"Timor-Leste":                       "TL",
+	"Cape Verde":                        "CV",
+	"Brunei":                            "BN",
+	"Laos":                              "LA",
+	"Syria":                             "SY",
+	"Iran":                              "IR",
+	"Russia":                            "RU",
+	"Vietnam":                           "VN",
+	"Taiwan":                            "TW",
+	"Tanzania":                          "TZ",
+	"Bolivia":                           "BO",
+	"Venezuela":                         "VE",
+	"Moldova":                           "MD",
+	"Palestine":                         "PS",
+	"Saint Kitts and Nevis":             "KN",
+	"Saint Lucia":                       "LC",
+	"Saint Vincent and the Grenadines":  "VC",
+	"Sao Tome and Principe":             "ST",
+	"Antigua and Barbuda":               "AG",
+	"Trinidad and Tobago":               "TT",
+	"Bosnia and Herzegovina":            "BA",
+	"Heard Island and McDonald Islands": "HM",
+}
+
+// countryNames replaces the tzdata names that read oddly on their own
+var countryNames = map[string]string{
+	"GB": "United Kingdom",
+	"KR": "South Korea",
+	"KP": "North Korea",
+	"CD": "Democratic Republic of the Congo",
+	"CG": "Republic of the Congo",
+	"MK": "North Macedonia",
+	"US": "United States",
+}
+
+// cityNames replaces city names that have changed since the list was made
+var cityNames = map[string]string{
+	"Kiev":     "Kyiv",
+	"Calcutta": "Kolkata",
+}
+
+func main() {
+	zoneinfo := flag.String("zoneinfo", "/usr/share/zoneinfo", "tzdata directory with iso3166.tab, zone.tab and tzdata.zi")
+	dir := flag.String("out", "../../data", "directory to write the datasets to")
+	flag.Parse()
+
+	names := readTab(filepath.Join(*zoneinfo, "iso3166.tab"))
+	codes := make(map[string]string)
+	for _, row := range names {
+		codes[row[1]] = row[0]
+	}
+	countryZones := make(map[string][]string)
+	links := readLinks(filepath.Join(*zoneinfo, "tzdata.zi"))
+	for _, row := range readTab(filepath.Join(*zoneinfo, "zone.tab")) {
+		countryZones[row[0]] = append(countryZones[row[0]], row[2])
+		// Keep zones that zone.tab still lists for their country, such as
+		// Europe/Mariehamn, even if tzdata links them to another zone
+		delete(links, row[2])
+	}
+
+	out, err := os.Create(filepath.Join(*dir, "cities.tsv"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer out.Close()
+	w := bufio.NewWriter(out)
+	defer w.Flush()
+	fmt.Fprintln(w, "# name\tcountry\tzone")
+	fmt.Fprintln(w, "# Generated by time/internal/gen from github.com/tidwall/cities (public domain); do not edit.")
+
+	seen := make(map[string]bool)
+	unknown := make(map[string]bool)
+	for _, c := range cities.Cities {
+		code, ok := countryFixes[c.Country]
+		if !ok {
+			code, ok = codes[c.Country]
+		}
+		if !ok {
+			unknown[c.Country] = true
+			continue
+		}
+		zone := timezonemapper.LatLngToTimezoneString(c.Latitude, c.Longitude)
+		// The mapper still uses some deprecated names such as Europe/Kiev
+		if target, ok := links[zone]; ok {
+			zone = target
+		}
+		if _, err := time.LoadLocation(zone); zone == "" || err != nil {
+			continue
+		}
+		name := strings.TrimSpace(c.City)
+		if n, ok := cityNames[name]; ok {
+			name = n
+		}
+		key := strings.ToLower(name) + "|" + code + "|" + zone
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		fmt.Fprintf(w, "%s\t%s\t%s\n", name, code, zone)
+	}
+	for name := range unknown {
+		log.Printf("skipped cities in unknown country %q", name)
+	}
+
+	cout, err := os.Create(filepath.Join(*dir, "countries.tsv"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer cout.Close()
+	cw := bufio.NewWriter(cout)
+	defer cw.Flush()
+	fmt.Fprintln(cw, "# code\tname\tzones")
+	fmt.Fprintln(cw, "# Generated by time/internal/gen from the tzdata iso3166.tab and zone.tab; do not edit.")
+	sort.Slice(names, func(i, j int) bool { return names[i][0] < names[j][0] })
+	for _, row := range names {
+		zones := countryZones[row[0]]
+		if len(zones) == 0 {
+			continue
+		}
+		name := row[1]
+		if n, ok := countryNames[row[0]]; ok {
+			name = n
+		}
+		fmt.Fprintf(cw, "%s\t%s\t%s\n", row[0], name, strings.Join(zones, ","))
+	}
+}
+
+// readTab reads a tab separated tzdata table, skipping comments
+func readTab(path string) [][]string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var rows [][]string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rows = append(rows, strings.Split(line, "\t"))
+	}
+	return rows
+}
+
+// readLinks reads the links of tzdata.zi, mapping each old zone name to
+// the zone it now follows
+func readLinks(path string) map[string]string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	links := make(map[string]string)
+	for _, line := range strings.Split(string(data), "\n") {
+		// L Europe/Kyiv Europe/Kiev
+		if f := strings.Fields(line); len(f) == 3 && f[0] == "L" {
+			links[f[2]] = f[1]
+		}
+	}
+	return links
+}
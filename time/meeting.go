@@ -0,0 +1,166 @@
+package time
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WorkingHours is the part of each weekday someone is available, in
+// minutes after local midnight. End may be 24*60.
+type WorkingHours struct {
+	Start, End int
+}
+
+// DefaultWorkingHours is nine to five
+var DefaultWorkingHours = WorkingHours{Start: 9 * 60, End: 17 * 60}
+
+var workingHoursPattern = regexp.MustCompile(`^(\d{1,2})(?::(\d{2}))?\s*-\s*(\d{1,2})(?::(\d{2}))?$`)
+
+// ParseWorkingHours reads "9-17" or "8:30-16:30"
+func ParseWorkingHours(s string) (WorkingHours, error) {
+	m := workingHoursPattern.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return WorkingHours{}, fmt.Errorf("invalid working hours %q, use e.g. 9-17 or 8:30-16:30", s)
+	}
+	minutes := func(h, m string) int {
+		hours, _ := strconv.Atoi(h)
+		mins, _ := strconv.Atoi(m) // empty means 0
+		return hours*60 + mins
+	}
+	wh := WorkingHours{Start: minutes(m[1], m[2]), End: minutes(m[3], m[4])}
+	if wh.Start >= wh.End || wh.End > 24*60 {
+		return WorkingHours{}, fmt.Errorf("invalid working hours %q, the start must be before the end", s)
+	}
+	return wh, nil
+}
+
+func (wh WorkingHours) String() string {
+	return fmt.Sprintf("%02d:%02d-%02d:%02d", wh.Start/60, wh.Start%60, wh.End/60, wh.End%60)
+}
+
+// Participant is someone a meeting has to suit
+type Participant struct {
+	Name  string
+	Loc   *time.Location
+	Hours WorkingHours
+}
+
+// available reports whether t falls in the participant's working hours on
+// a local weekday
+func (p Participant) available(t time.Time) bool {
+	local := t.In(p.Loc)
+	if wd := local.Weekday(); wd == time.Saturday || wd == time.Sunday {
+		return false
+	}
+	minute := local.Hour()*60 + local.Minute()
+	return minute >= p.Hours.Start && minute < p.Hours.End
+}
+
+// MeetingWindow is a span of time that suits every participant
+type MeetingWindow struct {
+	Start, End time.Time
+}
+
+// meetingStep is the resolution of PlanMeeting. Every UTC offset in use
+// is a multiple of it.
+const meetingStep = 5 * time.Minute
+
+// PlanMeeting returns the windows of at least minLength in the days after
+// from during which every participant is within their working hours on a
+// local weekday. Each instant is checked in each participant's zone, so
+// DST changes during the period are taken into account.
+func PlanMeeting(participants []Participant, from time.Time, days int, minLength time.Duration) []MeetingWindow {
+	if len(participants) == 0 || days <= 0 {
+		return nil
+	}
+	start := from.Truncate(meetingStep)
+	if start.Before(from) {
+		start = start.Add(meetingStep)
+	}
+	end := from.AddDate(0, 0, days)
+
+	var windows []MeetingWindow
+	var open *MeetingWindow
+	for t := start; t.Before(end); t = t.Add(meetingStep) {
+		ok := true
+		for _, p := range participants {
+			if !p.available(t) {
+				ok = false
+				break
+			}
+		}
+		switch {
+		case ok && open == nil:
+			open = &MeetingWindow{Start: t}
+		case !ok && open != nil:
+			open.End = t
+			windows = append(windows, *open)
+			open = nil
+		}
+	}
+	if open != nil {
+		open.End = end
+		windows = append(windows, *open)
+	}
+
+	long := windows[:0]
+	for _, w := range windows {
+		if w.End.Sub(w.Start) >= minLength {
+			long = append(long, w)
+		}
+	}
+	return long
+}
+
+// meetingColumnWidth fits "Mon 09:00-17:00" and most city names
+const meetingColumnWidth = 16
+
+// FormatMeetingWindows lays the windows out as a table with one column per
+// participant showing the window in their local time
+func FormatMeetingWindows(participants []Participant, windows []MeetingWindow) string {
+	var b strings.Builder
+	newline := func() {
+		// Drop the padding of the last column
+		trimmed := strings.TrimRight(b.String(), " ")
+		b.Reset()
+		b.WriteString(trimmed + "\n")
+	}
+	for i, p := range participants {
+		if i > 0 {
+			b.WriteString(" ")
+		}
+		b.WriteString(pad(truncate(p.Name, meetingColumnWidth), meetingColumnWidth))
+	}
+	newline()
+	for _, w := range windows {
+		for i, p := range participants {
+			if i > 0 {
+				b.WriteString(" ")
+			}
+			start, end := w.Start.In(p.Loc), w.End.In(p.Loc)
+			b.WriteString(pad(start.Format("Mon 15:04")+"-"+end.Format("15:04"), meetingColumnWidth))
+		}
+		newline()
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// pad fills s with spaces to n runes
+func pad(s string, n int) string {
+	if r := len([]rune(s)); r < n {
+		return s + strings.Repeat(" ", n-r)
+	}
+	return s
+}
+
+// truncate shortens s to n runes, marking the cut with an ellipsis
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
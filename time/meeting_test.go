@@ -0,0 +1,125 @@
+package time
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseWorkingHours(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    WorkingHours
+		wantErr bool
+	}{
+		{input: "9-17", want: WorkingHours{9 * 60, 17 * 60}},
+		{input: "8:30 - 16:45", want: WorkingHours{8*60 + 30, 16*60 + 45}},
+		{input: "0-24", want: WorkingHours{0, 24 * 60}},
+		{input: "17-9", wantErr: true},
+		{input: "9-25", wantErr: true},
+		{input: "nine to five", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseWorkingHours(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseWorkingHours(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseWorkingHours(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlanMeeting(t *testing.T) {
+	newYork := Participant{Name: "New York", Loc: mustLoad(t, "America/New_York"), Hours: DefaultWorkingHours}
+	london := Participant{Name: "London", Loc: mustLoad(t, "Europe/London"), Hours: DefaultWorkingHours}
+	tokyo := Participant{Name: "Tokyo", Loc: mustLoad(t, "Asia/Tokyo"), Hours: DefaultWorkingHours}
+	kolkata := Participant{Name: "Kolkata", Loc: mustLoad(t, "Asia/Kolkata"), Hours: DefaultWorkingHours}
+
+	tests := []struct {
+		name         string
+		participants []Participant
+		from         time.Time
+		days         int
+		minLength    time.Duration
+		want         []MeetingWindow
+	}{
+		{
+			name:         "Before the US changes clocks",
+			participants: []Participant{newYork, london},
+			from:         time.Date(2024, 3, 8, 0, 0, 0, 0, time.UTC), // Friday
+			days:         1,
+			want: []MeetingWindow{
+				{time.Date(2024, 3, 8, 14, 0, 0, 0, time.UTC), time.Date(2024, 3, 8, 17, 0, 0, 0, time.UTC)},
+			},
+		},
+		{
+			// New York is on EDT and London still on GMT; the weekend is skipped
+			name:         "After the US changes clocks",
+			participants: []Participant{newYork, london},
+			from:         time.Date(2024, 3, 9, 0, 0, 0, 0, time.UTC), // Saturday
+			days:         3,
+			want: []MeetingWindow{
+				{time.Date(2024, 3, 11, 13, 0, 0, 0, time.UTC), time.Date(2024, 3, 11, 17, 0, 0, 0, time.UTC)},
+			},
+		},
+		{
+			name:         "Half-hour offset",
+			participants: []Participant{london, kolkata},
+			from:         time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC), // Monday
+			days:         1,
+			want: []MeetingWindow{
+				{time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC), time.Date(2024, 1, 15, 11, 30, 0, 0, time.UTC)},
+			},
+		},
+		{
+			name:         "No overlap",
+			participants: []Participant{newYork, tokyo},
+			from:         time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC),
+			days:         5,
+		},
+		{
+			name:         "Too short",
+			participants: []Participant{london, kolkata},
+			from:         time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC),
+			days:         1,
+			minLength:    3 * time.Hour,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := PlanMeeting(tt.participants, tt.from, tt.days, tt.minLength)
+			if len(got) != len(tt.want) {
+				t.Fatalf("PlanMeeting() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if !got[i].Start.Equal(tt.want[i].Start) || !got[i].End.Equal(tt.want[i].End) {
+					t.Errorf("window %d = %v-%v, want %v-%v", i,
+						got[i].Start.UTC(), got[i].End.UTC(), tt.want[i].Start, tt.want[i].End)
+				}
+			}
+		})
+	}
+}
+
+func TestFormatMeetingWindows(t *testing.T) {
+	participants := []Participant{
+		{Name: "New York", Loc: mustLoad(t, "America/New_York")},
+		{Name: "São Paulo", Loc: mustLoad(t, "America/Sao_Paulo")},
+	}
+	windows := []MeetingWindow{
+		{time.Date(2024, 3, 11, 13, 0, 0, 0, time.UTC), time.Date(2024, 3, 11, 17, 0, 0, 0, time.UTC)},
+	}
+	want := "New York         São Paulo\n" +
+		"Mon 09:00-13:00  Mon 10:00-14:00"
+	if got := FormatMeetingWindows(participants, windows); got != want {
+		t.Errorf("FormatMeetingWindows() =\n%s\nwant\n%s", got, want)
+	}
+	if got := FormatMeetingWindows([]Participant{{Name: strings.Repeat("x", 20)}}, nil); got != strings.Repeat("x", 15)+"…" {
+		t.Errorf("long name = %q", got)
+	}
+}
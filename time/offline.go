@@ -0,0 +1,99 @@
+package time
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// OfflineUsage describes the queries understood without an LLM
+const OfflineUsage = `Supported formats:
+• /time Tokyo — current time in a city, country, airport (LHR) or zone
+• /time 2:30 PM New York to Tokyo — convert a time between locations
+• /time 9am London to Sydney on March 10 — convert on a given date
+• /time 3pm to Tokyo — convert from your home time zone (see /settz)
+• /time info London — time zone details`
+
+var (
+	// "2:30 PM New York to Tokyo", "14:00 london in tokyo"
+	offlineConvertPattern = regexp.MustCompile(`(?i)^(\d{1,2}(?::\d{2})?\s*(?:am|pm)?)\s+(?:in\s+|from\s+)?(.+?)\s+(?:to|in|into)\s+(.+)$`)
+	// "... on March 10", "... tomorrow", "... in 3 days"
+	offlineDatePattern = regexp.MustCompile(`(?i)^(.+?)\s+(?:on\s+(.+)|(today|tomorrow|yesterday|in\s+\d+\s+days?))\??$`)
+	// "info London", "zone info for London"
+	offlineInfoPattern = regexp.MustCompile(`(?i)^(?:zone\s+)?info(?:\s+for)?\s+(.+)$`)
+	// "3pm to Tokyo", converted from the user's home time zone
+	offlineHomeConvertPattern = regexp.MustCompile(`(?i)^(?:what(?:'s| is)\s+)?(\d{1,2}(?::\d{2})?\s*(?:am|pm)?)\s+(?:to|in|into)\s+(.+)$`)
+	// "what time is it", "time", "now", answered for the user's home time zone
+	offlineLocalTimePattern = regexp.MustCompile(`(?i)^(?:(?:what(?:'s| is)?\s+)?(?:the\s+)?(?:current\s+)?time(?:\s+is\s+it)?(?:\s+now)?|now)\??$`)
+	// "what time is it in Tokyo", "time in Tokyo", "Tokyo"
+	offlineCurrentPattern = regexp.MustCompile(`(?i)^(?:(?:what(?:'s| is)?\s+)?(?:the\s+)?(?:current\s+)?time(?:\s+is\s+it)?\s+(?:in|at)\s+)?(.+?)\??$`)
+)
+
+// ProcessQueryOffline answers simple time queries with the deterministic
+// tools only. It is used when no OpenRouter key is configured.
+func ProcessQueryOffline(query string) (string, error) {
+	return processQueryOffline(query, QueryContext{})
+}
+
+// processQueryOffline is ProcessQueryOffline for a user whose home time
+// zone may be known, which is used when a query doesn't name a place
+func processQueryOffline(query string, qc QueryContext) (string, error) {
+	query = strings.TrimSpace(query)
+	if qc.HomeZone != "" && (query == "" || offlineLocalTimePattern.MatchString(query)) {
+		return GetCurrentTimeWithTools(qc.HomeZone)
+	}
+	if query == "" {
+		return "", fmt.Errorf("empty query\n\n%s", OfflineUsage)
+	}
+
+	conversion, date := query, ""
+	if m := offlineDatePattern.FindStringSubmatch(query); m != nil {
+		conversion, date = m[1], m[2]+m[3]
+	}
+	if m := offlineConvertPattern.FindStringSubmatch(conversion); m != nil {
+		timeStr, err := normalizeClockTime(m[1])
+		if err == nil {
+			return ConvertTimeZonesOnDateWithTools(timeStr, date, m[2], strings.TrimSuffix(m[3], "?"))
+		}
+	}
+	if m := offlineHomeConvertPattern.FindStringSubmatch(conversion); m != nil && qc.HomeZone != "" {
+		timeStr, err := normalizeClockTime(m[1])
+		if err == nil {
+			return ConvertTimeZonesOnDateWithTools(timeStr, date, qc.HomeZone, strings.TrimSuffix(m[2], "?"))
+		}
+	}
+
+	if m := offlineInfoPattern.FindStringSubmatch(query); m != nil {
+		return GetDetailedTimeZoneInfoWithTools(strings.TrimSuffix(m[1], "?"))
+	}
+
+	if m := offlineCurrentPattern.FindStringSubmatch(query); m != nil {
+		result, err := GetCurrentTimeWithTools(m[1])
+		if err == nil {
+			return result, nil
+		} else if len(LocationSuggestions(err)) > 0 {
+			// Ambiguous or misspelled, e.g. "Portland" or "Tokio"
+			return "", err
+		}
+	}
+
+	return "", fmt.Errorf("I couldn't understand %q without the AI assistant\n\n%s", query, OfflineUsage)
+}
+
+// normalizeClockTime turns "2pm", "2:30pm" or "14:00" into a format
+// accepted by ConvertTimeZonesWithTools
+func normalizeClockTime(s string) (string, error) {
+	s = strings.ToUpper(strings.ReplaceAll(s, " ", ""))
+	suffix := ""
+	if strings.HasSuffix(s, "AM") || strings.HasSuffix(s, "PM") {
+		suffix = " " + s[len(s)-2:]
+		s = s[:len(s)-2]
+	}
+	if !strings.Contains(s, ":") {
+		if suffix == "" {
+			return "", fmt.Errorf("ambiguous time %q", s)
+		}
+		s += ":00"
+	}
+	return s + suffix, nil
+}
@@ -0,0 +1,140 @@
+package time
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProcessQueryOffline(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		wantErr  bool
+		contains string
+	}{
+		{
+			name:     "Current time by city",
+			query:    "Tokyo",
+			contains: "current time in Tokyo",
+		},
+		{
+			name:     "Current time question",
+			query:    "what time is it in New York?",
+			contains: "current time in New York",
+		},
+		{
+			name:     "Conversion 12h",
+			query:    "2:30 PM New York to Tokyo",
+			contains: "→",
+		},
+		{
+			name:     "Conversion short form",
+			query:    "2pm london in tokyo",
+			contains: "→",
+		},
+		{
+			name:     "Conversion 24h",
+			query:    "14:00 Europe/London to Asia/Tokyo",
+			contains: "→",
+		},
+		{
+			name:     "Conversion on a date",
+			query:    "9am London to Sydney on 2025-04-06",
+			contains: "Sun Apr 6 2025, 9:00 AM",
+		},
+		{
+			name:     "Conversion tomorrow",
+			query:    "2pm london in tokyo tomorrow",
+			contains: "→",
+		},
+		{
+			name:     "Zone info",
+			query:    "info London",
+			contains: "UTC",
+		},
+		{
+			name:     "Airport code",
+			query:    "time in LHR",
+			contains: "current time in LHR",
+		},
+		{
+			name:    "Ambiguous city",
+			query:   "time in Portland",
+			wantErr: true,
+		},
+		{
+			name:    "Misspelled city",
+			query:   "time in Tokio",
+			wantErr: true,
+		},
+		{
+			name:    "Unknown query",
+			query:   "how long until my flight",
+			wantErr: true,
+		},
+		{
+			name:    "Empty query",
+			query:   "  ",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ProcessQueryOffline(tt.query)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ProcessQueryOffline() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !strings.Contains(got, tt.contains) {
+				t.Errorf("ProcessQueryOffline() = %q, want it to contain %q", got, tt.contains)
+			}
+		})
+	}
+}
+
+func TestProcessQueryOfflineWithHome(t *testing.T) {
+	home := QueryContext{HomeName: "Portland, Maine", HomeZone: "America/New_York"}
+	tests := []struct {
+		query    string
+		contains string
+	}{
+		{"", "current time in America/New_York"},
+		{"what time is it?", "current time in America/New_York"},
+		{"now", "current time in America/New_York"},
+		{"3pm to Tokyo on 2024-06-10", "Mon Jun 10 2024, 3:00 PM (15:00) America/New_York EDT"},
+		{"what's 9am in London tomorrow?", "→"},
+		{"2pm Paris to Tokyo", "Paris"},
+		{"Tokyo", "current time in Tokyo"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			got, err := processQueryOffline(tt.query, home)
+			if err != nil {
+				t.Fatalf("processQueryOffline(%q) error = %v", tt.query, err)
+			}
+			if !strings.Contains(got, tt.contains) {
+				t.Errorf("processQueryOffline(%q) = %q, want it to contain %q", tt.query, got, tt.contains)
+			}
+		})
+	}
+
+	// Without a home time zone these need a place
+	for _, query := range []string{"", "3pm to Tokyo"} {
+		if _, err := processQueryOffline(query, QueryContext{}); err == nil {
+			t.Errorf("processQueryOffline(%q) without a home zone succeeded", query)
+		}
+	}
+}
+
+func TestQueryContextDescribe(t *testing.T) {
+	now := time.Date(2024, 6, 10, 14, 30, 0, 0, time.UTC)
+	if got := (QueryContext{}).describe(now); got != "" {
+		t.Errorf("empty context = %q", got)
+	}
+	got := QueryContext{HomeName: "Kolkata", HomeZone: "Asia/Kolkata"}.describe(now)
+	want := "User's home time zone: Kolkata (Asia/Kolkata), local time Monday 2024-06-10 20:00 IST (UTC+05:30)."
+	if !strings.HasPrefix(got, want) {
+		t.Errorf("describe() = %q, want prefix %q", got, want)
+	}
+}
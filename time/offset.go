@@ -0,0 +1,29 @@
+package time
+
+import (
+	"fmt"
+	"time"
+)
+
+// ZoneOffset returns how far ahead of UTC t's zone is at t. Offsets are
+// not always whole hours: India is UTC+05:30, Nepal UTC+05:45.
+func ZoneOffset(t time.Time) time.Duration {
+	_, offset := t.Zone()
+	return time.Duration(offset) * time.Second
+}
+
+// FormatOffset formats an offset from UTC as "UTC+05:30" or "UTC-04:00".
+// Seconds are shown only for the local mean times of old tzdata entries.
+func FormatOffset(offset time.Duration) string {
+	sign := '+'
+	if offset < 0 {
+		sign = '-'
+		offset = -offset
+	}
+	total := int(offset / time.Second)
+	hours, minutes, seconds := total/3600, total/60%60, total%60
+	if seconds != 0 {
+		return fmt.Sprintf("UTC%c%02d:%02d:%02d", sign, hours, minutes, seconds)
+	}
+	return fmt.Sprintf("UTC%c%02d:%02d", sign, hours, minutes)
+}
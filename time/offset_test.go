@@ -0,0 +1,77 @@
+package time
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormatOffset(t *testing.T) {
+	tests := []struct {
+		offset time.Duration
+		want   string
+	}{
+		{0, "UTC+00:00"},
+		{time.Hour, "UTC+01:00"},
+		{-5 * time.Hour, "UTC-05:00"},
+		{5*time.Hour + 30*time.Minute, "UTC+05:30"},
+		{5*time.Hour + 45*time.Minute, "UTC+05:45"},
+		{-(3*time.Hour + 30*time.Minute), "UTC-03:30"},
+		{12*time.Hour + 45*time.Minute, "UTC+12:45"},
+		{14 * time.Hour, "UTC+14:00"},
+		// Amsterdam's local mean time until 1937
+		{19*time.Minute + 32*time.Second, "UTC+00:19:32"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := FormatOffset(tt.offset); got != tt.want {
+				t.Errorf("FormatOffset(%v) = %q, want %q", tt.offset, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNonHourOffsets(t *testing.T) {
+	tests := []struct {
+		location string
+		want     time.Duration
+	}{
+		{"Asia/Kolkata", 5*time.Hour + 30*time.Minute},
+		{"Asia/Kathmandu", 5*time.Hour + 45*time.Minute},
+		{"Asia/Tehran", 3*time.Hour + 30*time.Minute},
+		{"Australia/Eucla", 8*time.Hour + 45*time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.location, func(t *testing.T) {
+			info, err := GetDetailedTimeZoneInfo(tt.location)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Offset != tt.want {
+				t.Errorf("Offset = %v, want %v", info.Offset, tt.want)
+			}
+
+			text, err := GetDetailedTimeZoneInfoWithTools(tt.location)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := FormatOffset(tt.want); !strings.Contains(text, want) {
+				t.Errorf("GetDetailedTimeZoneInfoWithTools() = %q, want it to contain %q", text, want)
+			}
+		})
+	}
+}
+
+func TestConvertNonHourOffsets(t *testing.T) {
+	got, err := ConvertTimeZonesWithTools("9:00 AM", "Asia/Kathmandu", "Asia/Kolkata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"9:00 AM", "UTC+05:45", "8:45 AM", "UTC+05:30"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("ConvertTimeZonesWithTools() = %q, want it to contain %q", got, want)
+		}
+	}
+}
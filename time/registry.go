@@ -0,0 +1,404 @@
+package time
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ParamType is the JSON schema type of a tool parameter
+type ParamType string
+
+const (
+	ParamString  ParamType = "string"
+	ParamInteger ParamType = "integer"
+	ParamNumber  ParamType = "number"
+	ParamBoolean ParamType = "boolean"
+)
+
+// Param describes one argument of a Tool. Required parameters come first
+// because the text call format passes arguments by position.
+type Param struct {
+	Name        string
+	Type        ParamType // ParamString if empty
+	Description string
+	Required    bool
+}
+
+func (p Param) typ() ParamType {
+	if p.Type == "" {
+		return ParamString
+	}
+	return p.Type
+}
+
+// Tool is a function the assistant can call
+type Tool interface {
+	Name() string
+	Description() string
+	Params() []Param
+	Execute(args Args) (string, error)
+}
+
+// ToolWithExamples is implemented by tools that show the assistant
+// example calls, each a list of positional arguments
+type ToolWithExamples interface {
+	Tool
+	Examples() [][]string
+}
+
+// Args are the arguments of a tool call, converted to the Go type of each
+// parameter: string, int, float64 or bool. Optional arguments that were
+// left out are missing.
+type Args map[string]any
+
+// String returns a string argument, or "" if it is missing
+func (a Args) String(name string) string {
+	s, _ := a[name].(string)
+	return s
+}
+
+// Int returns an integer argument, or 0 if it is missing
+func (a Args) Int(name string) int {
+	n, _ := a[name].(int)
+	return n
+}
+
+// Float returns a number argument, or 0 if it is missing
+func (a Args) Float(name string) float64 {
+	f, _ := a[name].(float64)
+	return f
+}
+
+// Bool returns a boolean argument, or false if it is missing
+func (a Args) Bool(name string) bool {
+	b, _ := a[name].(bool)
+	return b
+}
+
+// FuncTool is a Tool built from a function
+type FuncTool struct {
+	ToolName     string
+	Desc         string
+	Parameters   []Param
+	ToolExamples [][]string
+	Run          func(args Args) (string, error)
+}
+
+func (f *FuncTool) Name() string                      { return f.ToolName }
+func (f *FuncTool) Description() string               { return f.Desc }
+func (f *FuncTool) Params() []Param                   { return f.Parameters }
+func (f *FuncTool) Examples() [][]string              { return f.ToolExamples }
+func (f *FuncTool) Execute(args Args) (string, error) { return f.Run(args) }
+
+var (
+	toolNamePattern = regexp.MustCompile(`^[A-Za-z_]\w*$`)
+	// Tool: ConvertTimeZones("2:30 PM", "New York", "Tokyo")
+	toolCallPattern = regexp.MustCompile(`Tool: (\w+)\(((?:\s*"[^"]*"\s*,?)*)\)`)
+	toolArgPattern  = regexp.MustCompile(`"([^"]*)"`)
+)
+
+// ToolRegistry holds the tools offered to the assistant, in the order
+// they were registered
+type ToolRegistry struct {
+	mu     sync.RWMutex
+	tools  []Tool
+	byName map[string]Tool
+}
+
+// NewToolRegistry creates an empty registry
+func NewToolRegistry() *ToolRegistry {
+	return &ToolRegistry{byName: make(map[string]Tool)}
+}
+
+// Register adds a tool. It fails if the name is taken or the parameters
+// are invalid.
+func (r *ToolRegistry) Register(t Tool) error {
+	name := t.Name()
+	if !toolNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid tool name %q", name)
+	}
+	seen := make(map[string]bool)
+	optional := false
+	for _, p := range t.Params() {
+		switch {
+		case !toolNamePattern.MatchString(p.Name):
+			return fmt.Errorf("tool %s: invalid parameter name %q", name, p.Name)
+		case seen[p.Name]:
+			return fmt.Errorf("tool %s: duplicate parameter %q", name, p.Name)
+		case p.Required && optional:
+			return fmt.Errorf("tool %s: required parameter %q follows an optional one", name, p.Name)
+		}
+		switch p.typ() {
+		case ParamString, ParamInteger, ParamNumber, ParamBoolean:
+		default:
+			return fmt.Errorf("tool %s: parameter %q has unsupported type %q", name, p.Name, p.Type)
+		}
+		seen[p.Name] = true
+		optional = optional || !p.Required
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.byName[name]; ok {
+		return fmt.Errorf("tool %s is already registered", name)
+	}
+	r.tools = append(r.tools, t)
+	r.byName[name] = t
+	return nil
+}
+
+// MustRegister is like Register but panics on error
+func (r *ToolRegistry) MustRegister(tools ...Tool) {
+	for _, t := range tools {
+		if err := r.Register(t); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// Lookup returns the tool with the given name
+func (r *ToolRegistry) Lookup(name string) (Tool, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	t, ok := r.byName[name]
+	return t, ok
+}
+
+// Tools returns the registered tools
+func (r *ToolRegistry) Tools() []Tool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]Tool(nil), r.tools...)
+}
+
+// Execute runs a tool with positional arguments, as written in the text
+// call format. Empty strings leave optional arguments out.
+func (r *ToolRegistry) Execute(name string, positional ...string) (string, error) {
+	t, ok := r.Lookup(name)
+	if !ok {
+		return "", fmt.Errorf("unknown tool: %s", name)
+	}
+	params := t.Params()
+	if len(positional) > len(params) {
+		return "", fmt.Errorf("%s takes at most %d arguments, got %d", name, len(params), len(positional))
+	}
+	raw := make(map[string]string)
+	for i, value := range positional {
+		if value != "" {
+			raw[params[i].Name] = value
+		}
+	}
+	args, err := convertArgs(name, params, raw)
+	if err != nil {
+		return "", err
+	}
+	return t.Execute(args)
+}
+
+// ExecuteJSON runs a tool with arguments given as a JSON object, as sent
+// by models with native tool calling
+func (r *ToolRegistry) ExecuteJSON(name, arguments string) (string, error) {
+	t, ok := r.Lookup(name)
+	if !ok {
+		return "", fmt.Errorf("unknown tool: %s", name)
+	}
+	var values map[string]any
+	if strings.TrimSpace(arguments) != "" {
+		if err := json.Unmarshal([]byte(arguments), &values); err != nil {
+			return "", fmt.Errorf("%s: invalid arguments: %v", name, err)
+		}
+	}
+
+	params := t.Params()
+	raw := make(map[string]string)
+	for key, value := range values {
+		if !hasParam(params, key) {
+			return "", fmt.Errorf("%s has no parameter %q", name, key)
+		}
+		switch v := value.(type) {
+		case nil:
+		case string:
+			if v != "" {
+				raw[key] = v
+			}
+		case float64:
+			raw[key] = strconv.FormatFloat(v, 'f', -1, 64)
+		case bool:
+			raw[key] = strconv.FormatBool(v)
+		default:
+			return "", fmt.Errorf("%s: %s must be a %s", name, key, paramType(params, key))
+		}
+	}
+	args, err := convertArgs(name, params, raw)
+	if err != nil {
+		return "", err
+	}
+	return t.Execute(args)
+}
+
+func hasParam(params []Param, name string) bool {
+	return paramType(params, name) != ""
+}
+
+func paramType(params []Param, name string) ParamType {
+	for _, p := range params {
+		if p.Name == name {
+			return p.typ()
+		}
+	}
+	return ""
+}
+
+// convertArgs checks that required arguments are present and converts
+// each one to the type of its parameter
+func convertArgs(tool string, params []Param, raw map[string]string) (Args, error) {
+	args := make(Args)
+	for _, p := range params {
+		value, ok := raw[p.Name]
+		if !ok {
+			if p.Required {
+				return nil, fmt.Errorf("%s requires %s", tool, p.Name)
+			}
+			continue
+		}
+		switch p.typ() {
+		case ParamString:
+			args[p.Name] = value
+		case ParamInteger:
+			n, err := strconv.Atoi(strings.TrimSpace(value))
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s must be an integer, got %q", tool, p.Name, value)
+			}
+			args[p.Name] = n
+		case ParamNumber:
+			f, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s must be a number, got %q", tool, p.Name, value)
+			}
+			args[p.Name] = f
+		case ParamBoolean:
+			b, err := strconv.ParseBool(strings.TrimSpace(value))
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s must be true or false, got %q", tool, p.Name, value)
+			}
+			args[p.Name] = b
+		}
+	}
+	return args, nil
+}
+
+// ExpandCalls replaces each text tool call in s with its result. Calls
+// that fail are left in place and logged.
+func (r *ToolRegistry) ExpandCalls(s string) string {
+	for _, call := range toolCallPattern.FindAllStringSubmatch(s, -1) {
+		var args []string
+		for _, arg := range toolArgPattern.FindAllStringSubmatch(call[2], -1) {
+			args = append(args, arg[1])
+		}
+		result, err := r.Execute(call[1], args...)
+		if err != nil {
+			log.Printf("Error executing tool %s: %v", call[1], err)
+			continue
+		}
+		s = strings.Replace(s, call[0], result, 1)
+	}
+	return s
+}
+
+// Prompt describes the registered tools and the text call format for the
+// system prompt
+func (r *ToolRegistry) Prompt() string {
+	tools := r.Tools()
+	var b strings.Builder
+	b.WriteString("Tool call format (optional arguments at the end may be left out):\n\n")
+	for _, t := range tools {
+		var names []string
+		for _, p := range t.Params() {
+			names = append(names, p.Name)
+		}
+		b.WriteString(formatToolCall(t.Name(), names) + "\n")
+	}
+
+	b.WriteString("\nThe tools available are:\n")
+	for i, t := range tools {
+		var names []string
+		for _, p := range t.Params() {
+			if p.Required {
+				names = append(names, p.Name)
+			} else {
+				names = append(names, p.Name+"?")
+			}
+		}
+		fmt.Fprintf(&b, "\n%d. %s(%s)\n", i+1, t.Name(), strings.Join(names, ", "))
+		fmt.Fprintf(&b, "   %s\n", t.Description())
+		for _, p := range t.Params() {
+			fmt.Fprintf(&b, "   - %s (%s", p.Name, p.typ())
+			if !p.Required {
+				b.WriteString(", optional")
+			}
+			fmt.Fprintf(&b, "): %s\n", p.Description)
+		}
+		if ex, ok := t.(ToolWithExamples); ok {
+			for _, args := range ex.Examples() {
+				fmt.Fprintf(&b, "   Example: %s\n", formatToolCall(t.Name(), args))
+			}
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+func formatToolCall(name string, args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = `"` + arg + `"`
+	}
+	return fmt.Sprintf("Tool: %s(%s)", name, strings.Join(quoted, ", "))
+}
+
+// ToolSchema is the OpenAI-style function definition of a tool, as sent
+// in the tools field of an OpenRouter request
+type ToolSchema struct {
+	Type     string         `json:"type"`
+	Function FunctionSchema `json:"function"`
+}
+
+type FunctionSchema struct {
+	Name        string       `json:"name"`
+	Description string       `json:"description"`
+	Parameters  ObjectSchema `json:"parameters"`
+}
+
+type ObjectSchema struct {
+	Type       string                    `json:"type"`
+	Properties map[string]PropertySchema `json:"properties"`
+	Required   []string                  `json:"required"`
+}
+
+type PropertySchema struct {
+	Type        ParamType `json:"type"`
+	Description string    `json:"description,omitempty"`
+}
+
+// Schemas returns the JSON schema of each registered tool
+func (r *ToolRegistry) Schemas() []ToolSchema {
+	var schemas []ToolSchema
+	for _, t := range r.Tools() {
+		params := ObjectSchema{Type: "object", Properties: make(map[string]PropertySchema), Required: []string{}}
+		for _, p := range t.Params() {
+			params.Properties[p.Name] = PropertySchema{Type: p.typ(), Description: p.Description}
+			if p.Required {
+				params.Required = append(params.Required, p.Name)
+			}
+		}
+		schemas = append(schemas, ToolSchema{
+			Type:     "function",
+			Function: FunctionSchema{Name: t.Name(), Description: t.Description(), Parameters: params},
+		})
+	}
+	return schemas
+}
@@ -0,0 +1,191 @@
+package time
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// repeatTool repeats a word, to exercise typed parameters
+func repeatTool() Tool {
+	return &FuncTool{
+		ToolName: "Repeat",
+		Desc:     "Repeats a word.",
+		Parameters: []Param{
+			{Name: "word", Required: true, Description: "word to repeat"},
+			{Name: "times", Type: ParamInteger, Description: "how often, 2 if left out"},
+			{Name: "shout", Type: ParamBoolean, Description: "upper case"},
+		},
+		ToolExamples: [][]string{{"hi", "3"}},
+		Run: func(args Args) (string, error) {
+			times := args.Int("times")
+			if _, ok := args["times"]; !ok {
+				times = 2
+			}
+			s := strings.TrimSpace(strings.Repeat(args.String("word")+" ", times))
+			if args.Bool("shout") {
+				s = strings.ToUpper(s)
+			}
+			return s, nil
+		},
+	}
+}
+
+func TestToolRegistryExecute(t *testing.T) {
+	r := NewToolRegistry()
+	if err := r.Register(repeatTool()); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		args    []string
+		want    string
+		wantErr bool
+	}{
+		{args: []string{"hi"}, want: "hi hi"},
+		{args: []string{"hi", "3"}, want: "hi hi hi"},
+		{args: []string{"hi", "", "true"}, want: "HI HI"},
+		{args: []string{"hi", "three"}, wantErr: true},
+		{args: []string{"hi", "1", "yes please"}, wantErr: true},
+		{args: []string{"hi", "1", "true", "extra"}, wantErr: true},
+		{args: nil, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, ","), func(t *testing.T) {
+			got, err := r.Execute("Repeat", tt.args...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Execute(%q) error = %v, wantErr %v", tt.args, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Execute(%q) = %q, want %q", tt.args, got, tt.want)
+			}
+		})
+	}
+
+	if _, err := r.Execute("Missing"); err == nil {
+		t.Error("expected an error for an unknown tool")
+	}
+}
+
+func TestToolRegistryExecuteJSON(t *testing.T) {
+	r := NewToolRegistry()
+	r.MustRegister(repeatTool())
+
+	tests := []struct {
+		args    string
+		want    string
+		wantErr bool
+	}{
+		{args: `{"word": "hi", "times": 3}`, want: "hi hi hi"},
+		{args: `{"word": "hi", "times": "1", "shout": true}`, want: "HI"},
+		{args: `{"word": "hi", "times": null}`, want: "hi hi"},
+		{args: `{"word": "hi", "times": 1.5}`, wantErr: true},
+		{args: `{"word": "hi", "loud": true}`, wantErr: true},
+		{args: `{"word": ["hi"]}`, wantErr: true},
+		{args: `{}`, wantErr: true},
+		{args: `not json`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.args, func(t *testing.T) {
+			got, err := r.ExecuteJSON("Repeat", tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ExecuteJSON(%s) error = %v, wantErr %v", tt.args, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ExecuteJSON(%s) = %q, want %q", tt.args, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToolRegistryRegister(t *testing.T) {
+	noop := func(Args) (string, error) { return "", nil }
+	tests := []struct {
+		name string
+		tool *FuncTool
+	}{
+		{"bad name", &FuncTool{ToolName: "Get Time", Run: noop}},
+		{"bad param", &FuncTool{ToolName: "A", Parameters: []Param{{Name: "a-b"}}, Run: noop}},
+		{"duplicate param", &FuncTool{ToolName: "A", Parameters: []Param{{Name: "a"}, {Name: "a"}}, Run: noop}},
+		{"required after optional", &FuncTool{ToolName: "A", Parameters: []Param{{Name: "a"}, {Name: "b", Required: true}}, Run: noop}},
+		{"bad type", &FuncTool{ToolName: "A", Parameters: []Param{{Name: "a", Type: "array"}}, Run: noop}},
+	}
+	for _, tt := range tests {
+		if err := NewToolRegistry().Register(tt.tool); err == nil {
+			t.Errorf("Register(%s) succeeded, want an error", tt.name)
+		}
+	}
+
+	r := NewToolRegistry()
+	r.MustRegister(repeatTool())
+	if err := r.Register(repeatTool()); err == nil {
+		t.Error("registering a tool twice succeeded")
+	}
+}
+
+func TestToolRegistryExpandCalls(t *testing.T) {
+	r := NewToolRegistry()
+	r.MustRegister(repeatTool())
+
+	got := r.ExpandCalls(`Say Tool: Repeat("hi", "3") and Tool: Repeat("yo", "", "true"), not Tool: Repeat("x", "many") or Tool: Nope("a")`)
+	want := `Say hi hi hi and YO YO, not Tool: Repeat("x", "many") or Tool: Nope("a")`
+	if got != want {
+		t.Errorf("ExpandCalls() = %q, want %q", got, want)
+	}
+}
+
+func TestToolRegistryPromptAndSchemas(t *testing.T) {
+	r := NewToolRegistry()
+	r.MustRegister(repeatTool())
+
+	prompt := r.Prompt()
+	for _, want := range []string{
+		`Tool: Repeat("word", "times", "shout")`,
+		"1. Repeat(word, times?, shout?)",
+		"   - times (integer, optional): how often, 2 if left out",
+		`   Example: Tool: Repeat("hi", "3")`,
+	} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("Prompt() = %q, want it to contain %q", prompt, want)
+		}
+	}
+
+	data, err := json.Marshal(r.Schemas())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `[{"type":"function","function":{"name":"Repeat","description":"Repeats a word.","parameters":` +
+		`{"type":"object","properties":{"shout":{"type":"boolean","description":"upper case"},` +
+		`"times":{"type":"integer","description":"how often, 2 if left out"},` +
+		`"word":{"type":"string","description":"word to repeat"}},"required":["word"]}}}]`
+	if string(data) != want {
+		t.Errorf("Schemas() = %s\nwant %s", data, want)
+	}
+}
+
+func TestTimeCalculatorTools(t *testing.T) {
+	tc := NewTimeCalculator("")
+	for _, name := range []string{"GetCurrentTime", "ConvertTimeZones", "GetDetailedTimeZoneInfo",
+		"ValidateLocationName", "AddDuration", "SubtractDuration", "SumDurations", "TimeDifference", "LeaveBy"} {
+		if _, ok := tc.Tools().Lookup(name); !ok {
+			t.Errorf("tool %s is not registered", name)
+		}
+	}
+
+	// Other packages can offer their own tools
+	if err := tc.Tools().Register(repeatTool()); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := tc.executeTool("Repeat", "ok"); err != nil || got != "ok ok" {
+		t.Errorf("executeTool(Repeat) = %q, %v", got, err)
+	}
+	if !strings.Contains(tc.Tools().Prompt(), fmt.Sprintf("%d. Repeat(", len(timeTools())+1)) {
+		t.Error("Prompt() doesn't list the registered tool")
+	}
+
+	got, err := tc.executeTool("ConvertTimeZones", "9:00 AM", "London", "Tokyo", "2024-06-10")
+	if err != nil || !strings.Contains(got, "Mon Jun 10 2024, 5:00 PM (17:00)") {
+		t.Errorf("executeTool(ConvertTimeZones) = %q, %v", got, err)
+	}
+}
@@ -0,0 +1,521 @@
+package time
+
+import (
+	"bufio"
+	"bytes"
+	"embed"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+//go:generate go run -C internal/gen .
+
+//go:embed data/cities.tsv data/countries.tsv data/airports.tsv
+var placeData embed.FS
+
+// PlaceKind says what a resolved name refers to
+type PlaceKind string
+
+const (
+	KindZone         PlaceKind = "zone"         // IANA name such as "Europe/Paris"
+	KindCity         PlaceKind = "city"         // city from the embedded dataset
+	KindCountry      PlaceKind = "country"      // country name or ISO code
+	KindAirport      PlaceKind = "airport"      // IATA airport code
+	KindAbbreviation PlaceKind = "abbreviation" // zone abbreviation such as "PST"
+)
+
+// Place is a location a query resolved to
+type Place struct {
+	Name    string    // display name, e.g. "Portland" or "JFK"
+	Region  string    // state or province when known, the city for airports
+	Country string    // ISO 3166 code, empty for zones and abbreviations
+	Zone    string    // IANA time zone
+	Kind    PlaceKind // what the query matched
+
+	rank   int  // population rank within the country, lower is bigger
+	served bool // has an airport in data/airports.tsv
+}
+
+// String describes the place, e.g. "Portland, Maine, US (America/New_York)"
+func (p Place) String() string {
+	parts := []string{p.Name}
+	if p.Region != "" {
+		parts = append(parts, p.Region)
+	}
+	if p.Country != "" && p.Kind != KindCountry {
+		parts = append(parts, p.Country)
+	}
+	name := strings.Join(parts, ", ")
+	if name == p.Zone {
+		return name
+	}
+	return fmt.Sprintf("%s (%s)", name, p.Zone)
+}
+
+// Qualified returns a name that resolves back to the place, e.g.
+// "Portland, Maine" or "Tokyo, JP"
+func (p Place) Qualified() string {
+	switch {
+	case p.Kind == KindCity && p.Region != "":
+		return p.Name + ", " + p.Region
+	case p.Kind == KindCity:
+		return p.Name + ", " + p.Country
+	case p.Kind == KindAirport:
+		return p.Name
+	default:
+		// Abbreviations and countries may span several zones
+		return p.Zone
+	}
+}
+
+// Location loads the time zone of the place
+func (p Place) Location() (*time.Location, error) {
+	return time.LoadLocation(p.Zone)
+}
+
+// AmbiguousError is returned when a name matches places in several time
+// zones, e.g. "Portland" in Oregon and Maine
+type AmbiguousError struct {
+	Query      string
+	Candidates []Place // best first, one per zone
+}
+
+// maxListedCandidates limits the candidates spelled out in error messages
+const maxListedCandidates = 5
+
+func (e *AmbiguousError) Error() string {
+	var names []string
+	for i, c := range e.Candidates {
+		if i == maxListedCandidates {
+			names = append(names, fmt.Sprintf("and %d more", len(e.Candidates)-i))
+			break
+		}
+		names = append(names, c.String())
+	}
+	return fmt.Sprintf("%q is ambiguous: %s", e.Query, strings.Join(names, "; "))
+}
+
+// UnknownLocationError is returned when nothing matches a name
+type UnknownLocationError struct {
+	Query       string
+	Suggestions []Suggestion // similar names, best first
+}
+
+func (e *UnknownLocationError) Error() string {
+	if len(e.Suggestions) == 0 {
+		return fmt.Sprintf("unknown location %q (try a city, country, airport code or IANA zone like 'America/New_York')", e.Query)
+	}
+	var names []string
+	for _, s := range e.Suggestions {
+		names = append(names, s.Place.String())
+	}
+	return fmt.Sprintf("unknown location %q, did you mean %s?", e.Query, strings.Join(names, " or "))
+}
+
+// maxSuggestions limits the alternatives offered for an unknown location
+const maxSuggestions = 5
+
+// dominance is how many times bigger (by rank within its country) the
+// best city must be than a same-named city in another zone to win
+// outright. Cities with a major airport always beat those without and
+// are never compared by rank, so "London" means England while "Portland"
+// asks about Oregon or Maine.
+const dominance = 10
+
+// placeAliases maps nicknames and alternate spellings to names in the
+// dataset. Qualified names such as "Portland, Maine" are resolved again.
+var placeAliases = map[string]string{
+	"nyc":           "new york city",
+	"new york":      "new york city",
+	"ny":            "new york city",
+	"la":            "los angeles",
+	"sf":            "san francisco",
+	"san fran":      "san francisco",
+	"dc":            "washington",
+	"washington dc": "washington",
+	"philly":        "philadelphia",
+	"vegas":         "las vegas",
+	"bombay":        "mumbai",
+	"bangalore":     "bengaluru",
+	"madras":        "chennai",
+	"calcutta":      "kolkata",
+	"peking":        "beijing",
+	"saigon":        "ho chi minh city",
+	"kiev":          "kyiv",
+	"st petersburg": "saint petersburg, ru",
+	"uk":            "gb",
+	"britain":       "gb",
+	"great britain": "gb",
+	"england":       "gb",
+	"scotland":      "gb",
+	"wales":         "gb",
+	"usa":           "us",
+	"america":       "us",
+	"holland":       "nl",
+	"utc":           "UTC",
+	"gmt":           "UTC",
+	"zulu":          "UTC",
+}
+
+// extraCities adds places missing from the generated dataset and regions
+// for well-known same-named cities
+var extraCities = []Place{
+	{Name: "Portland", Region: "Oregon", Country: "US", Zone: "America/Los_Angeles"},
+	{Name: "Portland", Region: "Maine", Country: "US", Zone: "America/New_York", rank: 120},
+}
+
+// zoneAbbreviations maps common abbreviations to the zones that use them.
+// Some are shared: IST is India, Israel and Ireland, CST is Central, China
+// and Cuba.
+var zoneAbbreviations = map[string][]string{
+	"utc":  {"UTC"},
+	"gmt":  {"UTC"},
+	"z":    {"UTC"},
+	"est":  {"America/New_York"},
+	"edt":  {"America/New_York"},
+	"et":   {"America/New_York"},
+	"cst":  {"America/Chicago", "Asia/Shanghai", "America/Havana"},
+	"cdt":  {"America/Chicago"},
+	"ct":   {"America/Chicago"},
+	"mst":  {"America/Denver", "America/Phoenix"},
+	"mdt":  {"America/Denver"},
+	"mt":   {"America/Denver"},
+	"pst":  {"America/Los_Angeles"},
+	"pdt":  {"America/Los_Angeles"},
+	"pt":   {"America/Los_Angeles"},
+	"akst": {"America/Anchorage"},
+	"akdt": {"America/Anchorage"},
+	"hst":  {"Pacific/Honolulu"},
+	"ast":  {"America/Halifax", "Asia/Riyadh"},
+	"adt":  {"America/Halifax"},
+	"nst":  {"America/St_Johns"},
+	"ndt":  {"America/St_Johns"},
+	"brt":  {"America/Sao_Paulo"},
+	"art":  {"America/Argentina/Buenos_Aires"},
+	"wet":  {"Europe/Lisbon"},
+	"west": {"Europe/Lisbon"},
+	"bst":  {"Europe/London"},
+	"ist":  {"Asia/Kolkata", "Asia/Jerusalem", "Europe/Dublin"},
+	"cet":  {"Europe/Paris"},
+	"cest": {"Europe/Paris"},
+	"eet":  {"Europe/Athens"},
+	"eest": {"Europe/Athens"},
+	"msk":  {"Europe/Moscow"},
+	"trt":  {"Europe/Istanbul"},
+	"gst":  {"Asia/Dubai"},
+	"pkt":  {"Asia/Karachi"},
+	"npt":  {"Asia/Kathmandu"},
+	"ict":  {"Asia/Bangkok"},
+	"wib":  {"Asia/Jakarta"},
+	"sgt":  {"Asia/Singapore"},
+	"hkt":  {"Asia/Hong_Kong"},
+	"pht":  {"Asia/Manila"},
+	"kst":  {"Asia/Seoul"},
+	"jst":  {"Asia/Tokyo"},
+	"awst": {"Australia/Perth"},
+	"acst": {"Australia/Adelaide"},
+	"acdt": {"Australia/Adelaide"},
+	"aest": {"Australia/Sydney", "Australia/Brisbane"},
+	"aedt": {"Australia/Sydney"},
+	"nzst": {"Pacific/Auckland"},
+	"nzdt": {"Pacific/Auckland"},
+	"sast": {"Africa/Johannesburg"},
+	"cat":  {"Africa/Maputo"},
+	"eat":  {"Africa/Nairobi"},
+	"wat":  {"Africa/Lagos"},
+}
+
+// Resolver turns free-form location names into time zones. It knows IANA
+// zone names, thousands of cities, countries, airport codes and zone
+// abbreviations.
+type Resolver struct {
+	cities    map[string][]Place // normalized name → cities
+	countries map[string][]Place // normalized name or code → one place per zone
+	airports  map[string]Place   // lowercase IATA code
+	zones     map[string]string  // lowercase IANA name → canonical name
+}
+
+var (
+	defaultResolver     *Resolver
+	defaultResolverErr  error
+	defaultResolverOnce sync.Once
+)
+
+// DefaultResolver returns the shared resolver over the embedded dataset
+func DefaultResolver() (*Resolver, error) {
+	defaultResolverOnce.Do(func() {
+		defaultResolver, defaultResolverErr = NewResolver()
+	})
+	return defaultResolver, defaultResolverErr
+}
+
+// ResolveLocation resolves a name with the default resolver
+func ResolveLocation(query string) (Place, error) {
+	r, err := DefaultResolver()
+	if err != nil {
+		return Place{}, err
+	}
+	return r.Resolve(query)
+}
+
+// NewResolver loads the embedded dataset
+func NewResolver() (*Resolver, error) {
+	r := &Resolver{
+		cities:    make(map[string][]Place),
+		countries: make(map[string][]Place),
+		airports:  make(map[string]Place),
+		zones:     map[string]string{"utc": "UTC"},
+	}
+
+	ranks := make(map[string]int)
+	err := readPlaceTable("data/cities.tsv", 3, func(f []string) {
+		ranks[f[1]]++
+		r.addCity(Place{Name: f[0], Country: f[1], Zone: f[2], Kind: KindCity, rank: ranks[f[1]]})
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range extraCities {
+		p.Kind = KindCity
+		r.addCity(p)
+	}
+
+	err = readPlaceTable("data/countries.tsv", 3, func(f []string) {
+		var places []Place
+		for _, zone := range strings.Split(f[2], ",") {
+			places = append(places, Place{Name: f[1], Country: f[0], Zone: zone, Kind: KindCountry})
+			r.zones[strings.ToLower(zone)] = zone
+		}
+		r.countries[normalizeName(f[0])] = places
+		r.countries[normalizeName(f[1])] = places
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = readPlaceTable("data/airports.tsv", 4, func(f []string) {
+		r.airports[strings.ToLower(f[0])] = Place{Name: f[0], Region: f[1], Country: f[2], Zone: f[3], Kind: KindAirport}
+		found := false
+		cities := r.cities[normalizeName(f[1])]
+		for i := range cities {
+			if cities[i].Country == f[2] {
+				cities[i].served, found = true, true
+			}
+		}
+		// The dataset lacks some countries and uses local names such as
+		// "Praha", so airports also add the cities they serve. They rank
+		// after the dataset's cities, in file order.
+		if !found {
+			ranks[f[2]]++
+			r.addCity(Place{Name: f[1], Country: f[2], Zone: f[3], Kind: KindCity, rank: ranks[f[2]], served: true})
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	// List each country's main zone first: the zone of its biggest city.
+	// Ties are broken by zone name since map order varies between runs.
+	mainZones := make(map[string]Place)
+	for _, p := range r.cities {
+		for _, c := range p {
+			r.zones[strings.ToLower(c.Zone)] = c.Zone
+			best, ok := mainZones[c.Country]
+			if !ok || moreProminent(c, best) || !moreProminent(best, c) && c.Zone < best.Zone {
+				mainZones[c.Country] = c
+			}
+		}
+	}
+	for _, places := range r.countries {
+		zone := mainZones[places[0].Country].Zone
+		sort.SliceStable(places, func(i, j int) bool {
+			return places[i].Zone == zone && places[j].Zone != zone
+		})
+	}
+
+	return r, nil
+}
+
+// addCity indexes a city; an entry for a known name, country and zone only
+// fills in the region and rank
+func (r *Resolver) addCity(p Place) {
+	key := normalizeName(p.Name)
+	for i, c := range r.cities[key] {
+		if c.Country == p.Country && c.Zone == p.Zone && c.Region == "" {
+			if p.Region != "" {
+				r.cities[key][i].Region = p.Region
+			}
+			if p.rank != 0 {
+				r.cities[key][i].rank = p.rank
+			}
+			return
+		}
+	}
+	if p.rank == 0 {
+		p.rank = 1
+	}
+	r.cities[key] = append(r.cities[key], p)
+}
+
+// readPlaceTable calls fn for each row of an embedded tab separated file
+func readPlaceTable(name string, columns int, fn func([]string)) error {
+	data, err := placeData.ReadFile(name)
+	if err != nil {
+		return err
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Split(text, "\t")
+		if len(fields) != columns {
+			return fmt.Errorf("%s:%d: want %d columns, got %d", name, line, columns, len(fields))
+		}
+		fn(fields)
+	}
+	return scanner.Err()
+}
+
+// Resolve returns the place a name refers to. Names may be qualified with
+// a region or country, e.g. "Portland, Maine" or "London, CA". It returns
+// an *AmbiguousError when the name matches several zones and none stands
+// out, and an *UnknownLocationError with suggestions when nothing matches.
+func (r *Resolver) Resolve(query string) (Place, error) {
+	candidates := r.Lookup(query)
+	if len(candidates) == 0 {
+		return Place{}, &UnknownLocationError{Query: strings.TrimSpace(query), Suggestions: r.Suggest(query, maxSuggestions)}
+	}
+	if len(candidates) > 1 {
+		return Place{}, &AmbiguousError{Query: strings.TrimSpace(query), Candidates: candidates}
+	}
+	return candidates[0], nil
+}
+
+// Lookup returns the places a name may refer to, best first and one per
+// zone. A single result means the name is unambiguous.
+func (r *Resolver) Lookup(query string) []Place {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil
+	}
+
+	// Exact IANA names, in any case
+	if zone, ok := r.zones[strings.ToLower(query)]; ok {
+		return []Place{{Name: zone, Zone: zone, Kind: KindZone}}
+	}
+	if _, err := time.LoadLocation(query); err == nil && strings.Contains(query, "/") {
+		return []Place{{Name: query, Zone: query, Kind: KindZone}}
+	}
+
+	name, qualifier, _ := strings.Cut(query, ",")
+	key := normalizeName(name)
+	if alias, ok := placeAliases[key]; ok {
+		if qualifier != "" {
+			// A qualifier in the query replaces the alias' own
+			alias, _, _ = strings.Cut(alias, ",")
+			alias += "," + qualifier
+		}
+		return r.Lookup(alias)
+	}
+	qualifier = normalizeName(qualifier)
+
+	if zones, ok := zoneAbbreviations[key]; ok && qualifier == "" {
+		var places []Place
+		for _, zone := range zones {
+			places = append(places, Place{Name: strings.ToUpper(key), Zone: zone, Kind: KindAbbreviation})
+		}
+		return places
+	}
+
+	if cities := r.qualified(r.cities[key], qualifier); len(cities) > 0 {
+		return pickCities(cities)
+	}
+	if qualifier == "" {
+		if countries, ok := r.countries[key]; ok {
+			return countries
+		}
+		if airport, ok := r.airports[key]; ok {
+			return []Place{airport}
+		}
+	}
+	return nil
+}
+
+// qualified keeps the places whose region or country matches the
+// qualifier, if any
+func (r *Resolver) qualified(places []Place, qualifier string) []Place {
+	if qualifier == "" {
+		return places
+	}
+	if alias, ok := placeAliases[qualifier]; ok {
+		qualifier = alias
+	}
+	country := ""
+	if c := r.countries[qualifier]; len(c) > 0 {
+		country = c[0].Country
+	}
+	var matched []Place
+	for _, p := range places {
+		if normalizeName(p.Region) == qualifier || p.Country == country {
+			matched = append(matched, p)
+		}
+	}
+	return matched
+}
+
+// pickCities reduces same-named cities to one per zone and drops the
+// alternatives when the most prominent city dominates
+func pickCities(cities []Place) []Place {
+	var picked []Place
+	seen := make(map[string]bool)
+	for _, c := range cities {
+		if !seen[c.Zone] {
+			seen[c.Zone] = true
+			picked = append(picked, c)
+		}
+	}
+	sort.SliceStable(picked, func(i, j int) bool {
+		return moreProminent(picked[i], picked[j])
+	})
+	if len(picked) > 1 {
+		first, second := picked[0], picked[1]
+		if first.served != second.served || !first.served && first.rank*dominance <= second.rank {
+			return picked[:1]
+		}
+	}
+	return picked
+}
+
+// moreProminent orders cities with an airport first, then by rank
+func moreProminent(a, b Place) bool {
+	if a.served != b.served {
+		return a.served
+	}
+	return a.rank < b.rank
+}
+
+// accentFolder maps the accented letters found in city names to ASCII
+var accentFolder = strings.NewReplacer(
+	"á", "a", "à", "a", "â", "a", "ä", "a", "ã", "a", "å", "a",
+	"é", "e", "è", "e", "ê", "e", "ë", "e",
+	"í", "i", "ì", "i", "î", "i", "ï", "i",
+	"ó", "o", "ò", "o", "ô", "o", "ö", "o", "õ", "o", "ø", "o",
+	"ú", "u", "ù", "u", "û", "u", "ü", "u",
+	"ç", "c", "ñ", "n", "ß", "ss", "æ", "ae",
+)
+
+// normalizeName lowercases a name, folds accents and spells out "St." so
+// "St. Louis", "st louis" and "Saint Louis" match
+func normalizeName(s string) string {
+	s = accentFolder.Replace(strings.ToLower(s))
+	s = strings.NewReplacer(".", " ", "-", " ", "'", "", "_", " ").Replace(s)
+	fields := strings.Fields(s)
+	if len(fields) > 1 && fields[0] == "st" {
+		fields[0] = "saint"
+	}
+	return strings.Join(fields, " ")
+}
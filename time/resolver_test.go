@@ -0,0 +1,134 @@
+package time
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		query    string
+		wantZone string
+		wantKind PlaceKind
+	}{
+		{"America/New_York", "America/New_York", KindZone},
+		{"asia/tokyo", "Asia/Tokyo", KindZone},
+		{"UTC", "UTC", KindZone},
+		{"Austin", "America/Chicago", KindCity},
+		{"sf", "America/Los_Angeles", KindCity},
+		{"NYC", "America/New_York", KindCity},
+		{"London", "Europe/London", KindCity},
+		{"London, Canada", "America/Toronto", KindCity},
+		{"London, CA", "America/Toronto", KindCity},
+		{"Portland, Maine", "America/New_York", KindCity},
+		{"St. Louis", "America/Chicago", KindCity},
+		{"Tromso", "Europe/Oslo", KindCity},
+		{"Kyiv", "Europe/Kyiv", KindCity},
+		{"Kiev", "Europe/Kyiv", KindCity},
+		{"Odesa", "Europe/Kyiv", KindCity},
+		{"Calcutta", "Asia/Kolkata", KindCity},
+		{"Thunder Bay", "America/Toronto", KindCity},
+		{"Japan", "Asia/Tokyo", KindCountry},
+		{"UK", "Europe/London", KindCountry},
+		{"JFK", "America/New_York", KindAirport},
+		{"lhr", "Europe/London", KindAirport},
+		{"PST", "America/Los_Angeles", KindAbbreviation},
+		{"cet", "Europe/Paris", KindAbbreviation},
+	}
+	r, err := NewResolver()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			got, err := r.Resolve(tt.query)
+			if err != nil {
+				t.Fatalf("Resolve(%q) error = %v", tt.query, err)
+			}
+			if got.Zone != tt.wantZone || got.Kind != tt.wantKind {
+				t.Errorf("Resolve(%q) = %s %s, want %s %s", tt.query, got.Kind, got.Zone, tt.wantKind, tt.wantZone)
+			}
+			if _, err := got.Location(); err != nil {
+				t.Errorf("Location() error = %v", err)
+			}
+			if again, err := r.Resolve(got.Qualified()); err != nil || again.Zone != got.Zone {
+				t.Errorf("Resolve(%q) = %v, %v; want %s", got.Qualified(), again, err, got.Zone)
+			}
+		})
+	}
+}
+
+func TestResolveAmbiguous(t *testing.T) {
+	tests := []struct {
+		query     string
+		wantZones []string
+	}{
+		{"Portland", []string{"America/Los_Angeles", "America/New_York"}},
+		{"IST", []string{"Asia/Kolkata", "Asia/Jerusalem", "Europe/Dublin"}},
+	}
+	r, err := NewResolver()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		_, err := r.Resolve(tt.query)
+		var ambiguous *AmbiguousError
+		if !errors.As(err, &ambiguous) {
+			t.Errorf("Resolve(%q) error = %v, want *AmbiguousError", tt.query, err)
+			continue
+		}
+		if len(ambiguous.Candidates) != len(tt.wantZones) {
+			t.Errorf("Resolve(%q) candidates = %v, want %v", tt.query, ambiguous.Candidates, tt.wantZones)
+			continue
+		}
+		for i, c := range ambiguous.Candidates {
+			if c.Zone != tt.wantZones[i] {
+				t.Errorf("Resolve(%q) candidate %d = %s, want %s", tt.query, i, c.Zone, tt.wantZones[i])
+			}
+		}
+	}
+
+	_, err = r.Resolve("xyzabc")
+	var unknown *UnknownLocationError
+	if !errors.As(err, &unknown) {
+		t.Errorf("Resolve(xyzabc) error = %v, want *UnknownLocationError", err)
+	}
+}
+
+func TestNormalizeName(t *testing.T) {
+	tests := map[string]string{
+		"  New   York ": "new york",
+		"St. Louis":     "saint louis",
+		"Tromsø":        "tromso",
+		"São Paulo":     "sao paulo",
+		"Winston-Salem": "winston salem",
+	}
+	for in, want := range tests {
+		if got := normalizeName(in); got != want {
+			t.Errorf("normalizeName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestCountryMainZone(t *testing.T) {
+	want := map[string]string{
+		"US":        "America/New_York",
+		"Spain":     "Europe/Madrid",
+		"Canada":    "America/Toronto",
+		"Australia": "Australia/Sydney",
+		"Russia":    "Europe/Moscow",
+		"Brazil":    "America/Sao_Paulo",
+	}
+	// Map iteration order differs between resolvers, so build several
+	for i := 0; i < 5; i++ {
+		r, err := NewResolver()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for country, zone := range want {
+			if places := r.Lookup(country); len(places) == 0 || places[0].Zone != zone {
+				t.Fatalf("Lookup(%q)[0] = %v, want zone %s", country, places, zone)
+			}
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package time
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -8,184 +9,176 @@ import (
 
 // GetCurrentTimeWithTools returns the current time in the specified location
 func GetCurrentTimeWithTools(location string) (string, error) {
-	loc, err := time.LoadLocation(location)
+	loc, err := loadLocation(location)
 	if err != nil {
-		// Try to map common city names to IANA zones
-		if mappedZone, ok := commonCityToZone[strings.ToLower(location)]; ok {
-			loc, err = time.LoadLocation(mappedZone)
-			if err != nil {
-				return "", fmt.Errorf("invalid location after mapping: %v", err)
-			}
-		} else {
-			return "", fmt.Errorf("invalid location: %v", err)
-		}
+		return "", fmt.Errorf("invalid location: %w", err)
 	}
 
 	now := time.Now().In(loc)
-	zoneName, offset := now.Zone()
+	zoneName, _ := now.Zone()
 	isDST := isDSTForLocation(now, loc)
 
 	// Format the response with both 12h and 24h time formats
-	return fmt.Sprintf("The current time in %s is %s %s (UTC%+d), DST is %s",
+	return fmt.Sprintf("The current time in %s is %s %s (%s), DST is %s",
 		strings.Title(location),
 		now.Format("3:04 PM (15:04)"),
 		zoneName,
-		offset/3600,
+		FormatOffset(ZoneOffset(now)),
 		map[bool]string{true: "in effect", false: "not in effect"}[isDST]), nil
 }
 
-// ConvertTimeZonesWithTools converts a time from one zone to another
+// ConvertTimeZonesWithTools converts a time today from one zone to another
 func ConvertTimeZonesWithTools(timeStr, fromZone, toZone string) (string, error) {
-	// Clean up input time string and zones
-	timeStr = strings.TrimSpace(timeStr)
-	timeStr = strings.TrimSuffix(timeStr, " UTC") // Remove UTC suffix if present
-	fromZone = strings.ToLower(fromZone)
-	toZone = strings.ToLower(toZone)
-
-	// Special handling for UTC
-	if fromZone == "utc" {
-		fromZone = "UTC"
-	}
-	if toZone == "utc" {
-		toZone = "UTC"
-	}
+	return ConvertTimeZonesOnDateWithTools(timeStr, "", fromZone, toZone)
+}
 
-	// Parse the input time
-	parsedTime, err := time.Parse("3:04 PM", timeStr)
+// ConvertTimeZonesOnDateWithTools converts a time on a date from one zone
+// to another. The date is read by ParseDate relative to today in the
+// source zone. Times skipped or repeated by a DST change are explained.
+func ConvertTimeZonesOnDateWithTools(timeStr, dateStr, fromZone, toZone string) (string, error) {
+	// Clean up input zones
+	fromZone = strings.TrimSpace(fromZone)
+	toZone = strings.TrimSpace(toZone)
+
+	clock, err := parseClockTime(timeStr)
 	if err != nil {
-		parsedTime, err = time.Parse("15:04", timeStr)
-		if err != nil {
-			return "", fmt.Errorf("invalid time format: please use either 12-hour (e.g., 2:00 PM) or 24-hour (e.g., 14:00) format")
-		}
+		return "", err
 	}
 
-	// Load source location
-	fromLoc, err := time.LoadLocation(fromZone)
+	// Load source and target locations
+	fromLoc, err := loadLocation(fromZone)
 	if err != nil {
-		if mappedZone, ok := commonCityToZone[fromZone]; ok {
-			fromLoc, err = time.LoadLocation(mappedZone)
-			if err != nil {
-				return "", fmt.Errorf("invalid source location after mapping: %v", err)
-			}
-		} else {
-			return "", fmt.Errorf("invalid source location: %v (try using a city name like 'New York' or IANA zone like 'America/New_York', or 'UTC')", err)
-		}
+		return "", fmt.Errorf("invalid source location: %w", err)
+	}
+	toLoc, err := loadLocation(toZone)
+	if err != nil {
+		return "", fmt.Errorf("invalid target location: %w", err)
 	}
 
-	// Load target location
-	toLoc, err := time.LoadLocation(toZone)
+	date, err := ParseDate(dateStr, time.Now().In(fromLoc))
 	if err != nil {
-		if mappedZone, ok := commonCityToZone[toZone]; ok {
-			toLoc, err = time.LoadLocation(mappedZone)
-			if err != nil {
-				return "", fmt.Errorf("invalid target location after mapping: %v", err)
-			}
-		} else {
-			return "", fmt.Errorf("invalid target location: %v (try using a city name like 'New York' or IANA zone like 'America/New_York', or 'UTC')", err)
-		}
+		return "", err
 	}
+	conv := ConvertLocalTime(date.Year(), date.Month(), date.Day(), clock.Hour(), clock.Minute(), fromLoc, toLoc)
 
-	// Set the time in the source location
-	now := time.Now()
-	sourceTime := time.Date(
-		now.Year(), now.Month(), now.Day(),
-		parsedTime.Hour(), parsedTime.Minute(), 0, 0,
-		fromLoc,
-	)
-
-	// Convert to target location
-	targetTime := sourceTime.In(toLoc)
-
-	// Get zone information
-	_, fromOffset := sourceTime.Zone()
-	_, toOffset := targetTime.Zone()
-
-	// Check if either location is in DST
-	fromDST := isDSTForLocation(sourceTime, fromLoc)
-	toDST := isDSTForLocation(targetTime, toLoc)
-
-	// Format the response
-	dayDiff := ""
-	if targetTime.Day() != sourceTime.Day() || targetTime.Month() != sourceTime.Month() {
-		if targetTime.Day() < sourceTime.Day() || targetTime.Month() < sourceTime.Month() {
-			dayDiff = " previous day"
-		} else {
-			dayDiff = " next day"
-		}
+	format := func(c Conversion) string { return formatConversion(c, fromZone, toZone) }
+	return format(conv) + dstNote(conv, clock, date, fromZone, format), nil
+}
+
+// dstNote explains a conversion of a local time that a DST change skips or
+// repeats, using format to show the second reading of a repeated time. It
+// returns "" for ordinary times.
+func dstNote(conv Conversion, clock, date time.Time, from string, format func(Conversion) string) string {
+	switch conv.Status {
+	case LocalTimeSkipped:
+		return fmt.Sprintf("\nNote: %s doesn't exist in %s on %s because the clocks skip forward, so %s is used.",
+			clock.Format("3:04 PM"), from, date.Format("Jan 2"), conv.From.Format("3:04 PM MST"))
+	case LocalTimeRepeated:
+		return fmt.Sprintf("\nNote: %s happens twice in %s on %s because the clocks go back. The second time is:\n%s",
+			clock.Format("3:04 PM"), from, date.Format("Jan 2"), format(*conv.Later))
 	}
+	return ""
+}
 
-	return fmt.Sprintf("%s %s (UTC%+d, DST %s) →\n%s %s (UTC%+d, DST %s)%s",
-		sourceTime.Format("3:04 PM (15:04)"),
+// formatConversion shows both sides of a conversion with their dates
+func formatConversion(conv Conversion, fromZone, toZone string) string {
+	const layout = "Mon Jan 2 2006, 3:04 PM (15:04)"
+	shift := describeDayShift(conv.DayShift)
+	if shift != "" {
+		shift = " " + shift
+	}
+	return fmt.Sprintf("%s %s %s (%s, DST %s) →\n%s %s %s (%s, DST %s)%s",
+		conv.From.Format(layout),
 		fromZone,
-		fromOffset/3600,
-		map[bool]string{true: "in effect", false: "not in effect"}[fromDST],
-		targetTime.Format("3:04 PM (15:04)"),
+		zoneAbbreviation(conv.From),
+		FormatOffset(ZoneOffset(conv.From)),
+		map[bool]string{true: "in effect", false: "not in effect"}[isDSTForLocation(conv.From, conv.From.Location())],
+		conv.To.Format(layout),
 		toZone,
-		toOffset/3600,
-		map[bool]string{true: "in effect", false: "not in effect"}[toDST],
-		dayDiff), nil
+		zoneAbbreviation(conv.To),
+		FormatOffset(ZoneOffset(conv.To)),
+		map[bool]string{true: "in effect", false: "not in effect"}[isDSTForLocation(conv.To, conv.To.Location())],
+		shift)
+}
+
+func zoneAbbreviation(t time.Time) string {
+	name, _ := t.Zone()
+	return name
 }
 
 // GetDetailedTimeZoneInfoWithTools returns detailed information about a time zone
 func GetDetailedTimeZoneInfoWithTools(location string) (string, error) {
-	loc, err := time.LoadLocation(location)
+	loc, err := loadLocation(location)
 	if err != nil {
-		if mappedZone, ok := commonCityToZone[strings.ToLower(location)]; ok {
-			loc, err = time.LoadLocation(mappedZone)
-			if err != nil {
-				return "", fmt.Errorf("invalid location after mapping: %v", err)
-			}
-		} else {
-			return "", fmt.Errorf("invalid location: %v", err)
-		}
+		return "", fmt.Errorf("invalid location: %w", err)
 	}
 
 	now := time.Now().In(loc)
-	zoneName, offset := now.Zone()
+	zoneName, _ := now.Zone()
 	isDST := isDSTForLocation(now, loc)
 
-	// Get next DST transition if any
-	nextTransition := getNextDSTTransition(now, loc)
+	// Get the next offset change, if any
 	transitionInfo := ""
-	if nextTransition != nil {
-		transitionInfo = fmt.Sprintf("\nNext DST transition: %s", nextTransition.Format("2006-01-02 15:04 MST"))
+	if next, ok := NextTransition(loc, now); ok {
+		transitionInfo = fmt.Sprintf("\nNext transition: %s", next)
 	}
 
-	return fmt.Sprintf("%s, UTC%+d, DST %s%s",
+	return fmt.Sprintf("%s, %s, DST %s%s",
 		zoneName,
-		offset/3600,
+		FormatOffset(ZoneOffset(now)),
 		map[bool]string{true: "in effect", false: "not in effect"}[isDST],
 		transitionInfo), nil
 }
 
 // ValidateLocationNameWithTools checks if a location name is valid and returns suggestions if not
 func ValidateLocationNameWithTools(location string) (bool, []string) {
-	// First check if it's a direct IANA zone
-	_, err := time.LoadLocation(location)
+	valid, suggestions := validateLocation(location)
+	var names []string
+	for _, s := range suggestions {
+		names = append(names, fmt.Sprintf("%s, confidence %.2f", s.Place, s.Score))
+	}
+	return valid, names
+}
+
+// validateLocation resolves a location. When that fails it returns the
+// candidates of an ambiguous name or places with similar names.
+func validateLocation(location string) (bool, []Suggestion) {
+	_, err := ResolveLocation(location)
 	if err == nil {
 		return true, nil
 	}
+	return false, LocationSuggestions(err)
+}
 
-	// Check if it's in our common city mappings
-	if zone, ok := commonCityToZone[strings.ToLower(location)]; ok {
-		_, err := time.LoadLocation(zone)
-		if err == nil {
-			return true, nil
+// LocationSuggestions returns the places offered by an error from
+// ResolveLocation: every candidate of an ambiguous name, or the names
+// similar to an unknown one
+func LocationSuggestions(err error) []Suggestion {
+	var ambiguous *AmbiguousError
+	if errors.As(err, &ambiguous) {
+		var suggestions []Suggestion
+		for i, c := range ambiguous.Candidates {
+			if i == maxSuggestions {
+				break
+			}
+			suggestions = append(suggestions, Suggestion{Place: c, Score: 1})
 		}
+		return suggestions
 	}
-
-	// If not found, generate suggestions
-	suggestions := []string{}
-	searchTerm := strings.ToLower(location)
-
-	// Search through common city mappings
-	for city, zone := range commonCityToZone {
-		if strings.Contains(city, searchTerm) || strings.Contains(zone, searchTerm) {
-			suggestions = append(suggestions, fmt.Sprintf("%s (%s)", strings.Title(city), zone))
-		}
+	var unknown *UnknownLocationError
+	if errors.As(err, &unknown) {
+		return unknown.Suggestions
 	}
+	return nil
+}
 
-	return false, suggestions
+// loadLocation resolves a location name and loads its time zone
+func loadLocation(location string) (*time.Location, error) {
+	place, err := ResolveLocation(location)
+	if err != nil {
+		return nil, err
+	}
+	return place.Location()
 }
 
 // Helper function to check if a time is in DST
@@ -201,52 +194,136 @@ func isDSTForLocation(t time.Time, loc *time.Location) bool {
 	return offset != janOffset
 }
 
-// Helper function to get the next DST transition
-func getNextDSTTransition(t time.Time, loc *time.Location) *time.Time {
-	// Look ahead up to a year
-	for i := 0; i < 365; i++ {
-		nextDay := t.AddDate(0, 0, i)
-		_, offset1 := nextDay.In(loc).Zone()
-		nextDayPlus := nextDay.AddDate(0, 0, 1)
-		_, offset2 := nextDayPlus.In(loc).Zone()
-
-		if offset1 != offset2 {
-			return &nextDay
-		}
+// timeTools are the tools every TimeCalculator offers the assistant
+func timeTools() []Tool {
+	durations := Param{Name: "durations", Required: true,
+		Description: `durations such as "2.5 hours" or "1h 20m, 45 min"`}
+	return []Tool{
+		&FuncTool{
+			ToolName: "GetCurrentTime",
+			Desc:     "Returns the current time in a location, its zone name and DST status.",
+			Parameters: []Param{
+				{Name: "location", Required: true, Description: "city, country, airport code or IANA time zone"},
+			},
+			ToolExamples: [][]string{{"New York"}},
+			Run: func(args Args) (string, error) {
+				return GetCurrentTimeWithTools(args.String("location"))
+			},
+		},
+		&FuncTool{
+			ToolName: "ConvertTimeZones",
+			Desc: "Converts a time between two locations. Returns the converted time and dates with zone details, " +
+				"day shifts, and a note when the time is skipped or repeated by a DST change.",
+			Parameters: []Param{
+				{Name: "time", Required: true, Description: `time of day, e.g. "2:30 PM" or "14:30"`},
+				{Name: "fromZone", Required: true, Description: "location the time is given in"},
+				{Name: "toZone", Required: true, Description: "location to convert to"},
+				{Name: "date", Description: "date in the source location (2025-03-10, March 10, tomorrow, friday); today if left out"},
+			},
+			ToolExamples: [][]string{{"2:30 PM", "New York", "Tokyo"}, {"9:00 AM", "London", "Sydney", "2025-04-06"}},
+			Run: func(args Args) (string, error) {
+				return ConvertTimeZonesOnDateWithTools(args.String("time"), args.String("date"),
+					args.String("fromZone"), args.String("toZone"))
+			},
+		},
+		&FuncTool{
+			ToolName: "GetDetailedTimeZoneInfo",
+			Desc:     "Returns the zone name, UTC offset, DST status and next transition of a location.",
+			Parameters: []Param{
+				{Name: "location", Required: true, Description: "city, country, airport code or IANA time zone"},
+			},
+			ToolExamples: [][]string{{"London"}},
+			Run: func(args Args) (string, error) {
+				return GetDetailedTimeZoneInfoWithTools(args.String("location"))
+			},
+		},
+		&FuncTool{
+			ToolName: "ValidateLocationName",
+			Desc:     "Checks whether a location name is known and unambiguous. Returns true, or false with suggestions.",
+			Parameters: []Param{
+				{Name: "location", Required: true, Description: "location name to check"},
+			},
+			ToolExamples: [][]string{{"NYC"}},
+			Run: func(args Args) (string, error) {
+				valid, suggestions := ValidateLocationNameWithTools(args.String("location"))
+				if valid {
+					return "true", nil
+				}
+				if len(suggestions) > 0 {
+					return fmt.Sprintf("false, suggestions: %s", strings.Join(suggestions, ", ")), nil
+				}
+				return "false", nil
+			},
+		},
+		&FuncTool{
+			ToolName: "AddDuration",
+			Desc:     "Adds durations to a time. Returns the resulting time and any day change.",
+			Parameters: []Param{
+				{Name: "time", Required: true, Description: "time, optionally with a date"},
+				durations,
+				{Name: "location", Description: "location of the time, to count real time across DST changes"},
+			},
+			ToolExamples: [][]string{{"3:15 PM", "2.5 hours"}},
+			Run: func(args Args) (string, error) {
+				return AddDurationWithTools(args.String("time"), args.String("durations"), args.String("location"))
+			},
+		},
+		&FuncTool{
+			ToolName: "SubtractDuration",
+			Desc:     "Subtracts durations from a time. Returns the earlier time and any day change.",
+			Parameters: []Param{
+				{Name: "time", Required: true, Description: "time, optionally with a date"},
+				durations,
+				{Name: "location", Description: "location of the time, to count real time across DST changes"},
+			},
+			ToolExamples: [][]string{{"7:00 PM", "45 minutes"}},
+			Run: func(args Args) (string, error) {
+				return SubtractDurationWithTools(args.String("time"), args.String("durations"), args.String("location"))
+			},
+		},
+		&FuncTool{
+			ToolName: "SumDurations",
+			Desc:     "Adds up durations. Returns the total.",
+			Parameters: []Param{
+				{Name: "durations", Required: true, Description: "durations separated by commas"},
+			},
+			ToolExamples: [][]string{{"1h 20m, 45 min, 2.5 hours"}},
+			Run: func(args Args) (string, error) {
+				return SumDurationsWithTools(args.String("durations"))
+			},
+		},
+		&FuncTool{
+			ToolName: "TimeDifference",
+			Desc:     "Returns the real time elapsed between two times in two locations.",
+			Parameters: []Param{
+				{Name: "fromTime", Required: true, Description: "first time, optionally with a date"},
+				{Name: "fromLocation", Required: true, Description: "location of the first time"},
+				{Name: "toTime", Required: true, Description: "second time, optionally with a date"},
+				{Name: "toLocation", Required: true, Description: "location of the second time"},
+			},
+			ToolExamples: [][]string{{"9:00 AM", "New York", "5:30 PM tomorrow", "London"}},
+			Run: func(args Args) (string, error) {
+				return TimeDifferenceWithTools(args.String("fromTime"), args.String("fromLocation"),
+					args.String("toTime"), args.String("toLocation"))
+			},
+		},
+		&FuncTool{
+			ToolName: "LeaveBy",
+			Desc:     "Plans backwards from an arrival time. Returns when to leave and when each step starts.",
+			Parameters: []Param{
+				{Name: "arrivalTime", Required: true, Description: "when to arrive, optionally with a date"},
+				{Name: "durations", Required: true, Description: "the labelled steps before arriving, in order"},
+				{Name: "arrivalLocation", Description: "where the trip ends, for travel across time zones"},
+				{Name: "departureLocation", Description: "where the trip starts, for travel across time zones"},
+			},
+			ToolExamples: [][]string{
+				{"7:45 AM", "breakfast 40 minutes, drive 1 hour, security 25 minutes"},
+				{"6:00 PM", "flight 7 hours", "London", "New York"},
+			},
+			Run: func(args Args) (string, error) {
+				return LeaveByWithTools(args.String("arrivalTime"), args.String("durations"),
+					args.String("arrivalLocation"), args.String("departureLocation"))
+			},
+		},
 	}
-	return nil
-}
-
-// Common city names mapped to IANA time zones
-var commonCityToZone = map[string]string{
-	"new york":     "America/New_York",
-	"nyc":          "America/New_York",
-	"london":       "Europe/London",
-	"paris":        "Europe/Paris",
-	"tokyo":        "Asia/Tokyo",
-	"sydney":       "Australia/Sydney",
-	"melbourne":    "Australia/Melbourne",
-	"singapore":    "Asia/Singapore",
-	"hong kong":    "Asia/Hong_Kong",
-	"berlin":       "Europe/Berlin",
-	"rome":         "Europe/Rome",
-	"madrid":       "Europe/Madrid",
-	"dubai":        "Asia/Dubai",
-	"moscow":       "Europe/Moscow",
-	"beijing":      "Asia/Shanghai",
-	"shanghai":     "Asia/Shanghai",
-	"los angeles":  "America/Los_Angeles",
-	"la":           "America/Los_Angeles",
-	"chicago":      "America/Chicago",
-	"toronto":      "America/Toronto",
-	"vancouver":    "America/Vancouver",
-	"sao paulo":    "America/Sao_Paulo",
-	"mexico city":  "America/Mexico_City",
-	"mumbai":       "Asia/Kolkata",
-	"delhi":        "Asia/Kolkata",
-	"bangkok":      "Asia/Bangkok",
-	"cairo":        "Africa/Cairo",
-	"johannesburg": "Africa/Johannesburg",
-	"auckland":     "Pacific/Auckland",
-	"utc":          "UTC", // Add UTC as a valid zone
 }
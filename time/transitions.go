@@ -0,0 +1,74 @@
+package time
+
+import (
+	"fmt"
+	"time"
+)
+
+// Transition is a change of a zone's UTC offset or abbreviation, such as
+// the start or end of daylight saving time
+type Transition struct {
+	At        time.Time     // first instant of the new offset, in UTC
+	OldName   string        // abbreviation before, e.g. "EDT"
+	OldOffset time.Duration // offset from UTC before
+	NewName   string
+	NewOffset time.Duration
+}
+
+// String describes the transition in the zone's local time, e.g.
+// "2024-11-03 02:00 EDT (UTC-04:00) → 01:00 EST (UTC-05:00)"
+func (t Transition) String() string {
+	before := t.At.Add(t.OldOffset).UTC()
+	after := t.At.Add(t.NewOffset).UTC()
+	return fmt.Sprintf("%s %s (%s) → %s %s (%s)",
+		before.Format("2006-01-02 15:04"), t.OldName, FormatOffset(t.OldOffset),
+		after.Format("15:04"), t.NewName, FormatOffset(t.NewOffset))
+}
+
+// nextTransitionWindow bounds NextTransition; zones with daylight saving
+// time change at least once within it
+const nextTransitionWindow = 400 * 24 * time.Hour
+
+// NextTransition returns the first transition of loc after t, looking up
+// to a little over a year ahead
+func NextTransition(loc *time.Location, t time.Time) (Transition, bool) {
+	transitions := findTransitions(loc, t, t.Add(nextTransitionWindow), 1)
+	if len(transitions) == 0 {
+		return Transition{}, false
+	}
+	return transitions[0], true
+}
+
+// TransitionsBetween lists the transitions of loc in (from, to]
+func TransitionsBetween(loc *time.Location, from, to time.Time) []Transition {
+	return findTransitions(loc, from, to, -1)
+}
+
+// findTransitions walks the zone periods of loc from one bound to the next,
+// reporting those that change the abbreviation or offset. A negative limit
+// means no limit.
+func findTransitions(loc *time.Location, from, to time.Time, limit int) []Transition {
+	var transitions []Transition
+	t := from.In(loc)
+	name, offset := t.Zone()
+	for limit != 0 {
+		// end is zero when the zone never changes again
+		_, end := t.ZoneBounds()
+		if end.IsZero() || end.After(to) {
+			break
+		}
+		newName, newOffset := end.Zone()
+		if newName != name || newOffset != offset {
+			transitions = append(transitions, Transition{
+				At:        end.UTC(),
+				OldName:   name,
+				OldOffset: time.Duration(offset) * time.Second,
+				NewName:   newName,
+				NewOffset: time.Duration(newOffset) * time.Second,
+			})
+			limit--
+		}
+		t, name, offset = end, newName, newOffset
+	}
+	return transitions
+}
@@ -0,0 +1,140 @@
+package time
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNextTransition(t *testing.T) {
+	tests := []struct {
+		zone      string
+		from      time.Time
+		want      time.Time
+		oldName   string
+		oldOffset time.Duration
+		newName   string
+		newOffset time.Duration
+	}{
+		{"America/New_York", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			time.Date(2024, 3, 10, 7, 0, 0, 0, time.UTC), "EST", -5 * time.Hour, "EDT", -4 * time.Hour},
+		{"America/New_York", time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC),
+			time.Date(2024, 11, 3, 6, 0, 0, 0, time.UTC), "EDT", -4 * time.Hour, "EST", -5 * time.Hour},
+		{"Europe/London", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			time.Date(2024, 3, 31, 1, 0, 0, 0, time.UTC), "GMT", 0, "BST", time.Hour},
+		{"Europe/London", time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC),
+			time.Date(2024, 10, 27, 1, 0, 0, 0, time.UTC), "BST", time.Hour, "GMT", 0},
+		{"Australia/Sydney", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			time.Date(2024, 4, 6, 16, 0, 0, 0, time.UTC), "AEDT", 11 * time.Hour, "AEST", 10 * time.Hour},
+		{"Australia/Sydney", time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC),
+			time.Date(2024, 10, 5, 16, 0, 0, 0, time.UTC), "AEST", 10 * time.Hour, "AEDT", 11 * time.Hour},
+		// Lord Howe Island moves by half an hour
+		{"Australia/Lord_Howe", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			time.Date(2024, 4, 6, 15, 0, 0, 0, time.UTC), "+11", 11 * time.Hour, "+1030", 10*time.Hour + 30*time.Minute},
+		// Chatham Islands are 45 minutes ahead of New Zealand
+		{"Pacific/Chatham", time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC),
+			time.Date(2024, 9, 28, 14, 0, 0, 0, time.UTC), "+1245", 12*time.Hour + 45*time.Minute, "+1345", 13*time.Hour + 45*time.Minute},
+		// Moscow left permanent summer time in 2014
+		{"Europe/Moscow", time.Date(2014, 1, 1, 0, 0, 0, 0, time.UTC),
+			time.Date(2014, 10, 25, 22, 0, 0, 0, time.UTC), "MSK", 4 * time.Hour, "MSK", 3 * time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.zone+" "+tt.from.Format("2006-01"), func(t *testing.T) {
+			loc, err := time.LoadLocation(tt.zone)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, ok := NextTransition(loc, tt.from)
+			if !ok {
+				t.Fatal("NextTransition() found no transition")
+			}
+			if !got.At.Equal(tt.want) {
+				t.Errorf("At = %v, want %v", got.At, tt.want)
+			}
+			if got.OldName != tt.oldName || got.OldOffset != tt.oldOffset ||
+				got.NewName != tt.newName || got.NewOffset != tt.newOffset {
+				t.Errorf("got %s %d → %s %d, want %s %d → %s %d",
+					got.OldName, got.OldOffset, got.NewName, got.NewOffset,
+					tt.oldName, tt.oldOffset, tt.newName, tt.newOffset)
+			}
+
+			// The standard library knows the same boundary
+			_, end := got.At.Add(-time.Second).In(loc).ZoneBounds()
+			if !end.Equal(got.At) {
+				t.Errorf("ZoneBounds end = %v, want %v", end, got.At)
+			}
+		})
+	}
+}
+
+func TestNextTransitionNoDST(t *testing.T) {
+	for _, zone := range []string{"Asia/Kolkata", "Asia/Tokyo", "UTC"} {
+		loc, err := time.LoadLocation(zone)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, ok := NextTransition(loc, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)); ok {
+			t.Errorf("%s: NextTransition() = %v, want none", zone, got)
+		}
+	}
+}
+
+func TestTransitionsBetween(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := TransitionsBetween(loc,
+		time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	want := []time.Time{
+		time.Date(2023, 3, 26, 1, 0, 0, 0, time.UTC),
+		time.Date(2023, 10, 29, 1, 0, 0, 0, time.UTC),
+		time.Date(2024, 3, 31, 1, 0, 0, 0, time.UTC),
+		time.Date(2024, 10, 27, 1, 0, 0, 0, time.UTC),
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d transitions, want %d: %v", len(got), len(want), got)
+	}
+	for i := range want {
+		if !got[i].At.Equal(want[i]) {
+			t.Errorf("transition %d at %v, want %v", i, got[i].At, want[i])
+		}
+	}
+}
+
+func TestTransitionsBetweenBounds(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	spring := time.Date(2024, 3, 31, 1, 0, 0, 0, time.UTC)
+	autumn := time.Date(2024, 10, 27, 1, 0, 0, 0, time.UTC)
+
+	// The range excludes its start and includes its end
+	if got := TransitionsBetween(loc, spring, autumn); len(got) != 1 || !got[0].At.Equal(autumn) {
+		t.Errorf("TransitionsBetween(spring, autumn) = %v, want only the autumn change", got)
+	}
+	// Changes just after the start are found to the second
+	if got := TransitionsBetween(loc, spring.Add(-time.Second), spring); len(got) != 1 || !got[0].At.Equal(spring) {
+		t.Errorf("TransitionsBetween() over one second = %v", got)
+	}
+	if got := TransitionsBetween(time.UTC, spring, autumn); len(got) != 0 {
+		t.Errorf("TransitionsBetween(UTC) = %v", got)
+	}
+}
+
+func TestTransitionString(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, ok := NextTransition(loc, time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC))
+	if !ok {
+		t.Fatal("no transition")
+	}
+	want := "2024-11-03 02:00 EDT (UTC-04:00) → 01:00 EST (UTC-05:00)"
+	if got.String() != want {
+		t.Errorf("String() = %q, want %q", got.String(), want)
+	}
+}
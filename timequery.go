@@ -0,0 +1,178 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	tgbot "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	timecalc "github.com/jgabriele321/onmymind/time"
+)
+
+// timeSuggestionsTimeout is how long "Did you mean" buttons work
+const timeSuggestionsTimeout = 30 * time.Minute
+
+// timeSuggestion holds the /time queries offered by one keyboard of
+// "Did you mean" buttons
+type timeSuggestion struct {
+	ChatID    int64
+	Queries   []string
+	CreatedAt time.Time
+}
+
+var (
+	// Keyboard ID → suggested queries. The ID is part of the callback data
+	// so that buttons on an old message never run a newer suggestion.
+	timeSuggestions    = make(map[int64]timeSuggestion)
+	lastTimeSuggestion int64
+	tsMutex            = &sync.Mutex{} // Protects timeSuggestions and lastTimeSuggestion
+)
+
+// runTimeQuery answers a /time query. When a location is ambiguous or
+// misspelled it also returns "Did you mean" buttons that re-run the query
+// with a suggested location.
+func runTimeQuery(budget *llmBudget, prefs *userPrefs, chatID, userID int64, query string) (string, *tgbot.InlineKeyboardMarkup) {
+	// Fall back to the deterministic tools once the budget is used up
+	calc := timeCalculator
+	note := ""
+	if calc.HasLLM() {
+		if reason, err := budget.exceeded(); err != nil {
+			log.Printf("Error checking LLM budget: %v", err)
+		} else if reason != "" {
+			calc = timecalc.NewTimeCalculator("")
+			note = fmt.Sprintf("\n\n(AI %s reached, using basic tools)", reason)
+		}
+	}
+
+	response, usage, err := calc.ProcessQueryWithContext(query, prefs.queryContext(userID))
+	if usage.TotalTokens > 0 || usage.Cost > 0 {
+		budget.record(chatID, userID, "time", usage)
+	}
+	if err != nil {
+		log.Printf("Error processing time query: %v", err)
+		return fmt.Sprintf("Error: %v", err) + note, timeSuggestionKeyboard(chatID, query, err)
+	}
+	return response + note, nil
+}
+
+// timeSuggestionKeyboard offers the places suggested by a location error,
+// or returns nil if there are none
+func timeSuggestionKeyboard(chatID int64, query string, err error) *tgbot.InlineKeyboardMarkup {
+	suggestions := timecalc.LocationSuggestions(err)
+	name := failedLocation(err)
+	if len(suggestions) == 0 || name == "" || !strings.Contains(query, name) {
+		return nil
+	}
+
+	now := time.Now()
+	tsMutex.Lock()
+	lastTimeSuggestion++
+	id := lastTimeSuggestion
+	// Drop keyboards nobody used
+	for k, ts := range timeSuggestions {
+		if now.Sub(ts.CreatedAt) > timeSuggestionsTimeout {
+			delete(timeSuggestions, k)
+		}
+	}
+	tsMutex.Unlock()
+
+	var queries []string
+	var rows [][]tgbot.InlineKeyboardButton
+	for i, s := range suggestions {
+		label := s.Place.String()
+		if s.Score < 1 {
+			label = fmt.Sprintf("%s · %.0f%%", label, s.Score*100)
+		}
+		queries = append(queries, strings.Replace(query, name, s.Place.Qualified(), 1))
+		rows = append(rows, tgbot.NewInlineKeyboardRow(
+			tgbot.NewInlineKeyboardButtonData(label, fmt.Sprintf("time:%d:%d", id, i))))
+	}
+
+	tsMutex.Lock()
+	timeSuggestions[id] = timeSuggestion{ChatID: chatID, Queries: queries, CreatedAt: now}
+	tsMutex.Unlock()
+
+	markup := tgbot.NewInlineKeyboardMarkup(rows...)
+	return &markup
+}
+
+// takeTimeSuggestion returns the query behind the callback data of a
+// "Did you mean" button pressed in chatID. Using one button expires the
+// others on the same keyboard.
+func takeTimeSuggestion(chatID int64, data string, now time.Time) (string, bool) {
+	return lookupTimeSuggestion(chatID, data, now, true)
+}
+
+// hasTimeSuggestion reports whether a button still works, without using it
+func hasTimeSuggestion(chatID int64, data string, now time.Time) bool {
+	_, ok := lookupTimeSuggestion(chatID, data, now, false)
+	return ok
+}
+
+func lookupTimeSuggestion(chatID int64, data string, now time.Time, take bool) (string, bool) {
+	var id int64
+	var index int
+	if _, err := fmt.Sscanf(data, "time:%d:%d", &id, &index); err != nil {
+		return "", false
+	}
+
+	tsMutex.Lock()
+	defer tsMutex.Unlock()
+	ts, ok := timeSuggestions[id]
+	if !ok || ts.ChatID != chatID || index < 0 || index >= len(ts.Queries) {
+		return "", false
+	}
+	expired := now.Sub(ts.CreatedAt) > timeSuggestionsTimeout
+	if take || expired {
+		delete(timeSuggestions, id)
+	}
+	if expired {
+		return "", false
+	}
+	return ts.Queries[index], true
+}
+
+// failedLocation returns the location name a resolver error is about
+func failedLocation(err error) string {
+	var ambiguous *timecalc.AmbiguousError
+	if errors.As(err, &ambiguous) {
+		return ambiguous.Query
+	}
+	var unknown *timecalc.UnknownLocationError
+	if errors.As(err, &unknown) {
+		return unknown.Query
+	}
+	return ""
+}
+
+// handleTimeCallback runs the query behind a "Did you mean" button and
+// shows the answer in place of the error. Running the query counts
+// against the /time rate limit like the command.
+func handleTimeCallback(bot *tgbot.BotAPI, q *tgbot.CallbackQuery, limiter *rateLimiter, budget *llmBudget, prefs *userPrefs) {
+	chatID := q.Message.Chat.ID
+	answer := tgbot.NewCallback(q.ID, "")
+
+	// Expired buttons don't use up the rate limit, and limited ones keep
+	// working once the wait is over
+	const expired = "This suggestion has expired, please ask again."
+	if !hasTimeSuggestion(chatID, q.Data, time.Now()) {
+		answer.Text = expired
+	} else if ok, wait := limiter.Allow(chatID, "time"); !ok {
+		answer.Text = fmt.Sprintf("⏳ Slow down! Try again in %s.", formatWait(wait))
+	} else if query, ok := takeTimeSuggestion(chatID, q.Data, time.Now()); !ok {
+		answer.Text = expired
+	} else {
+		text, markup := runTimeQuery(budget, prefs, chatID, q.From.ID, query)
+		edit := tgbot.NewEditMessageText(chatID, q.Message.MessageID, text)
+		edit.ReplyMarkup = markup
+		if _, err := bot.Send(edit); err != nil {
+			log.Printf("Error editing message: %v", err)
+		}
+	}
+	if _, err := bot.Request(answer); err != nil {
+		log.Printf("Error answering callback: %v", err)
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	timecalc "github.com/jgabriele321/onmymind/time"
+)
+
+func TestTimeSuggestions(t *testing.T) {
+	old := timeCalculator
+	timeCalculator = timecalc.NewTimeCalculator("")
+	defer func() { timeCalculator = old }()
+
+	const chat = 7
+	text, markup := runTimeQuery(nil, nil, chat, chat, "2pm Tokio to London")
+	if !strings.HasPrefix(text, "Error:") || markup == nil {
+		t.Fatalf("runTimeQuery() = %q, %v; want an error with suggestions", text, markup)
+	}
+	first := markup.InlineKeyboard[0][0]
+	if !strings.HasPrefix(first.Text, "Tokyo, JP") || !strings.HasSuffix(*first.CallbackData, ":0") {
+		t.Errorf("first button = %q (%s)", first.Text, *first.CallbackData)
+	}
+
+	// Buttons only work in their chat and only once
+	now := time.Now()
+	if !hasTimeSuggestion(chat, *first.CallbackData, now) || !hasTimeSuggestion(chat, *first.CallbackData, now) {
+		t.Error("hasTimeSuggestion() should report a live button without using it")
+	}
+	if _, ok := takeTimeSuggestion(chat+1, *first.CallbackData, now); ok {
+		t.Error("suggestion used from another chat")
+	}
+	query, ok := takeTimeSuggestion(chat, *first.CallbackData, now)
+	if !ok || query != "2pm Tokyo, JP to London" {
+		t.Fatalf("takeTimeSuggestion() = %q, %v", query, ok)
+	}
+	if _, ok := takeTimeSuggestion(chat, *markup.InlineKeyboard[1][0].CallbackData, now); ok {
+		t.Error("second button still works after the first was used")
+	}
+	if text, markup := runTimeQuery(nil, nil, chat, chat, query); !strings.Contains(text, "→") || markup != nil {
+		t.Errorf("suggested query = %q, %v", text, markup)
+	}
+
+	// Ambiguous names offer every candidate without a score
+	_, markup = runTimeQuery(nil, nil, chat, chat, "time in Portland")
+	if markup == nil || len(markup.InlineKeyboard) != 2 || strings.Contains(markup.InlineKeyboard[0][0].Text, "%") {
+		t.Errorf("ambiguous keyboard = %+v", markup)
+	}
+
+	if _, markup := runTimeQuery(nil, nil, chat, chat, "how long until my flight"); markup != nil {
+		t.Errorf("keyboard for an unrelated error = %+v", markup)
+	}
+
+	// A newer keyboard doesn't answer the buttons of an older one, and
+	// buttons expire
+	_, older := runTimeQuery(nil, nil, chat, chat, "2pm Tokio to London")
+	_, newer := runTimeQuery(nil, nil, chat, chat, "time in Portland")
+	if query, ok := takeTimeSuggestion(chat, *older.InlineKeyboard[0][0].CallbackData, now); !ok || !strings.Contains(query, "Tokyo") {
+		t.Errorf("older button = %q, %v", query, ok)
+	}
+	if hasTimeSuggestion(chat, *newer.InlineKeyboard[0][0].CallbackData, now.Add(timeSuggestionsTimeout+time.Minute)) {
+		t.Error("hasTimeSuggestion() reported an expired button")
+	}
+	if _, ok := takeTimeSuggestion(chat, *newer.InlineKeyboard[0][0].CallbackData, now.Add(timeSuggestionsTimeout+time.Minute)); ok {
+		t.Error("expired suggestion was used")
+	}
+	for _, data := range []string{"time:0", "time:x:0", "time:1:-1"} {
+		if _, ok := takeTimeSuggestion(chat, data, now); ok {
+			t.Errorf("takeTimeSuggestion(%q) succeeded", data)
+		}
+	}
+}
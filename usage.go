@@ -0,0 +1,142 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	timecalc "github.com/jgabriele321/onmymind/time"
+)
+
+// usageTotals sums LLM consumption over a period
+type usageTotals struct {
+	Calls  int
+	Tokens int
+	Cost   float64
+}
+
+// llmBudget records OpenRouter usage in SQLite and enforces the daily and
+// monthly token and cost budgets from the configuration.
+type llmBudget struct {
+	db            *sql.DB
+	dailyTokens   int
+	monthlyTokens int
+	dailyCost     float64
+	monthlyCost   float64
+	now           func() time.Time
+}
+
+func newLLMBudget(db *sql.DB, cfg *Config) *llmBudget {
+	return &llmBudget{
+		db:            db,
+		dailyTokens:   cfg.LLMDailyTokenBudget,
+		monthlyTokens: cfg.LLMMonthlyTokenBudget,
+		dailyCost:     cfg.LLMDailyCostBudget,
+		monthlyCost:   cfg.LLMMonthlyCostBudget,
+		now:           time.Now,
+	}
+}
+
+// periodStarts returns the start of the current UTC day and month
+func (b *llmBudget) periodStarts() (day, month time.Time) {
+	now := b.now().UTC()
+	day = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	month = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return day, month
+}
+
+// record stores the usage of one LLM call
+func (b *llmBudget) record(chatID, userID int64, command string, u timecalc.Usage) {
+	if _, err := b.db.Exec(`
+		INSERT INTO llm_usage (chat_id, user_id, command, model, prompt_tokens, completion_tokens, total_tokens, cost, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		chatID, userID, command, u.Model, u.PromptTokens, u.CompletionTokens, u.TotalTokens, u.Cost, b.now().UTC()); err != nil {
+		log.Printf("Error recording LLM usage: %v", err)
+	}
+}
+
+// totals sums usage since the given time; userID 0 means all users
+func (b *llmBudget) totals(since time.Time, userID int64) (usageTotals, error) {
+	query := "SELECT COUNT(*), COALESCE(SUM(total_tokens), 0), COALESCE(SUM(cost), 0) FROM llm_usage WHERE created_at >= ?"
+	args := []interface{}{since}
+	if userID != 0 {
+		query += " AND user_id = ?"
+		args = append(args, userID)
+	}
+
+	var t usageTotals
+	err := b.db.QueryRow(query, args...).Scan(&t.Calls, &t.Tokens, &t.Cost)
+	return t, err
+}
+
+// exceeded reports which budget, if any, is used up
+func (b *llmBudget) exceeded() (string, error) {
+	day, month := b.periodStarts()
+
+	today, err := b.totals(day, 0)
+	if err != nil {
+		return "", err
+	}
+	if b.dailyTokens > 0 && today.Tokens >= b.dailyTokens {
+		return "daily token budget", nil
+	}
+	if b.dailyCost > 0 && today.Cost >= b.dailyCost {
+		return "daily cost budget", nil
+	}
+
+	thisMonth, err := b.totals(month, 0)
+	if err != nil {
+		return "", err
+	}
+	if b.monthlyTokens > 0 && thisMonth.Tokens >= b.monthlyTokens {
+		return "monthly token budget", nil
+	}
+	if b.monthlyCost > 0 && thisMonth.Cost >= b.monthlyCost {
+		return "monthly cost budget", nil
+	}
+	return "", nil
+}
+
+// handleUsage implements /usage
+func (b *llmBudget) handleUsage(userID int64) string {
+	day, month := b.periodStarts()
+
+	mineToday, err1 := b.totals(day, userID)
+	mineMonth, err2 := b.totals(month, userID)
+	allToday, err3 := b.totals(day, 0)
+	allMonth, err4 := b.totals(month, 0)
+	for _, err := range []error{err1, err2, err3, err4} {
+		if err != nil {
+			log.Printf("Error reading LLM usage: %v", err)
+			return "Failed to read usage."
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString("📊 AI usage\n\n")
+	fmt.Fprintf(&sb, "You today: %s\n", formatTotals(mineToday))
+	fmt.Fprintf(&sb, "You this month: %s\n\n", formatTotals(mineMonth))
+	fmt.Fprintf(&sb, "Bot today: %s%s\n", formatTotals(allToday), formatLimit(b.dailyTokens, b.dailyCost))
+	fmt.Fprintf(&sb, "Bot this month: %s%s", formatTotals(allMonth), formatLimit(b.monthlyTokens, b.monthlyCost))
+	return sb.String()
+}
+
+func formatTotals(t usageTotals) string {
+	return fmt.Sprintf("%d calls, %d tokens, $%.4f", t.Calls, t.Tokens, t.Cost)
+}
+
+func formatLimit(tokens int, cost float64) string {
+	var limits []string
+	if tokens > 0 {
+		limits = append(limits, fmt.Sprintf("%d tokens", tokens))
+	}
+	if cost > 0 {
+		limits = append(limits, fmt.Sprintf("$%.2f", cost))
+	}
+	if len(limits) == 0 {
+		return ""
+	}
+	return " (budget " + strings.Join(limits, ", ") + ")"
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	tgbot "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// transcribeTimeout bounds download plus transcription of one voice note
+const transcribeTimeout = 3 * time.Minute
+
+// isTranscribable reports whether a media item holds recorded speech
+func isTranscribable(media *mediaInfo) bool {
+	return media != nil && (media.Kind == kindVoice || media.Kind == kindAudio)
+}
+
+// audioFilename gives the transcription API a name that reveals the format
+func audioFilename(media *mediaInfo) string {
+	if media.FileName != "" {
+		return media.FileName
+	}
+	if ext := extensionFor(media.MimeType); ext != "" {
+		return media.Kind + ext
+	}
+	// Telegram voice notes are OGG/Opus
+	return "voice.ogg"
+}
+
+// errTranscriptDiscarded is returned by transcribeItem when the item was
+// edited or deleted during the transcription
+var errTranscriptDiscarded = errors.New("item changed during transcription")
+
+// transcribeItem downloads the audio of an item, transcribes it and stores
+// the transcript as the item text (after the caption, if any). The item is
+// only updated while its text is still the caption. It returns the new
+// text.
+func transcribeItem(ctx context.Context, fetch func(fileID string) ([]byte, error), itemID int64, media *mediaInfo, caption string) (string, error) {
+	audio, err := fetch(media.FileID)
+	if err != nil {
+		return "", err
+	}
+
+	transcript, err := transcriber.Transcribe(ctx, audio, audioFilename(media))
+	if err != nil {
+		return "", fmt.Errorf("transcription failed: %v", err)
+	}
+	if transcript == "" {
+		return "", fmt.Errorf("no speech recognized")
+	}
+
+	text := transcript
+	if caption != "" {
+		text = caption + "\n\n" + transcript
+	}
+	ok, err := store.ReplaceText(itemID, caption, text)
+	if err != nil {
+		return "", fmt.Errorf("failed to store transcript: %v", err)
+	}
+	if !ok {
+		return "", errTranscriptDiscarded
+	}
+	return text, nil
+}
+
+// transcribeInBackground transcribes a saved voice item and updates the
+// confirmation message once the transcript is ready
+func transcribeInBackground(bot *tgbot.BotAPI, confirmation tgbot.Message, itemID int64, media *mediaInfo, caption string) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), transcribeTimeout)
+		defer cancel()
+
+		fetch := func(fileID string) ([]byte, error) {
+			return downloadTelegramFile(bot, fileID)
+		}
+
+		status := ""
+		text, err := transcribeItem(ctx, fetch, itemID, media, caption)
+		if err == errTranscriptDiscarded {
+			// Keep the confirmation as the user left it, e.g. after Undo
+			log.Printf("Discarded transcript of item %d: %v", itemID, err)
+			return
+		} else if err != nil {
+			log.Printf("Error transcribing item %d: %v", itemID, err)
+			status = fmt.Sprintf("📥 Saved %s#%d (transcription failed, audio kept)", kindIcon(media.Kind), itemID)
+		} else {
+			status = fmt.Sprintf("📥 Saved %s#%d\n\n📝 %s", kindIcon(media.Kind), itemID, text)
+		}
+
+		edit := tgbot.NewEditMessageTextAndMarkup(confirmation.Chat.ID, confirmation.MessageID, status, inboxKeyboard(itemID))
+		if _, err := bot.Send(edit); err != nil {
+			log.Printf("Error editing message: %v", err)
+		}
+	}()
+}
+
+// transcribingSuffix is appended to the confirmation while the transcript
+// is pending
+func transcribingSuffix(media *mediaInfo) string {
+	if transcriber == nil || !isTranscribable(media) {
+		return ""
+	}
+	return " — transcribing…"
+}
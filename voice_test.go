@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jgabriele321/onmymind/speech"
+)
+
+func TestTranscribeItem(t *testing.T) {
+	if err := initDB(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	fake := &speech.Fake{Text: "pick up the dry cleaning"}
+	transcriber = fake
+	defer func() { transcriber = nil }()
+
+	media := &mediaInfo{Kind: kindVoice, FileID: "voice-1", MimeType: "audio/ogg", Duration: 4}
+	id, err := storeMediaItem(capturedItem{Text: "errands", Source: sourceMessage}, media, 1, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fetched := ""
+	fetch := func(fileID string) ([]byte, error) {
+		fetched = fileID
+		return []byte("ogg-data"), nil
+	}
+
+	text, err := transcribeItem(context.Background(), fetch, id, media, "errands")
+	if err != nil {
+		t.Fatalf("transcribeItem() error = %v", err)
+	}
+	if text != "errands\n\npick up the dry cleaning" {
+		t.Errorf("transcribeItem() = %q", text)
+	}
+	if fetched != "voice-1" || len(fake.Calls) != 1 || fake.Calls[0].Filename != "voice.ogg" {
+		t.Errorf("fetched %q, calls %+v", fetched, fake.Calls)
+	}
+
+	var stored, fileID string
+	if err := db.QueryRow("SELECT text, file_id FROM items WHERE id = ?", id).Scan(&stored, &fileID); err != nil {
+		t.Fatal(err)
+	}
+	if stored != text || fileID != "voice-1" {
+		t.Errorf("stored item = %q / %q, want transcript and original file_id", stored, fileID)
+	}
+
+	// Failures leave the item untouched
+	transcriber = &speech.Fake{Err: errors.New("boom")}
+	if _, err := transcribeItem(context.Background(), fetch, id, media, ""); err == nil {
+		t.Error("transcribeItem() expected error from transcriber")
+	}
+	if err := db.QueryRow("SELECT text FROM items WHERE id = ?", id).Scan(&stored); err != nil || stored != text {
+		t.Errorf("item text changed after failed transcription: %q", stored)
+	}
+
+	// Items edited or undone while transcribing keep their text
+	transcriber = fake
+	edited, err := storeMediaItem(capturedItem{Source: sourceMessage}, media, 1, 6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := editItem(edited, "my own words", 1, editSourceCommand); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := transcribeItem(context.Background(), fetch, edited, media, ""); err != errTranscriptDiscarded {
+		t.Errorf("transcribeItem() of an edited item error = %v, want errTranscriptDiscarded", err)
+	}
+	if item, err := store.Get(edited); err != nil || item.Text != "my own words" {
+		t.Errorf("edited item = %q, %v", item.Text, err)
+	}
+
+	undone, err := storeMediaItem(capturedItem{Source: sourceMessage}, media, 1, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Delete(undone); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := transcribeItem(context.Background(), fetch, undone, media, ""); err != errTranscriptDiscarded {
+		t.Errorf("transcribeItem() of a deleted item error = %v, want errTranscriptDiscarded", err)
+	}
+}
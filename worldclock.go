@@ -0,0 +1,180 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbot "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	timecalc "github.com/jgabriele321/onmymind/time"
+)
+
+// maxWorldClocks limits the places saved per chat
+const maxWorldClocks = 12
+
+const worldClockUsage = `Usage:
+/worldclock — show the time in the saved places
+/worldclock add Tokyo — save a city, country, airport or zone
+/worldclock remove Tokyo — remove a place (or its number)
+/worldclock clear — remove all places`
+
+// worldClock is a place saved by /worldclock add
+type worldClock struct {
+	Label string // name shown, e.g. "Portland, Maine"
+	Zone  string // IANA time zone
+}
+
+// worldClocks returns the places saved for a chat in the order they were
+// added
+func worldClocks(chatID int64) ([]worldClock, error) {
+	rows, err := db.Query("SELECT label, zone FROM world_clocks WHERE chat_id = ? ORDER BY position", chatID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var clocks []worldClock
+	for rows.Next() {
+		var c worldClock
+		if err := rows.Scan(&c.Label, &c.Zone); err != nil {
+			return nil, err
+		}
+		clocks = append(clocks, c)
+	}
+	return clocks, rows.Err()
+}
+
+// addWorldClock saves a place at the end of a chat's list. It returns
+// false if the place is already saved.
+func addWorldClock(chatID int64, c worldClock) (bool, error) {
+	res, err := db.Exec(`
+		INSERT INTO world_clocks (chat_id, label, zone, position)
+		SELECT ?, ?, ?, COALESCE(MAX(position), 0) + 1 FROM world_clocks WHERE chat_id = ?
+		ON CONFLICT (chat_id, label) DO NOTHING`,
+		chatID, c.Label, c.Zone, chatID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// removeWorldClock deletes a saved place by label. It returns false if
+// the place wasn't saved.
+func removeWorldClock(chatID int64, label string) (bool, error) {
+	res, err := db.Exec("DELETE FROM world_clocks WHERE chat_id = ? AND LOWER(label) = LOWER(?)", chatID, label)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// handleWorldClock handles /worldclock and returns an HTML reply
+func handleWorldClock(m *tgbot.Message) string {
+	return worldClockReply(m.Chat.ID, m.CommandArguments(), time.Now())
+}
+
+func worldClockReply(chatID int64, args string, now time.Time) string {
+	action, rest, _ := strings.Cut(strings.TrimSpace(args), " ")
+	rest = strings.TrimSpace(rest)
+
+	switch strings.ToLower(action) {
+	case "":
+		clocks, err := worldClocks(chatID)
+		if err != nil {
+			return "Error loading world clock: " + html.EscapeString(err.Error())
+		}
+		if len(clocks) == 0 {
+			return "No places saved yet.\n\n" + html.EscapeString(worldClockUsage)
+		}
+		return "<pre>" + html.EscapeString(formatWorldClock(clocks, now)) + "</pre>"
+
+	case "add":
+		if rest == "" {
+			return html.EscapeString(worldClockUsage)
+		}
+		place, err := timecalc.ResolveLocation(rest)
+		if err != nil {
+			return "❌ " + html.EscapeString(err.Error())
+		}
+		clocks, err := worldClocks(chatID)
+		if err != nil {
+			return "Error loading world clock: " + html.EscapeString(err.Error())
+		}
+		if len(clocks) >= maxWorldClocks {
+			return fmt.Sprintf("The world clock is full (%d places). Remove one first.", maxWorldClocks)
+		}
+		c := worldClock{Label: worldClockLabel(place), Zone: place.Zone}
+		added, err := addWorldClock(chatID, c)
+		if err != nil {
+			return "Error saving place: " + html.EscapeString(err.Error())
+		}
+		if !added {
+			return html.EscapeString(c.Label) + " is already on the world clock."
+		}
+		return fmt.Sprintf("✅ Added %s (%s)", html.EscapeString(c.Label), html.EscapeString(c.Zone))
+
+	case "remove", "delete":
+		label := rest
+		if n, err := strconv.Atoi(rest); err == nil {
+			clocks, err := worldClocks(chatID)
+			if err != nil {
+				return "Error loading world clock: " + html.EscapeString(err.Error())
+			}
+			if n < 1 || n > len(clocks) {
+				return fmt.Sprintf("There is no place number %d.", n)
+			}
+			label = clocks[n-1].Label
+		}
+		removed, err := removeWorldClock(chatID, label)
+		if err != nil {
+			return "Error removing place: " + html.EscapeString(err.Error())
+		}
+		if !removed {
+			return html.EscapeString(label) + " is not on the world clock."
+		}
+		return "🗑 Removed " + html.EscapeString(label)
+
+	case "clear":
+		if _, err := db.Exec("DELETE FROM world_clocks WHERE chat_id = ?", chatID); err != nil {
+			return "Error clearing world clock: " + html.EscapeString(err.Error())
+		}
+		return "World clock cleared."
+	}
+	return html.EscapeString(worldClockUsage)
+}
+
+// worldClockLabel names a place the way its row on the world clock shows it
+func worldClockLabel(p timecalc.Place) string {
+	if p.Kind == timecalc.KindCity && p.Region != "" {
+		return p.Name + ", " + p.Region
+	}
+	return p.Name
+}
+
+// formatWorldClock lists the current time in each place, e.g.
+// "1. Tokyo     Tue 03:12  JST  UTC+09:00"
+func formatWorldClock(clocks []worldClock, now time.Time) string {
+	width := 0
+	for _, c := range clocks {
+		width = max(width, len([]rune(c.Label)))
+	}
+
+	var lines []string
+	for i, c := range clocks {
+		loc, err := time.LoadLocation(c.Zone)
+		if err != nil {
+			lines = append(lines, fmt.Sprintf("%d. %s: %v", i+1, c.Label, err))
+			continue
+		}
+		local := now.In(loc)
+		name, _ := local.Zone()
+		lines = append(lines, fmt.Sprintf("%d. %s%s  %s  %-5s %s",
+			i+1, c.Label, strings.Repeat(" ", width-len([]rune(c.Label))),
+			local.Format("Mon 15:04"), name, timecalc.FormatOffset(timecalc.ZoneOffset(local))))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWorldClock(t *testing.T) {
+	if err := initDB(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	const chat = 7
+	now := time.Date(2024, 3, 11, 14, 30, 0, 0, time.UTC)
+
+	if got := worldClockReply(chat, "", now); !strings.Contains(got, "No places saved") {
+		t.Errorf("empty world clock = %q", got)
+	}
+	for _, place := range []string{"New York", "Kolkata", "Portland, Maine"} {
+		if got := worldClockReply(chat, "add "+place, now); !strings.HasPrefix(got, "✅") {
+			t.Fatalf("add %s = %q", place, got)
+		}
+	}
+	if got := worldClockReply(chat, "add new york city", now); !strings.Contains(got, "already") {
+		t.Errorf("duplicate add = %q", got)
+	}
+	if got := worldClockReply(chat, "add Portland", now); !strings.Contains(got, "ambiguous") {
+		t.Errorf("ambiguous add = %q", got)
+	}
+
+	got := worldClockReply(chat, "", now)
+	for _, want := range []string{
+		"1. New York City    Mon 10:30  EDT   UTC-04:00",
+		"2. Kolkata          Mon 20:00  IST   UTC+05:30",
+		"3. Portland, Maine  Mon 10:30  EDT   UTC-04:00",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("world clock = %q, want it to contain %q", got, want)
+		}
+	}
+
+	if got := worldClockReply(chat, "remove 2", now); !strings.Contains(got, "Removed Kolkata") {
+		t.Errorf("remove by number = %q", got)
+	}
+	if got := worldClockReply(chat, "remove portland, maine", now); !strings.Contains(got, "Removed") {
+		t.Errorf("remove by name = %q", got)
+	}
+	if got := worldClockReply(chat, "remove Tokyo", now); !strings.Contains(got, "not on the world clock") {
+		t.Errorf("remove missing = %q", got)
+	}
+	// Other chats have their own list
+	if got := worldClockReply(chat+1, "", now); !strings.Contains(got, "No places saved") {
+		t.Errorf("other chat = %q", got)
+	}
+
+	// /meet without places uses the world clock
+	worldClockReply(chat, "add London", now)
+	got = meetReply(chat, "", now)
+	if !strings.Contains(got, "<pre>New York City    London") || !strings.Contains(got, "Mon 10:30-13:00  Mon 14:30-17:00") {
+		t.Errorf("meet from world clock = %q", got)
+	}
+
+	worldClockReply(chat, "clear", now)
+	if got := meetReply(chat, "", now); !strings.HasPrefix(got, "Usage") {
+		t.Errorf("meet without places = %q", got)
+	}
+}
+
+func TestParseMeetRequest(t *testing.T) {
+	tests := []struct {
+		args      string
+		wantNames []string
+		wantDays  int
+		wantMin   time.Duration
+		wantErr   bool
+	}{
+		{args: "New York, London, Tokyo", wantNames: []string{"New York City", "London", "Tokyo"}, wantDays: 5, wantMin: 30 * time.Minute},
+		{args: "Portland, Maine 8-16; Berlin; 7 days; 1h", wantNames: []string{"Portland, Maine", "Berlin"}, wantDays: 7, wantMin: time.Hour},
+		{args: "London; Tokyo; 45m", wantNames: []string{"London", "Tokyo"}, wantDays: 5, wantMin: 45 * time.Minute},
+		{args: "London; Tokyo; 30 days", wantErr: true},
+		{args: "London 17-9; Tokyo", wantErr: true},
+		{args: "Portland; Tokyo", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.args, func(t *testing.T) {
+			req, err := parseMeetRequest(tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseMeetRequest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			var names []string
+			for _, p := range req.Participants {
+				names = append(names, p.Name)
+			}
+			if strings.Join(names, "|") != strings.Join(tt.wantNames, "|") {
+				t.Errorf("participants = %q, want %q", names, tt.wantNames)
+			}
+			if req.Days != tt.wantDays || req.MinLength != tt.wantMin {
+				t.Errorf("days, min = %d, %v; want %d, %v", req.Days, req.MinLength, tt.wantDays, tt.wantMin)
+			}
+		})
+	}
+}